@@ -0,0 +1,210 @@
+package dotlite
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Affinity is the type affinity of a column; see: https://www.sqlite.org/datatype3.html#type_affinity
+type Affinity int
+
+const (
+	AffinityBlob Affinity = iota // also known as "no affinity"
+	AffinityText
+	AffinityNumeric
+	AffinityInteger
+	AffinityReal
+)
+
+func (a Affinity) String() string {
+	switch a {
+	case AffinityText:
+		return "TEXT"
+	case AffinityNumeric:
+		return "NUMERIC"
+	case AffinityInteger:
+		return "INTEGER"
+	case AffinityReal:
+		return "REAL"
+	}
+	return "BLOB"
+}
+
+// AffinityOf determines the affinity of a column from its declared type, applying sqlite's rules in order:
+// a type containing "INT" has INTEGER affinity; otherwise one containing "CHAR", "CLOB" or "TEXT" has TEXT affinity;
+// otherwise one containing "BLOB" (or no type at all) has BLOB affinity; otherwise one containing "REAL", "FLOA"
+// or "DOUB" has REAL affinity; any other type has NUMERIC affinity. Matches are case-insensitive.
+//
+// see: https://www.sqlite.org/datatype3.html#determination_of_column_affinity
+func AffinityOf(declType string) Affinity {
+	var typ = strings.ToUpper(declType)
+	switch {
+	case strings.Contains(typ, "INT"):
+		return AffinityInteger
+	case strings.Contains(typ, "CHAR"), strings.Contains(typ, "CLOB"), strings.Contains(typ, "TEXT"):
+		return AffinityText
+	case strings.Contains(typ, "BLOB"), typ == "":
+		return AffinityBlob
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+		return AffinityReal
+	}
+	return AffinityNumeric
+}
+
+// cast converts val following the rules of sqlite's CAST(val AS <affinity>) expression.
+// NULL is never converted, and BLOB affinity leaves values unchanged.
+//
+// see: https://www.sqlite.org/lang_expr.html#castexpr
+func cast(val any, affinity Affinity) any {
+	if val == nil {
+		return nil
+	}
+
+	switch affinity {
+	case AffinityText:
+		switch v := val.(type) {
+		case int64:
+			return strconv.FormatInt(v, 10)
+		case float64:
+			return formatReal(v)
+		case []byte:
+			return string(v)
+		}
+
+	case AffinityInteger:
+		switch v := val.(type) {
+		case float64:
+			return realToInt(v)
+		case string:
+			return textToInt(v)
+		case []byte:
+			return textToInt(string(v))
+		}
+
+	case AffinityReal:
+		switch v := val.(type) {
+		case int64:
+			return float64(v)
+		case string:
+			return textToReal(v)
+		case []byte:
+			return textToReal(string(v))
+		}
+
+	case AffinityNumeric:
+		var f float64
+		switch v := val.(type) {
+		case int64:
+			return v
+		case float64:
+			f = v
+		case string:
+			if i, ok := parseInt(v); ok {
+				return i
+			}
+			f = textToReal(v)
+		case []byte:
+			if i, ok := parseInt(string(v)); ok {
+				return i
+			}
+			f = textToReal(string(v))
+		}
+
+		// a real value that can be represented exactly as an integer becomes one
+		if f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f)
+		}
+		return f
+	}
+
+	return val
+}
+
+// formatReal renders the floating point value the way sqlite does, ie. always with a decimal point
+func formatReal(f float64) string {
+	var s = strconv.FormatFloat(f, 'g', 15, 64)
+	if strings.ContainsAny(s, ".nN") { // already has a decimal point, or is NaN / Inf
+		return s
+	} else if i := strings.IndexByte(s, 'e'); i >= 0 {
+		return s[:i] + ".0" + s[i:]
+	}
+	return s + ".0"
+}
+
+// realToInt truncates f towards zero, saturating at the limits of int64
+func realToInt(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	case f <= math.MinInt64:
+		return math.MinInt64
+	case f >= math.MaxInt64:
+		return math.MaxInt64
+	}
+	return int64(f)
+}
+
+// numericPrefix returns the longest prefix of s (ignoring leading whitespace) that looks like a number
+func numericPrefix(s string, real bool) string {
+	s = strings.TrimLeft(s, " \t\n\r\f\v")
+
+	var i = 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+
+	var digits = 0
+	for ; i < len(s) && isDigit(s[i]); i++ {
+		digits++
+	}
+
+	if real {
+		if i < len(s) && s[i] == '.' {
+			for i++; i < len(s) && isDigit(s[i]); i++ {
+				digits++
+			}
+		}
+
+		if digits > 0 && i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+			var j = i + 1
+			if j < len(s) && (s[j] == '+' || s[j] == '-') {
+				j++
+			}
+			if j < len(s) && isDigit(s[j]) {
+				for i = j; i < len(s) && isDigit(s[i]); i++ {
+				}
+			}
+		}
+	}
+
+	if digits == 0 {
+		return ""
+	}
+	return s[:i]
+}
+
+// textToInt converts text to an integer using its longest integer prefix, or zero if there isn't one
+func textToInt(s string) int64 {
+	var prefix = numericPrefix(s, false)
+	if i, err := strconv.ParseInt(prefix, 10, 64); err == nil {
+		return i
+	} else if prefix != "" && prefix[0] == '-' {
+		return math.MinInt64
+	} else if prefix != "" {
+		return math.MaxInt64
+	}
+	return 0
+}
+
+// textToReal converts text to a real using its longest numeric prefix, or zero if there isn't one
+func textToReal(s string) float64 {
+	var f, _ = strconv.ParseFloat(numericPrefix(s, true), 64)
+	return f
+}
+
+// parseInt parses s as an integer if the entire text (ignoring surrounding whitespace) is a well-formed integer
+func parseInt(s string) (int64, bool) {
+	var i, err = strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	return i, err == nil
+}
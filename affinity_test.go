@@ -0,0 +1,115 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAffinityOf(t *testing.T) {
+	// examples from https://www.sqlite.org/datatype3.html#affinity_name_examples, along with
+	// the edge cases called out by the documentation
+	for typ, expected := range map[string]Affinity{
+		"INT":                    AffinityInteger,
+		"INTEGER":                AffinityInteger,
+		"TINYINT":                AffinityInteger,
+		"SMALLINT":               AffinityInteger,
+		"MEDIUMINT":              AffinityInteger,
+		"BIGINT":                 AffinityInteger,
+		"UNSIGNED BIG INT":       AffinityInteger,
+		"INT2":                   AffinityInteger,
+		"INT8":                   AffinityInteger,
+		"CHARACTER(20)":          AffinityText,
+		"VARCHAR(255)":           AffinityText,
+		"VARYING CHARACTER(255)": AffinityText,
+		"NCHAR(55)":              AffinityText,
+		"NATIVE CHARACTER(70)":   AffinityText,
+		"NVARCHAR(100)":          AffinityText,
+		"TEXT":                   AffinityText,
+		"CLOB":                   AffinityText,
+		"BLOB":                   AffinityBlob,
+		"":                       AffinityBlob,
+		"REAL":                   AffinityReal,
+		"DOUBLE":                 AffinityReal,
+		"DOUBLE PRECISION":       AffinityReal,
+		"FLOAT":                  AffinityReal,
+		"NUMERIC":                AffinityNumeric,
+		"DECIMAL(10,5)":          AffinityNumeric,
+		"BOOLEAN":                AffinityNumeric,
+		"DATE":                   AffinityNumeric,
+		"DATETIME":               AffinityNumeric,
+		"CHARINT":                AffinityInteger, // "INT" is checked before "CHAR"
+		"FLOATING POINT":         AffinityInteger, // "POINT" contains "INT"
+		"STRING":                 AffinityNumeric, // not TEXT, as none of the patterns match
+		"text":                   AffinityText,
+		"BLOBCHAR":               AffinityText, // "CHAR" is checked before "BLOB"
+	} {
+		if affinity := AffinityOf(typ); affinity != expected {
+			t.Errorf("%q: expected affinity %s; got %s", typ, expected, affinity)
+		}
+	}
+}
+
+func TestCast(t *testing.T) {
+	var cases = []struct {
+		val      any
+		affinity Affinity
+		expected any
+	}{
+		{int64(12), AffinityText, "12"},
+		{1.5, AffinityText, "1.5"},
+		{2.0, AffinityText, "2.0"},
+		{1e20, AffinityText, "1.0e+20"},
+		{[]byte("abc"), AffinityText, "abc"},
+		{2.7, AffinityInteger, int64(2)},
+		{-2.7, AffinityInteger, int64(-2)},
+		{" 42abc", AffinityInteger, int64(42)},
+		{"abc", AffinityInteger, int64(0)},
+		{int64(3), AffinityReal, 3.0},
+		{"2.5e1x", AffinityReal, 25.0},
+		{"4.0", AffinityNumeric, int64(4)},
+		{"4.5", AffinityNumeric, 4.5},
+		{"abc", AffinityNumeric, int64(0)},
+		{3.0, AffinityNumeric, int64(3)},
+		{int64(5), AffinityBlob, int64(5)},
+		{nil, AffinityInteger, nil},
+	}
+
+	for _, c := range cases {
+		if got := cast(c.val, c.affinity); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("CAST(%#v AS %s): expected %#v; got %#v", c.val, c.affinity, c.expected, got)
+		}
+	}
+}
+
+func TestOpen_typed_values(t *testing.T) {
+	var file, err = Open("testdata/mixed-types.db", WithTypedValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var expected = [][]any{
+		{int64(2), "12", 3.0, int64(4), int64(5)},
+		{int64(42), "1.5", 25.0, int64(0), "text"},
+		{nil, nil, nil, nil, nil},
+	}
+
+	var i = 0
+	err = file.ForEach("m", func(rec *Record) error {
+		for c := 0; c < rec.NumValues(); c++ {
+			var val, err = rec.ValueAt(c)
+			if err != nil {
+				return err
+			}
+
+			if !reflect.DeepEqual(val, expected[i][c]) {
+				t.Errorf("row(%d) col(%d): expected %#v; got %#v", i, c, expected[i][c], val)
+			}
+		}
+		i++
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
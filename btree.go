@@ -2,10 +2,12 @@ package dotlite
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 const (
@@ -17,11 +19,11 @@ const (
 
 // TreeHeader represents the header for a b-tree page in the sqlite database file
 type TreeHeader struct {
-	Kind            byte  // the type of the node
-	FreeBlockOffset int16 // offset of the first freeblock on the page
-	NumCells        int16 // number of cells on the page
-	CellsOffset     int16 // offset into first byte of the cell content area
-	NumFreeBytes    int8  // the number of fragmented free bytes within the cell content area.
+	Kind            byte   // the type of the node
+	FreeBlockOffset uint16 // offset of the first freeblock on the page
+	NumCells        uint16 // number of cells on the page
+	CellsOffset     uint16 // offset into first byte of the cell content area
+	NumFreeBytes    uint8  // the number of fragmented free bytes within the cell content area.
 }
 
 // TreeNode represents an individual node in the tree
@@ -29,7 +31,7 @@ type TreeNode struct {
 	file   *File      // reference to the database file
 	header TreeHeader // header describing meta-information about this node
 	page   *Page      // page backing this node
-	cells  []int16    // offset of cells contained in this node
+	cells  []uint16   // offset of cells contained in this node
 
 	// the right-most child pointer. This value appears in the header of interior b-tree pages only and is omitted from all other pages.
 	right int32
@@ -49,6 +51,12 @@ func newNode(file *File, page *Page) (_ *TreeNode, err error) {
 		return nil, err
 	}
 
+	switch header.Kind {
+	case NodeIndexInt, NodeTableInt, NodeIndexLeaf, NodeTableLeaf:
+	default:
+		return nil, errorf(ErrCorruptPage, "page %d is not a b-tree page: unknown node type %#x", page.ID, header.Kind)
+	}
+
 	var node = &TreeNode{file: file, header: header, page: page}
 	if node.Kind() == NodeTableInt || node.Kind() == NodeIndexInt {
 		if err = binary.Read(page, binary.BigEndian, &node.right); err != nil {
@@ -56,10 +64,15 @@ func newNode(file *File, page *Page) (_ *TreeNode, err error) {
 		}
 	}
 
-	// TODO(@riyaz): using unsafe.Pointer can we directly map []int16 to the underlying page buffer?
-	var cells = make([]int16, node.header.NumCells)
+	// the cell pointer array must fit in the page
+	if int64(node.header.NumCells)*2 > page.Remaining() {
+		return nil, errorf(ErrCorruptPage, "page %d: cell count %d exceeds page size", page.ID, node.header.NumCells)
+	}
+
+	// TODO(@riyaz): using unsafe.Pointer can we directly map []uint16 to the underlying page buffer?
+	var cells = make([]uint16, node.header.NumCells)
 	for i := 0; i < len(cells); i++ {
-		var cell int16
+		var cell uint16
 		if err = binary.Read(page, binary.BigEndian, &cell); err != nil {
 			return nil, err
 		}
@@ -73,24 +86,110 @@ func newNode(file *File, page *Page) (_ *TreeNode, err error) {
 func (node *TreeNode) Kind() byte    { return node.header.Kind }
 func (node *TreeNode) NumCells() int { return int(node.header.NumCells) }
 
+// PageID returns the number of the page backing this node
+func (node *TreeNode) PageID() int { return node.page.ID }
+
+// IsLeaf reports whether the node is a leaf node, ie. it has no children
+func (node *TreeNode) IsLeaf() bool {
+	return node.Kind() == NodeTableLeaf || node.Kind() == NodeIndexLeaf
+}
+
+// ChildPage returns the page number of the i-th child of an interior node.
+// Children are numbered 0 to NumCells(), where child i < NumCells() is the left child of cell i
+// and child NumCells() is the right-most child pointer.
+func (node *TreeNode) ChildPage(i int) (_ int, err error) {
+	if node.IsLeaf() {
+		return 0, fmt.Errorf("page %d is a leaf node and has no children", node.page.ID)
+	}
+
+	if i < 0 || i > node.NumCells() {
+		return 0, fmt.Errorf("child index %d out of range for page %d", i, node.page.ID)
+	} else if i == node.NumCells() {
+		return int(node.right), nil
+	}
+
+	// the left child pointer is the first field of every interior cell
+	var buf [4]byte
+	if _, err = node.page.ReadAt(buf[:], int64(node.cells[i])); err != nil {
+		return 0, errorf(ErrCorruptCell, "error decoding left child: page=%d\tcell=%d", node.page.ID, i)
+	}
+
+	return int(binary.BigEndian.Uint32(buf[:])), nil
+}
+
 // Cell is the data container for b-tree
 type Cell struct {
 	LeftChild int32 // page number of the left child
 	Size      int64 // size of the byte payload (including overflow)
 	Rowid     int64 // rowid of the row contained in this cell; valid only for b-tree holding tables
 
+	rowid bool // true if Rowid holds a rowid, ie. the cell belongs to a table b-tree
+
+	format int32 // schema format of the file holding the cell; zero if unknown, eg. for a payload given to DecodeRecord
+
 	s []byte // cell data buffer
 	i int64
+
+	// chain is the part of the payload stored on overflow pages that hasn't been read into s yet;
+	// nil if the payload doesn't overflow, or once it has been read
+	chain *overflowChain
+}
+
+// overflowChain locates the part of a cell's payload stored on a chain of overflow pages
+type overflowChain struct {
+	pager  *Pager
+	page   int32 // first page of the chain
+	usable int   // usable size of each page
+	size   int   // number of payload bytes stored on the chain
+}
+
+func (c *overflowChain) reader() *overflow {
+	return newOverflowReader(c.pager, c.page, c.usable, c.size)
+}
+
+// fill reads the part of the payload stored on overflow pages, if it hasn't been read yet
+func (cell *Cell) fill() (err error) {
+	if cell.chain == nil {
+		return nil
+	}
+
+	// the buffer grows as overflow pages are read rather than being sized up front from the declared size
+	var buffer bytes.Buffer
+	buffer.Write(cell.s)
+	if _, err = io.Copy(&buffer, cell.chain.reader()); err != nil {
+		return err
+	}
+
+	if int64(buffer.Len()) != cell.Size {
+		return errorf(ErrCorruptCell, "read %d payload bytes instead of %d", buffer.Len(), cell.Size)
+	}
+
+	cell.s, cell.chain = buffer.Bytes(), nil
+	return nil
+}
+
+// size returns the size of the cell's data, including any part not read yet
+func (cell *Cell) size() int64 {
+	if cell.chain != nil {
+		return cell.Size
+	}
+	return int64(len(cell.s))
 }
 
 func (cell *Cell) Len() int {
-	if cell.i >= int64(len(cell.s)) {
+	if cell.i >= cell.size() {
 		return 0
 	}
-	return int(int64(len(cell.s)) - cell.i)
+	return int(cell.size() - cell.i)
 }
 
 func (cell *Cell) Read(b []byte) (n int, err error) {
+	if cell.i >= int64(len(cell.s)) && cell.chain != nil {
+		if err = cell.fill(); err != nil {
+			return 0, err
+		}
+	}
+
 	if cell.i >= int64(len(cell.s)) {
 		return 0, io.EOF
 	}
@@ -101,6 +200,12 @@ func (cell *Cell) Read(b []byte) (n int, err error) {
 }
 
 func (cell *Cell) ReadByte() (byte, error) {
+	if cell.i >= int64(len(cell.s)) && cell.chain != nil {
+		if err := cell.fill(); err != nil {
+			return 0, err
+		}
+	}
+
 	if cell.i >= int64(len(cell.s)) {
 		return 0, io.EOF
 	}
@@ -117,7 +222,7 @@ func (cell *Cell) Seek(offset int64, whence int) (int64, error) {
 	case io.SeekCurrent:
 		abs = cell.i + offset
 	case io.SeekEnd:
-		abs = int64(len(cell.s)) + offset
+		abs = cell.size() + offset
 	default:
 		return 0, errors.New("invalid whence")
 	}
@@ -128,144 +233,189 @@ func (cell *Cell) Seek(offset int64, whence int) (int64, error) {
 	return abs, nil
 }
 
+// section returns a reader over n bytes of the cell's data, starting at off. If the data is stored on overflow pages
+// that haven't been read yet, they are read as the reader is consumed, without filling the cell.
+func (cell *Cell) section(off, n int64) (io.Reader, error) {
+	if off < 0 || n < 0 || off+n > cell.size() {
+		return nil, fmt.Errorf("%d bytes at offset %d lie outside the cell's %d bytes", n, off, cell.size())
+	}
+
+	var local = int64(len(cell.s))
+	if cell.chain == nil || off+n <= local {
+		return bytes.NewReader(cell.s[off : off+n]), nil
+	}
+
+	var head []byte
+	if off < local {
+		head = cell.s[off:]
+	}
+
+	// overflow pages can only be reached by following the chain; skip over any data preceding the section
+	var r = cell.chain.reader()
+	if skip := off - local; skip > 0 {
+		if _, err := io.CopyN(io.Discard, r, skip); err != nil {
+			return nil, err
+		}
+	}
+
+	return io.MultiReader(bytes.NewReader(head), io.LimitReader(r, n-int64(len(head)))), nil
+}
+
+// errCellOverrun is reported by LoadCell for a cell whose local payload extends past the end of its page
+var errCellOverrun = errorf(ErrCorruptCell, "cell extends past the end of the page")
+
+// LoadCell reads the cell at pos.
+//
+// Only the part of the payload stored on the node's page is read; any part stored on overflow pages
+// is read the first time the cell is read past the local part.
 func (node *TreeNode) LoadCell(pos int) (_ *Cell, err error) {
 	var addr = int64(node.cells[pos])
 	if _, err = node.page.Seek(addr, io.SeekStart); err != nil {
 		return nil, err
 	}
 
+	var cell = &Cell{format: node.file.Header.SchemaFormat}
 	switch k := node.Kind(); k {
 	case NodeTableInt:
-		var left int32
-		if err = binary.Read(node.page, binary.BigEndian, &left); err != nil {
-			return nil, err
+		if err = binary.Read(node.page, binary.BigEndian, &cell.LeftChild); err != nil {
+			return nil, fmt.Errorf("%w: page=%d\tcell=%d", errCellOverrun, node.page.ID, pos)
 		}
 
-		var rowid int64
-		if rowid, err = Varint(node.page); err != nil {
-			return nil, fmt.Errorf("error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
+		if cell.Rowid, err = Varint(node.page); err != nil {
+			return nil, errorf(ErrCorruptCell, "error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
 		}
 
-		return &Cell{LeftChild: left, Rowid: rowid}, nil
+		cell.rowid = true
+		return cell, nil
 
 	case NodeTableLeaf:
-		var size, rowid int64
-
-		if size, err = Varint(node.page); err != nil {
-			return nil, fmt.Errorf("error decoding size: page=%d\tcell=%d", node.page.ID, pos)
+		if cell.Size, err = Varint(node.page); err != nil {
+			return nil, errorf(ErrCorruptCell, "error decoding size: page=%d\tcell=%d", node.page.ID, pos)
 		}
 
-		if rowid, err = Varint(node.page); err != nil {
-			return nil, fmt.Errorf("error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
+		if cell.Rowid, err = Varint(node.page); err != nil {
+			return nil, errorf(ErrCorruptCell, "error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
 		}
+		cell.rowid = true
 
-		// size of local (embedded in tree) and overflow content
-		var total, localsz, overflowsz = node.computeBufferSize(int(size))
-
-		var buffer bytes.Buffer
-		if _, err = io.CopyN(&buffer, node.page, int64(localsz)); err != nil {
-			return nil, err
+	case NodeIndexInt:
+		if err = binary.Read(node.page, binary.BigEndian, &cell.LeftChild); err != nil {
+			return nil, fmt.Errorf("%w: page=%d\tcell=%d", errCellOverrun, node.page.ID, pos)
 		}
 
-		if overflowsz > 0 {
-			var overflowPage int32
-			if err = binary.Read(node.page, binary.BigEndian, &overflowPage); err != nil {
-				return nil, err
-			}
-
-			var usable = int(node.file.Header.PageSize - uint16(node.file.Header.PageReserved))
-			_, err = io.Copy(&buffer, newOverflowReader(node.file.Pager, overflowPage, usable, overflowsz))
-			if err != nil {
-				return nil, err
-			}
+		if cell.Size, err = Varint(node.page); err != nil {
+			return nil, errorf(ErrCorruptCell, "error decoding size: page=%d\tcell=%d", node.page.ID, pos)
 		}
 
-		if buffer.Len() != total {
-			return nil, fmt.Errorf("read %d payload bytes instead of %d", buffer.Len(), total)
+	case NodeIndexLeaf:
+		if cell.Size, err = Varint(node.page); err != nil {
+			return nil, errorf(ErrCorruptCell, "error decoding size: page=%d\tcell=%d", node.page.ID, pos)
 		}
 
-		return &Cell{Size: int64(total), Rowid: rowid, s: buffer.Bytes(), i: 0}, err
+	default:
+		panic(fmt.Errorf("unknow node type: %v", k))
+	}
 
-	case NodeIndexInt:
-		var left int32
-		if err = binary.Read(node.page, binary.BigEndian, &left); err != nil {
-			return nil, err
-		}
+	if err = node.checkPayload(pos, cell.Size); err != nil {
+		return nil, err
+	}
 
-		var size int64
-		if size, err = Varint(node.page); err != nil {
-			return nil, fmt.Errorf("error decoding size: page=%d\tcell=%d", node.page.ID, pos)
-		}
+	// size of local (embedded in tree) and overflow content
+	var total, localsz, overflowsz = node.computeBufferSize(int(cell.Size))
+	cell.Size = int64(total)
 
-		// size of local (embedded in tree) and overflow content
-		var total, localsz, overflowsz = node.computeBufferSize(int(size))
+	var buffer bytes.Buffer
+	if _, err = io.CopyN(&buffer, node.page, int64(localsz)); err != nil {
+		return nil, fmt.Errorf("%w: page=%d\tcell=%d", errCellOverrun, node.page.ID, pos)
+	}
+	cell.s = buffer.Bytes()
 
-		var buffer bytes.Buffer
-		if _, err = io.CopyN(&buffer, node.page, int64(localsz)); err != nil {
-			return nil, err
+	if overflowsz > 0 {
+		var chain = &overflowChain{pager: node.file.Pager, usable: node.file.Header.usableSize(), size: overflowsz}
+		if err = binary.Read(node.page, binary.BigEndian, &chain.page); err != nil {
+			return nil, fmt.Errorf("%w: page=%d\tcell=%d", errCellOverrun, node.page.ID, pos)
 		}
+		cell.chain = chain
+	} else if buffer.Len() != total {
+		return nil, errorf(ErrCorruptCell, "read %d payload bytes instead of %d", buffer.Len(), total)
+	}
 
-		if overflowsz > 0 {
-			var overflowPage int32
-			if err = binary.Read(node.page, binary.BigEndian, &overflowPage); err != nil {
-				return nil, err
-			}
-
-			var usable = int(node.file.Header.PageSize - uint16(node.file.Header.PageReserved))
-			_, err = io.Copy(&buffer, newOverflowReader(node.file.Pager, overflowPage, usable, overflowsz))
-			if err != nil {
-				return nil, err
-			}
-		}
+	return cell, nil
+}
 
-		if buffer.Len() != total {
-			return nil, fmt.Errorf("read %d payload bytes instead of %d", buffer.Len(), total)
-		}
+// Rowid returns the rowid of the cell at pos without loading the cell's payload.
+// It is only valid for nodes of a table b-tree.
+func (node *TreeNode) Rowid(pos int) (_ int64, err error) {
+	if pos < 0 || pos >= node.NumCells() {
+		return 0, fmt.Errorf("cell index %d out of range for page %d", pos, node.page.ID)
+	}
 
-		return &Cell{LeftChild: left, Size: int64(total), s: buffer.Bytes(), i: 0}, err
+	if _, err = node.page.Seek(int64(node.cells[pos]), io.SeekStart); err != nil {
+		return 0, err
+	}
 
-	case NodeIndexLeaf:
-		var size int64
-		if size, err = Varint(node.page); err != nil {
-			return nil, fmt.Errorf("error decoding size: page=%d\tcell=%d", node.page.ID, pos)
+	switch node.Kind() {
+	case NodeTableInt: // skip over the left child pointer
+		if _, err = node.page.Seek(4, io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	case NodeTableLeaf: // skip over the payload size
+		if _, err = Varint(node.page); err != nil {
+			return 0, errorf(ErrCorruptCell, "error decoding size: page=%d\tcell=%d", node.page.ID, pos)
 		}
+	default:
+		return 0, fmt.Errorf("page %d is not part of a table b-tree", node.page.ID)
+	}
 
-		// size of local (embedded in tree) and overflow content
-		var total, localsz, overflowsz = node.computeBufferSize(int(size))
+	var rowid int64
+	if rowid, err = Varint(node.page); err != nil {
+		return 0, errorf(ErrCorruptCell, "error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
+	}
 
-		var buffer bytes.Buffer
-		if _, err = io.CopyN(&buffer, node.page, int64(localsz)); err != nil {
-			return nil, err
-		}
+	return rowid, nil
+}
 
-		if overflowsz > 0 {
-			var overflowPage int32
-			if err = binary.Read(node.page, binary.BigEndian, &overflowPage); err != nil {
-				return nil, err
-			}
+// search returns the position of the first cell whose rowid is greater than or equal to rowid,
+// or NumCells() if there is no such cell. It is only valid for nodes of a table b-tree.
+func (node *TreeNode) search(rowid int64) (_ int, err error) {
+	var lo, hi = 0, node.NumCells()
+	for lo < hi {
+		var mid = int(uint(lo+hi) >> 1)
 
-			var usable = int(node.file.Header.PageSize - uint16(node.file.Header.PageReserved))
-			_, err = io.Copy(&buffer, newOverflowReader(node.file.Pager, overflowPage, usable, overflowsz))
-			if err != nil {
-				return nil, err
-			}
+		var key int64
+		if key, err = node.Rowid(mid); err != nil {
+			return 0, err
 		}
 
-		if buffer.Len() != total {
-			return nil, fmt.Errorf("read %d payload bytes instead of %d", buffer.Len(), total)
+		if key < rowid {
+			lo = mid + 1
+		} else {
+			hi = mid
 		}
+	}
+	return lo, nil
+}
 
-		return &Cell{Size: int64(total), s: buffer.Bytes(), i: 0}, err
-
-	default:
-		panic(fmt.Errorf("unknow node type: %v", k))
+// checkPayload reports an error if the payload size declared by the cell at pos can't be stored in the file:
+// it can't be negative, and whatever doesn't fit on this page needs to fit on the file's overflow pages.
+// The size is read from the file and must be checked before any buffer is sized from it.
+func (node *TreeNode) checkPayload(pos int, size int64) error {
+	var usable = int64(node.file.Header.usableSize())
+	if size < 0 || size > usable+int64(node.file.Pager.pages)*(usable-4) {
+		return errorf(ErrCorruptCell, "payload of %d bytes can't be stored in the file: page=%d\tcell=%d", size, node.page.ID, pos)
 	}
+	return nil
 }
 
 // computeBufferSize returns the computed size of local (embedded) and overflown payload
 func (node *TreeNode) computeBufferSize(P int) (total, local, overflow int) {
-	U := int(node.file.Header.PageSize - uint16(node.file.Header.PageReserved)) // the usable page size of pages in the database
-	X := U - 35                                                                 // maximum amount of payload that can be stored directly on the b-tree page
+	U := node.file.Header.usableSize() // the usable page size of pages in the database
+	X := U - 35                        // maximum amount of payload that can be stored directly on the b-tree page
+
+	// cells of an index b-tree have a smaller threshold, so that each page holds at least four cells
+	if node.Kind() == NodeIndexInt || node.Kind() == NodeIndexLeaf {
+		X = ((U - 12) * 64 / 255) - 23
+	}
 
 	total, local, overflow = P, P, 0
 
@@ -285,79 +435,609 @@ func (node *TreeNode) computeBufferSize(P int) (total, local, overflow int) {
 	return
 }
 
+// overflowPage returns the first page of the overflow chain of the cell at pos along with the number of payload
+// bytes stored in the chain, without reading the payload. It returns zero if the cell's payload fits on the page.
+func (node *TreeNode) overflowPage(pos int) (page int32, size int, err error) {
+	var addr = int64(node.cells[pos])
+	var r = io.NewSectionReader(node.page, addr, node.page.Size()-addr)
+
+	switch node.Kind() {
+	case NodeTableInt: // interior cells of a table b-tree hold no payload
+		return 0, 0, nil
+	case NodeIndexInt: // skip the left child pointer
+		if _, err = r.Seek(4, io.SeekStart); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	var payload int64
+	if payload, err = Varint(r); err != nil {
+		return 0, 0, errorf(ErrCorruptCell, "error decoding size: page=%d\tcell=%d", node.page.ID, pos)
+	}
+
+	if node.Kind() == NodeTableLeaf {
+		if _, err = Varint(r); err != nil {
+			return 0, 0, errorf(ErrCorruptCell, "error decoding rowid: page=%d\tcell=%d", node.page.ID, pos)
+		}
+	}
+
+	if err = node.checkPayload(pos, payload); err != nil {
+		return 0, 0, err
+	}
+
+	var _, local, overflow = node.computeBufferSize(int(payload))
+	if overflow == 0 {
+		return 0, 0, nil
+	}
+
+	if _, err = r.Seek(int64(local), io.SeekCurrent); err != nil {
+		return 0, 0, err
+	}
+
+	if err = binary.Read(r, binary.BigEndian, &page); err != nil {
+		return 0, 0, errorf(ErrCorruptCell, "error decoding overflow page: page=%d\tcell=%d", node.page.ID, pos)
+	}
+
+	return page, overflow, nil
+}
+
 // Tree represents a B-Tree in the sqlite database file
 // see: https://www.sqlite.org/fileformat.html#b_tree_pages
 type Tree struct {
 	file  *File  // reference to the database file
 	pager *Pager // pager used to fetch pages containing nodes of the tree
 	root  int    // page containing the root node of the tree
+
+	// MaxDepth is the maximum number of levels below the root node a walk descends to before failing,
+	// guarding against corrupt (or malicious) files that chain interior pages to exhaust the stack.
+	// It defaults to DefaultMaxDepth, or to the limit set WithMaxDepth for trees of the file; sqlite itself never reads
+	// trees deeper than 20 levels, so it can safely be lowered.
+	MaxDepth int
 }
 
+// DefaultMaxDepth is the default value of Tree.MaxDepth
+const DefaultMaxDepth = 1000
+
 // NewTree creates a new Tree using the provided pager, with page at r as the root
 func NewTree(file *File, pager *Pager, root int) (_ *Tree) {
-	return &Tree{file: file, pager: pager, root: root}
+	var depth = DefaultMaxDepth
+	if file != nil && file.maxDepth > 0 {
+		depth = file.maxDepth
+	}
+	return &Tree{file: file, pager: pager, root: root, MaxDepth: depth}
+}
+
+// checkDepth fails if a node at the given depth (below the root) is deeper than MaxDepth allows
+func (tree *Tree) checkDepth(depth int) error {
+	if depth > tree.MaxDepth {
+		return errorf(ErrCorruptPage, "b-tree rooted at page %d is deeper than %d levels", tree.root, tree.MaxDepth)
+	}
+	return nil
+}
+
+// ErrStopIteration can be returned by the callback passed to Walk (or ForEach) to stop the iteration early.
+// The iteration then ends without reading any further pages and without an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// Root returns the page number of the tree's root node
+func (tree *Tree) Root() int { return tree.root }
+
+// RootNode reads and returns the root node of the tree.
+// Together with Child, it allows callers to navigate the tree one node at a time.
+func (tree *Tree) RootNode() (*TreeNode, error) { return tree.node(tree.root) }
+
+// Child reads and returns the i-th child of the given interior node; see TreeNode.ChildPage.
+func (tree *Tree) Child(node *TreeNode, i int) (_ *TreeNode, err error) {
+	var page int
+	if page, err = node.ChildPage(i); err != nil {
+		return nil, err
+	}
+
+	return tree.node(page)
+}
+
+// LeafPageFor returns the number of the leaf page that holds the row with the given rowid, or the page
+// where such a row would be stored if it doesn't exist. Only the interior keys along the path to the leaf
+// are read; no cell is decoded. It is only valid for a table b-tree.
+func (tree *Tree) LeafPageFor(rowid int64) (_ int, err error) {
+	var leaf *TreeNode
+	if leaf, err = tree.seek(rowid); err != nil {
+		return 0, err
+	}
+	return leaf.PageID(), nil
+}
+
+// Search returns the cell holding the row with the given rowid, descending from the root through the interior
+// nodes on the path to its leaf; only the matching cell is loaded. An error wrapping ErrNotFound is returned
+// if there is no such row. It is only valid for a table b-tree.
+func (tree *Tree) Search(rowid int64) (_ *Cell, err error) {
+	var leaf *TreeNode
+	if leaf, err = tree.seek(rowid); err != nil {
+		return nil, err
+	}
+
+	var i int
+	if i, err = leaf.search(rowid); err != nil {
+		return nil, err
+	}
+
+	var key int64
+	if i < leaf.NumCells() {
+		if key, err = leaf.Rowid(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if i == leaf.NumCells() || key != rowid {
+		return nil, fmt.Errorf("rowid %d in b-tree rooted at page %d: %w", rowid, tree.root, ErrNotFound)
+	}
+
+	return leaf.LoadCell(i)
+}
+
+// seek descends from the root of a table b-tree to the leaf node that holds (or would hold) rowid
+func (tree *Tree) seek(rowid int64) (node *TreeNode, err error) {
+	if node, err = tree.RootNode(); err != nil {
+		return nil, err
+	}
+
+	var visited = map[int]bool{node.PageID(): true}
+	for depth := 1; node.Kind() == NodeTableInt; depth++ {
+		// the key of an interior cell is the largest rowid in its left child; find the first cell whose key >= rowid.
+		// if there is no such cell the rowid belongs to the right-most child.
+		var i int
+		if i, err = node.search(rowid); err != nil {
+			return nil, err
+		}
+
+		if node, err = tree.child(node, i, depth, visited); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.Kind() != NodeTableLeaf {
+		return nil, fmt.Errorf("page %d is not part of a table b-tree", node.PageID())
+	}
+
+	return node, nil
+}
+
+// node reads the node stored at the given page
+func (tree *Tree) node(id int) (_ *TreeNode, err error) {
+	var page *Page
+	if page, err = tree.pager.ReadPage(id); err != nil {
+		return nil, err
+	}
+
+	return newNode(tree.file, page)
 }
 
 // Walk walks the tree using in-order traversal, invoking user-defined fn for each cell in all the nodes of the tree.
 func (tree *Tree) Walk(fn func(*Cell) error) (err error) {
-	var rootPage *Page
-	if rootPage, err = tree.pager.ReadPage(tree.root); err != nil {
+	return tree.walkWith(context.Background(), func(_, _ int, cell *Cell) error { return fn(cell) }, nil)
+}
+
+// WalkContext walks the tree like Walk, checking ctx before reading each page of the tree.
+// If ctx is cancelled (or its deadline passes) the walk ends, returning ctx's error.
+func (tree *Tree) WalkContext(ctx context.Context, fn func(*Cell) error) (err error) {
+	return tree.walkWith(ctx, func(_, _ int, cell *Cell) error { return fn(cell) }, nil)
+}
+
+// WalkWithLocation walks the tree like Walk, additionally passing to fn the number of the page holding each cell
+// and the index of the cell on that page, allowing callers to relate a row to its physical location in the file.
+func (tree *Tree) WalkWithLocation(fn func(page, cell int, c *Cell) error) (err error) {
+	return tree.walkWith(context.Background(), fn, nil)
+}
+
+// walkWith walks the tree like WalkWithLocation. If skip is non-nil, a cell (or child page) that cannot be read
+// is passed to it and the walk continues past it, unless skip returns an error. The walk ends with ctx's error
+// as soon as ctx is done; that error is never passed to skip.
+func (tree *Tree) walkWith(ctx context.Context, fn func(page, cell int, c *Cell) error, skip func(error) error) (err error) {
+	if skip == nil {
+		skip = func(err error) error { return err }
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	var root *TreeNode
+	if root, err = tree.RootNode(); err != nil {
 		return err
 	}
 
+	var visited = map[int]bool{root.PageID(): true}
+	if err = tree.walk(ctx, root, visited, fn, skip); errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+
+	return err
+}
+
+// walkFrame is the state of a node being walked by walk
+type walkFrame struct {
+	node *TreeNode
+	i    int   // index of the next cell to visit; NumCells() for the right-most child
+	cell *Cell // cell i, whose left child is being walked; it's visited once the walk returns to this frame
+}
+
+// walk walks the tree rooted at root in order. Rather than recursing, it keeps an explicit stack with a frame
+// for every node on the path from the root, so the memory it uses is bounded by the height of the tree.
+// visited holds the pages already visited during the walk, used to detect cycles.
+func (tree *Tree) walk(ctx context.Context, root *TreeNode, visited map[int]bool, fn func(page, cell int, c *Cell) error, skip func(error) error) (err error) {
+	var stack = []walkFrame{{node: root}}
+	for len(stack) > 0 {
+		var top = &stack[len(stack)-1]
+		var node = top.node
+
+		switch {
+		case top.cell != nil: // back from the left child of cell i
+			var cell = top.cell
+			top.cell = nil
+
+			if node.Kind() != NodeTableInt {
+				if err = fn(node.PageID(), top.i, cell); err != nil {
+					return err
+				}
+			}
+			top.i++
+
+		case top.i < node.NumCells():
+			var cell *Cell
+			if cell, err = node.LoadCell(top.i); err != nil {
+				if err = skip(err); err != nil {
+					return err
+				}
+				top.i++
+				continue
+			}
+
+			// a cell without a left child (ie. on a leaf) is visited right away
+			if cell.LeftChild == 0 {
+				if err = fn(node.PageID(), top.i, cell); err != nil {
+					return err
+				}
+				top.i++
+				continue
+			}
+
+			top.cell = cell
+
+			var child *TreeNode
+			if child, err = tree.descend(ctx, int(cell.LeftChild), len(stack), visited, skip); err != nil {
+				return err
+			} else if child != nil {
+				stack = append(stack, walkFrame{node: child})
+			}
+
+		case top.i == node.NumCells() && node.right != 0:
+			top.i++
+
+			var child *TreeNode
+			if child, err = tree.descend(ctx, int(node.right), len(stack), visited, skip); err != nil {
+				return err
+			} else if child != nil {
+				stack = append(stack, walkFrame{node: child})
+			}
+
+		default: // every cell and child of the node has been walked
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return nil
+}
+
+// descend reads the child node at the given page, found depth levels below the root, for walk to visit.
+// If the page cannot be read, the error is passed to skip; if skip ignores it, a nil node is returned.
+func (tree *Tree) descend(ctx context.Context, page, depth int, visited map[int]bool, skip func(error) error) (_ *TreeNode, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	} else if err = tree.checkDepth(depth); err != nil {
+		return nil, err
+	}
+
+	var child *TreeNode
+	if child, err = tree.visit(page, depth, visited); err != nil {
+		return nil, skip(err)
+	}
+
+	return child, nil
+}
+
+// WalkReverse walks the tree like Walk, but in reverse order: cells are visited in descending rowid (or key) order,
+// which makes it suitable to read the last rows of a table. As with Walk, fn may return ErrStopIteration to end the walk.
+func (tree *Tree) WalkReverse(fn func(*Cell) error) (err error) {
 	var root *TreeNode
-	if root, err = newNode(tree.file, rootPage); err != nil {
+	if root, err = tree.RootNode(); err != nil {
 		return err
 	}
 
-	return tree.walk(root, fn)
+	if err = tree.walkReverse(root, 0, map[int]bool{root.PageID(): true}, fn); errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+
+	return err
 }
 
-func (tree *Tree) walk(node *TreeNode, fn func(*Cell) error) (err error) {
-	for i := 0; i < node.NumCells(); i++ {
+func (tree *Tree) walkReverse(node *TreeNode, depth int, visited map[int]bool, fn func(*Cell) error) (err error) {
+	var child *TreeNode
+	if node.right != 0 {
+		if child, err = tree.visit(int(node.right), depth+1, visited); err != nil {
+			return err
+		}
+
+		if err = tree.walkReverse(child, depth+1, visited, fn); err != nil {
+			return err
+		}
+	}
+
+	for i := node.NumCells() - 1; i >= 0; i-- {
 		var cell *Cell
 		if cell, err = node.LoadCell(i); err != nil {
 			return err
 		}
 
+		// on interior nodes of an index b-tree, the cell's entry sorts after every entry in its left child
+		if node.Kind() != NodeTableInt {
+			if err = fn(cell); err != nil {
+				return err
+			}
+		}
+
 		if cell.LeftChild != 0 {
-			var page *Page
-			if page, err = tree.pager.ReadPage(int(cell.LeftChild)); err != nil {
+			if child, err = tree.visit(int(cell.LeftChild), depth+1, visited); err != nil {
 				return err
 			}
 
-			var child *TreeNode
-			if child, err = newNode(tree.file, page); err != nil {
+			if err = tree.walkReverse(child, depth+1, visited, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// visit reads the node stored at the given page, found depth levels below the root, failing if the page was
+// already visited or if it is deeper than MaxDepth allows. Every traversal of the tree goes through it:
+// a page is only ever referenced once in a well-formed tree, and visiting it again would loop forever.
+func (tree *Tree) visit(page, depth int, visited map[int]bool) (*TreeNode, error) {
+	if err := tree.checkDepth(depth); err != nil {
+		return nil, err
+	} else if visited[page] {
+		return nil, errorf(ErrCorruptPage, "cycle detected at page %d", page)
+	}
+	visited[page] = true
+	return tree.node(page)
+}
+
+// child reads the i-th child of the given interior node, found depth levels below the root, through visit
+func (tree *Tree) child(node *TreeNode, i, depth int, visited map[int]bool) (_ *TreeNode, err error) {
+	var page int
+	if page, err = node.ChildPage(i); err != nil {
+		return nil, err
+	}
+	return tree.visit(page, depth, visited)
+}
+
+// Range invokes fn, in ascending rowid order, for every cell of a table b-tree whose rowid lies within [lo, hi];
+// both bounds are inclusive. Subtrees that can't hold a rowid within the range are never read.
+// If lo is greater than hi the range is empty, and fn is never invoked. As with Walk, fn may return
+// ErrStopIteration to end the scan early.
+func (tree *Tree) Range(lo, hi int64, fn func(*Cell) error) (err error) {
+	if lo > hi {
+		return nil
+	}
+
+	var root *TreeNode
+	if root, err = tree.RootNode(); err != nil {
+		return err
+	}
+
+	if err = tree.rangeNode(root, lo, hi, fn, 0, map[int]bool{root.PageID(): true}); errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+
+	return err
+}
+
+func (tree *Tree) rangeNode(node *TreeNode, lo, hi int64, fn func(*Cell) error, depth int, visited map[int]bool) (err error) {
+	if k := node.Kind(); k != NodeTableInt && k != NodeTableLeaf {
+		return fmt.Errorf("page %d is not part of a table b-tree", node.PageID())
+	}
+
+	// skip over the cells (and, on interior nodes, the children) holding rowids less than lo
+	var start int
+	if start, err = node.search(lo); err != nil {
+		return err
+	}
+
+	if node.IsLeaf() {
+		for i := start; i < node.NumCells(); i++ {
+			var rowid int64
+			if rowid, err = node.Rowid(i); err != nil {
+				return err
+			} else if rowid > hi {
+				return nil
+			}
+
+			var cell *Cell
+			if cell, err = node.LoadCell(i); err != nil {
+				return err
+			}
+
+			if err = fn(cell); err != nil {
 				return err
 			}
+		}
+		return nil
+	}
+
+	for i := start; i <= node.NumCells(); i++ {
+		var child *TreeNode
+		if child, err = tree.child(node, i, depth+1, visited); err != nil {
+			return err
+		}
+
+		if err = tree.rangeNode(child, lo, hi, fn, depth+1, visited); err != nil {
+			return err
+		}
 
-			if err = tree.walk(child, fn); err != nil {
+		// the key of an interior cell is the largest rowid in its left child; children past it hold larger rowids
+		if i < node.NumCells() {
+			var key int64
+			if key, err = node.Rowid(i); err != nil {
 				return err
+			} else if key >= hi {
+				return nil
 			}
 		}
+	}
+
+	return nil
+}
+
+// skipLeaves walks the table b-tree rooted at node like Walk, skipping its first *n cells. Leaves holding
+// no more than the cells left to skip are passed over using the cell count in their header, without loading any cell.
+func (tree *Tree) skipLeaves(node *TreeNode, depth int, visited map[int]bool, n *int, fn func(*Cell) error) (err error) {
+	if node.IsLeaf() {
+		if *n >= node.NumCells() {
+			*n -= node.NumCells()
+			return nil
+		}
+
+		for i := *n; i < node.NumCells(); i++ {
+			var cell *Cell
+			if cell, err = node.LoadCell(i); err != nil {
+				return err
+			}
 
-		if node.Kind() != NodeTableInt {
 			if err = fn(cell); err != nil {
 				return err
 			}
 		}
+
+		*n = 0
+		return nil
 	}
 
-	if node.right != 0 {
-		var page *Page
-		if page, err = tree.pager.ReadPage(int(node.right)); err != nil {
+	for i := 0; i <= node.NumCells(); i++ {
+		var child *TreeNode
+		if child, err = tree.child(node, i, depth+1, visited); err != nil {
 			return err
 		}
 
+		if err = tree.skipLeaves(child, depth+1, visited, n, fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of entries in the tree: the rows of a table, or the entries of an index. It is the
+// equivalent of SELECT COUNT(*), but no cell is ever decoded: leaves are counted using the cell count in their
+// header, and interior nodes are only read for their child pointers. On index b-trees, whose interior cells
+// also hold entries, those are counted too.
+func (tree *Tree) Count() (n int64, err error) {
+	var root *TreeNode
+	if root, err = tree.RootNode(); err != nil {
+		return 0, err
+	}
+
+	err = tree.count(root, 0, map[int]bool{root.PageID(): true}, &n)
+	return n, err
+}
+
+func (tree *Tree) count(node *TreeNode, depth int, visited map[int]bool, n *int64) (err error) {
+	if node.IsLeaf() || node.Kind() == NodeIndexInt {
+		*n += int64(node.NumCells())
+	}
+
+	if node.IsLeaf() {
+		return nil
+	}
+
+	for i := 0; i <= node.NumCells(); i++ {
 		var child *TreeNode
-		if child, err = newNode(tree.file, page); err != nil {
+		if child, err = tree.child(node, i, depth+1, visited); err != nil {
 			return err
 		}
 
-		if err = tree.walk(child, fn); err != nil {
+		if err = tree.count(child, depth+1, visited, n); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+// EstimateCount returns an approximate number of entries in the tree, cheap enough to size a progress bar
+// before a full scan. The estimate is only approximate: use Count for an exact number.
+//
+// If the database was analyzed, the row count recorded in sqlite_stat1 for the tree's table or index is returned,
+// which is as accurate as it was when ANALYZE last ran. Otherwise, only the nodes along a single path from the
+// root to a leaf are read: the number of children of each node is taken to be the fan-out at its level, and the
+// cell count of the leaf to be that of every leaf. Zero is returned if not even the root node can be read.
+func (tree *Tree) EstimateCount() int64 {
+	if n, ok := tree.statCount(); ok {
+		return n
+	}
+
+	var node, err = tree.RootNode()
+	if err != nil {
+		return 0
+	}
+
+	var n int64 = 1
+	var visited = map[int]bool{node.PageID(): true}
+	for depth := 1; !node.IsLeaf(); depth++ {
+		n *= int64(node.NumCells() + 1)
+
+		// the middle child is more likely to be typical than the first or last one, which may be partly filled
+		if node, err = tree.child(node, node.NumCells()/2, depth, visited); err != nil {
+			return 0
+		}
+	}
+
+	return n * int64(node.NumCells())
+}
+
+// statCount returns the row count recorded in sqlite_stat1 for the table or index stored in the tree
+func (tree *Tree) statCount() (n int64, ok bool) {
+	if tree.file == nil {
+		return 0, false
+	}
+
+	var stats, err = tree.file.Stat1()
+	if err != nil || len(stats) == 0 {
+		return 0, false
+	}
+
+	var obj *Object
+	_ = tree.file.schema(func(o *Object) error {
+		if o.hasTree() && o.tree.root == tree.root {
+			obj = o
+			return ErrStopIteration
+		}
+		return nil
+	})
+
+	if obj == nil {
+		return 0, false
+	} else if entry, ok := stats[obj.name]; ok {
+		return entry.Rows, true
+	} else if obj.kind == ObjectIndex {
+		return 0, false
+	}
+
+	// a table with indexes has no entry of its own, but each entry of its indexes holds its row count
+	for _, entry := range stats {
+		if strings.EqualFold(entry.Table, obj.name) {
+			return entry.Rows, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,657 @@
+package dotlite
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTree_navigate(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := table.tree.RootNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if root.IsLeaf() || root.PageID() != table.tree.Root() {
+		t.Fatalf("expected root of Track to be an interior node on page %d", table.tree.Root())
+	}
+
+	// count all the cells by descending the tree manually; it must match a full walk
+	var count func(node *TreeNode) int
+	count = func(node *TreeNode) int {
+		if node.IsLeaf() {
+			return node.NumCells()
+		}
+
+		var n int
+		for i := 0; i <= node.NumCells(); i++ {
+			var child, err = table.tree.Child(node, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n += count(child)
+		}
+		return n
+	}
+
+	var walked int
+	if err = table.tree.Walk(func(*Cell) error { walked++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := count(root); n != walked {
+		t.Errorf("expected %d cells; got %d", walked, n)
+	}
+
+	if _, err = table.tree.Child(root, root.NumCells()+1); err == nil {
+		t.Errorf("expected out of range error")
+	}
+}
+
+func TestTree_LeafPageFor(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every rowid stored on a leaf must map back to that leaf
+	var check func(node *TreeNode)
+	check = func(node *TreeNode) {
+		if node.IsLeaf() {
+			for i := 0; i < node.NumCells(); i++ {
+				var rowid, err = node.Rowid(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if page, err := table.tree.LeafPageFor(rowid); err != nil {
+					t.Fatal(err)
+				} else if page != node.PageID() {
+					t.Errorf("rowid %d: expected page %d; got %d", rowid, node.PageID(), page)
+				}
+			}
+			return
+		}
+
+		for i := 0; i <= node.NumCells(); i++ {
+			var child, err = table.tree.Child(node, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(child)
+		}
+	}
+
+	root, err := table.tree.RootNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(root)
+
+	// a rowid past the end belongs to the right-most leaf
+	var max int64
+	if max, err = table.MaxRowid(); err != nil {
+		t.Fatal(err)
+	}
+
+	var last, page int
+	if last, err = table.tree.LeafPageFor(max); err != nil {
+		t.Fatal(err)
+	}
+	if page, err = table.tree.LeafPageFor(max + 1000); err != nil || page != last {
+		t.Errorf("expected page %d; got %d (err=%v)", last, page, err)
+	}
+}
+
+func TestTree_Search(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every cell reached by walking the tree must be found by searching for its rowid
+	var tree = table.Tree()
+	err = tree.Walk(func(cell *Cell) error {
+		var found, err = tree.Search(cell.Rowid)
+		if err != nil {
+			return err
+		}
+
+		if found.Rowid != cell.Rowid || found.Size != cell.Size {
+			t.Errorf("rowid %d: found cell with rowid %d and size %d; expected size %d", cell.Rowid, found.Rowid, found.Size, cell.Size)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var max int64
+	if max, err = table.MaxRowid(); err != nil {
+		t.Fatal(err)
+	}
+
+	// rowids before the first, past the last (in the right-most child) and beyond any key are not found
+	for _, rowid := range []int64{0, -1, max + 1, math.MaxInt64} {
+		if _, err = tree.Search(rowid); !errors.Is(err, ErrNotFound) {
+			t.Errorf("rowid %d: expected ErrNotFound; got %v", rowid, err)
+		}
+	}
+}
+
+func TestTree_WalkReverse(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// a table and an index, both spanning several levels of interior pages
+	for _, name := range []string{"Track", "IFK_TrackAlbumId"} {
+		var obj, err = file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		type entry struct {
+			rowid   int64
+			payload []byte
+		}
+
+		var forward, reverse []entry
+		var collect = func(entries *[]entry) func(*Cell) error {
+			return func(cell *Cell) error {
+				var b = make([]byte, cell.Len())
+				if _, err := io.ReadFull(cell, b); err != nil {
+					return err
+				}
+				*entries = append(*entries, entry{cell.Rowid, b})
+				return nil
+			}
+		}
+
+		if err = obj.tree.Walk(collect(&forward)); err != nil {
+			t.Fatal(err)
+		}
+		if err = obj.tree.WalkReverse(collect(&reverse)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(forward) != len(reverse) {
+			t.Fatalf("%s: expected %d cells; got %d", name, len(forward), len(reverse))
+		}
+
+		for i := range forward {
+			if !reflect.DeepEqual(forward[i], reverse[len(reverse)-1-i]) {
+				t.Fatalf("%s: cell %d from the end doesn't match the walk in ascending order", name, i)
+			}
+		}
+	}
+}
+
+func TestTree_Range(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var all []int64
+	if err = table.tree.Walk(func(cell *Cell) error { all = append(all, cell.Rowid); return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	var max = all[len(all)-1]
+	for _, r := range [][2]int64{{1, 1}, {100, 200}, {0, 10}, {max - 5, max + 5}, {1, max}, {math.MinInt64, math.MaxInt64}, {max + 1, max + 100}, {10, 9}} {
+		var expected []int64
+		for _, rowid := range all {
+			if rowid >= r[0] && rowid <= r[1] {
+				expected = append(expected, rowid)
+			}
+		}
+
+		var got []int64
+		if err = table.tree.Range(r[0], r[1], func(cell *Cell) error { got = append(got, cell.Rowid); return nil }); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("range [%d, %d]: expected %d rowids; got %d", r[0], r[1], len(expected), len(got))
+		}
+	}
+
+	var n int
+	err = table.tree.Range(1, max, func(*Cell) error {
+		if n++; n == 10 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+
+	if err != nil || n != 10 {
+		t.Errorf("expected the scan to stop after %d cells; got %d (err=%v)", 10, n, err)
+	}
+}
+
+func TestTree_WalkWithLocation(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows int
+	err = table.tree.WalkWithLocation(func(page, cell int, c *Cell) (err error) {
+		rows++
+
+		var node *TreeNode
+		if node, err = table.tree.node(page); err != nil {
+			return err
+		}
+
+		// the cell at the reported location must be the one passed to the callback
+		var rowid int64
+		if rowid, err = node.Rowid(cell); err != nil {
+			return err
+		} else if rowid != c.Rowid {
+			t.Errorf("page %d, cell %d: expected rowid %d; got %d", page, cell, c.Rowid, rowid)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if rows != 3503 {
+		t.Errorf("expected %d rows; got %d", 3503, rows)
+	}
+}
+
+func TestTreeNode_computeBufferSize(t *testing.T) {
+	// expected splits as computed by sqlite's btreeParseCellAdjustSizeForOverflow() / btreePayloadToLocal()
+	var cases = []struct {
+		name            string
+		pageSize        uint16
+		reserved        byte
+		kind            byte
+		payload         int
+		local, overflow int
+	}{
+		// usable size 1024: X = 989, M = 103 (table) and X = 230 (index)
+		{"table: at X", 1024, 0, NodeTableLeaf, 989, 989, 0},
+		{"table: X+1", 1024, 0, NodeTableLeaf, 990, 103, 887},
+		{"table: K at M", 1024, 0, NodeTableLeaf, 1123, 103, 1020},
+		{"table: K at X", 1024, 0, NodeTableLeaf, 2009, 989, 1020},
+		{"table: K at X+1", 1024, 0, NodeTableLeaf, 2010, 103, 1907},
+		{"index: at X", 1024, 0, NodeIndexLeaf, 230, 230, 0},
+		{"index: X+1", 1024, 0, NodeIndexLeaf, 231, 103, 128},
+		{"index: K at X", 1024, 0, NodeIndexInt, 1250, 230, 1020},
+		{"index: K at X+1", 1024, 0, NodeIndexInt, 1251, 103, 1148},
+
+		// usable size 4064 (32 reserved bytes): X = 4029, M = 485 (table) and X = 993 (index)
+		{"reserved table: at X", 4096, 32, NodeTableLeaf, 4029, 4029, 0},
+		{"reserved table: X+1", 4096, 32, NodeTableLeaf, 4030, 485, 3545},
+		{"reserved table: K at M", 4096, 32, NodeTableLeaf, 4545, 485, 4060},
+		{"reserved table: K at X", 4096, 32, NodeTableLeaf, 8089, 4029, 4060},
+		{"reserved table: K at X+1", 4096, 32, NodeTableLeaf, 8090, 485, 7605},
+		{"reserved index: at X", 4096, 32, NodeIndexLeaf, 993, 993, 0},
+		{"reserved index: X+1", 4096, 32, NodeIndexLeaf, 994, 485, 509},
+		{"reserved index: K at X", 4096, 32, NodeIndexLeaf, 5053, 993, 4060},
+		{"reserved index: K at X+1", 4096, 32, NodeIndexLeaf, 5054, 485, 4569},
+
+		// page size of 65536 is stored as 1: X = 65501, M = 8199
+		{"64k table: at X", 1, 0, NodeTableLeaf, 65501, 65501, 0},
+		{"64k table: X+1", 1, 0, NodeTableLeaf, 65502, 8199, 57303},
+	}
+
+	for _, c := range cases {
+		var file = &File{Header: Header{PageSize: c.pageSize, PageReserved: c.reserved}}
+		var node = &TreeNode{file: file, header: TreeHeader{Kind: c.kind}}
+
+		var total, local, overflow = node.computeBufferSize(c.payload)
+		if total != c.payload || local != c.local || overflow != c.overflow {
+			t.Errorf("%s: expected (%d, %d, %d); got (%d, %d, %d)", c.name, c.payload, c.local, c.overflow, total, local, overflow)
+		}
+	}
+}
+
+func TestTree_WalkContext(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+
+	// cancel after the first cell; no page is read after that
+	var n int
+	err = table.tree.WalkContext(ctx, func(*Cell) error {
+		n++
+		cancel()
+		return nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+
+	var leaf *TreeNode
+	if leaf, err = table.tree.seek(1); err != nil {
+		t.Fatal(err)
+	} else if n != leaf.NumCells() {
+		t.Errorf("expected the walk to end after the first leaf (%d cells); got %d cells", leaf.NumCells(), n)
+	}
+
+	if err = table.ForEachContext(ctx, func(*Record) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled; got %v", err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+
+	if err = file.ForEachContext(ctx, "Track", func(*Record) error { return nil }); err != nil {
+		t.Errorf("expected no error; got %v", err)
+	}
+}
+
+func TestTree_Walk_cycle(t *testing.T) {
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// point the right-most child of an interior page of Track (page 252) back at the table's root (page 409)
+	binary.BigEndian.PutUint32(b[251*1024+8:], 409)
+
+	var name = filepath.Join(t.TempDir(), "cycle.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var file = open(t, name)
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = "cycle detected at page 409"
+	if err = table.tree.Walk(func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.tree.WalkReverse(func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if _, err = table.tree.Count(); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.tree.Range(math.MinInt64, math.MaxInt64, func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.Slice(3000, 10, func(*Record) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	// lookups of the rowids under the right-most child of page 252 run into the cycle too
+	var cycles int
+	for rowid := int64(1); rowid <= 3503; rowid++ {
+		if _, err = table.tree.Search(rowid); err != nil && err.Error() == expected {
+			cycles++
+		} else if err != nil {
+			t.Fatalf("rowid %d: expected %q; got %v", rowid, expected, err)
+		}
+	}
+
+	if cycles == 0 {
+		t.Errorf("expected some lookups to report the cycle")
+	}
+}
+
+func TestTree_MaxDepth(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.tree.MaxDepth != DefaultMaxDepth {
+		t.Errorf("expected max depth to default to %d; got %d", DefaultMaxDepth, table.tree.MaxDepth)
+	}
+
+	// the leaves of Track are two levels below the root
+	table.tree.MaxDepth = 1
+
+	var expected = "b-tree rooted at page 409 is deeper than 1 levels"
+	if err = table.tree.Walk(func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.tree.WalkReverse(func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.Slice(10, 10, func(*Record) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if _, err = table.tree.Search(1); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if err = table.tree.Range(1, 10, func(*Cell) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	if _, err = table.tree.Count(); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+
+	table.tree.MaxDepth = 2
+	if err = table.tree.Walk(func(*Cell) error { return nil }); err != nil {
+		t.Errorf("expected no error; got %v", err)
+	}
+
+	// the limit can be set for every tree of the file, including those walked by ForEach
+	limited, err := Open("testdata/chinook.db", WithMaxDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limited.Close()
+
+	if table, err = limited.Object("Track"); err != nil {
+		t.Fatal(err)
+	} else if depth := table.Tree().MaxDepth; depth != 1 {
+		t.Errorf("expected max depth to be %d; got %d", 1, depth)
+	}
+
+	if err = limited.ForEach("Track", func(*Record) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	} else if !errors.Is(err, ErrCorruptPage) {
+		t.Errorf("expected ErrCorruptPage; got %v", err)
+	}
+}
+
+func TestTreeNode_LoadCell_oversized(t *testing.T) {
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	page, err := table.tree.LeafPageFor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, err := table.tree.node(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// declare a payload far larger than the whole file in the first cell of the leaf
+	copy(b[(page-1)*1024+int(node.cells[0]):], putVarint(1<<48))
+
+	if file, err = OpenBytes(b); err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if table, err = file.Object("Track"); err != nil {
+		t.Fatal(err)
+	}
+
+	if node, err = table.tree.node(page); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = node.LoadCell(0); !errors.Is(err, ErrCorruptCell) {
+		t.Errorf("expected ErrCorruptCell; got %v", err)
+	}
+
+	if err = table.tree.Walk(func(*Cell) error { return nil }); !errors.Is(err, ErrCorruptCell) {
+		t.Errorf("expected ErrCorruptCell; got %v", err)
+	}
+}
+
+func TestTree_Walk_index_order(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	index, err := file.Object("IFK_TrackAlbumId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// entries on interior pages must be visited between the entries of their left and right subtrees
+	var n int
+	var last [2]int64
+	err = index.ForEach(func(rec *Record) (err error) {
+		var key [2]int64
+		if key[0], err = rec.AsInt64(0); err != nil {
+			return err
+		} else if key[1], err = rec.AsInt64(1); err != nil {
+			return err
+		}
+
+		if n++; n > 1 && (key[0] < last[0] || key[0] == last[0] && key[1] <= last[1]) {
+			t.Fatalf("entry %v visited after %v", key, last)
+		}
+		last = key
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 3503 {
+		t.Errorf("expected %d entries; got %d", 3503, n)
+	}
+}
+
+func TestTree_Count(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	for _, test := range []struct {
+		name     string
+		expected int64
+	}{
+		{"Track", 3503},
+		{"IFK_TrackAlbumId", 3503}, // an index spanning several levels
+		{"Artist", 275},
+		{"sqlite_autoindex_PlaylistTrack_1", 8715},
+	} {
+		var obj, err = file.Object(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var walked int64
+		if err = obj.Tree().Walk(func(*Cell) error { walked++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := obj.Tree().Count()
+		if err != nil {
+			t.Fatal(err)
+		} else if n != test.expected || n != walked {
+			t.Errorf("%s: expected %d entries (%d walked); got %d", test.name, test.expected, walked, n)
+		}
+	}
+}
+
+func TestTree_EstimateCount(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// without sqlite_stat1, the estimate must be within a factor of two of the exact count
+	for _, name := range []string{"Track", "IFK_TrackAlbumId", "PlaylistTrack", "Genre"} {
+		var obj, err = file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n, err := obj.tree.Count()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if estimate := obj.tree.EstimateCount(); estimate < n/2 || estimate > n*2 {
+			t.Errorf("%s: estimate %d is too far from the exact count %d", name, estimate, n)
+		}
+	}
+
+	// analyzed.db holds 2000 rows in Item; its stat was then changed to 1234 to tell it apart from other estimates
+	var analyzed = open(t, "testdata/analyzed.db")
+	defer analyzed.Close()
+
+	for name, expected := range map[string]int64{"Item": 1234, "ItemName": 1234, "Tag": 3} {
+		var obj, err = analyzed.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if estimate := obj.tree.EstimateCount(); estimate != expected {
+			t.Errorf("%s: expected estimate from sqlite_stat1 to be %d; got %d", name, expected, estimate)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package dotlite
+
+import (
+	"container/list"
+	"sync"
+)
+
+// WithPageCache makes the pager keep up to capacity recently read pages in memory, evicting the least recently
+// used page when full. Walks revisit interior pages often, so even a small cache saves many reads from the
+// underlying file. Hits and misses are reported by Pager.CacheStats.
+//
+// The cache assumes the file isn't changed while it is open, and holds pages as they were when first read.
+// RefreshSchemaCookie empties the cache when it detects a change to the schema. A capacity less than or equal
+// to zero disables the cache.
+func WithPageCache(capacity int) Option {
+	return func(f *File) {
+		if capacity > 0 {
+			f.Pager.cache = newPageCache(capacity)
+		} else {
+			f.Pager.cache = nil
+		}
+	}
+}
+
+// CacheStats reports the use of a pager's page cache
+type CacheStats struct {
+	Hits     uint64 // number of pages served from the cache
+	Misses   uint64 // number of pages read from the file and added to the cache
+	Len      int    // number of pages in the cache
+	Capacity int    // maximum number of pages in the cache
+}
+
+// CacheStats returns statistics about the use of the page cache; all zero if the pager has no cache
+func (pager *Pager) CacheStats() CacheStats {
+	if pager.cache == nil {
+		return CacheStats{}
+	}
+	return pager.cache.stats()
+}
+
+// pageCache is a least-recently-used cache of page contents, keyed by page number
+type pageCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List            // cached pages, from the most to the least recently used
+	pages    map[int]*list.Element // elements of order, keyed by page number
+
+	hits, misses uint64
+}
+
+// cachedPage is the value of an element of pageCache.order
+type cachedPage struct {
+	id  int
+	buf []byte
+}
+
+func newPageCache(capacity int) *pageCache {
+	return &pageCache{capacity: capacity, order: list.New(), pages: make(map[int]*list.Element, capacity)}
+}
+
+// get returns the content of the given page, if it is cached, marking it as the most recently used page
+func (c *pageCache) get(id int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.pages[id]; ok {
+		c.hits++
+		c.order.MoveToFront(e)
+		return e.Value.(*cachedPage).buf, true
+	}
+
+	c.misses++
+	return nil, false
+}
+
+// add caches the content of the given page, evicting the least recently used page if the cache is full
+func (c *pageCache) add(id int, buf []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.pages[id]; ok { // added concurrently since the miss
+		e.Value.(*cachedPage).buf = buf
+		c.order.MoveToFront(e)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		var last = c.order.Back()
+		c.order.Remove(last)
+		delete(c.pages, last.Value.(*cachedPage).id)
+	}
+
+	c.pages[id] = c.order.PushFront(&cachedPage{id: id, buf: buf})
+}
+
+// reset empties the cache; the statistics are kept
+func (c *pageCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.order.Init()
+	c.pages = make(map[int]*list.Element, c.capacity)
+}
+
+func (c *pageCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return CacheStats{Hits: c.hits, Misses: c.misses, Len: c.order.Len(), Capacity: c.capacity}
+}
@@ -0,0 +1,121 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithPageCache(t *testing.T) {
+	file, err := Open("testdata/chinook.db", WithPageCache(2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var walk = func() (rows int) {
+		if err := file.ForEach("Track", func(*Record) error { rows++; return nil }); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	if rows := walk(); rows != 3503 {
+		t.Errorf("expected 3503 rows; got %d", rows)
+	}
+
+	var first = file.Pager.CacheStats()
+	if first.Misses == 0 || first.Len != int(first.Misses) || first.Capacity != 2000 {
+		t.Errorf("unexpected stats after the first walk: %+v", first)
+	}
+
+	// every page of the second walk is served from the cache
+	if rows := walk(); rows != 3503 {
+		t.Errorf("expected 3503 rows; got %d", rows)
+	}
+
+	if second := file.Pager.CacheStats(); second.Misses != first.Misses || second.Hits <= first.Hits {
+		t.Errorf("expected only hits during the second walk; got %+v after %+v", second, first)
+	}
+}
+
+func TestWithPageCache_eviction(t *testing.T) {
+	file, err := Open("testdata/chinook.db", WithPageCache(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, page := range []int{1, 2, 1, 3, 2} { // reading 3 evicts 2, the least recently used page
+		if _, err = file.Pager.ReadPage(page); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var expected = CacheStats{Hits: 1, Misses: 4, Len: 2, Capacity: 2}
+	if stats := file.Pager.CacheStats(); stats != expected {
+		t.Errorf("expected %+v; got %+v", expected, stats)
+	}
+
+	// cached pages have their own read position, like uncached pages
+	a, _ := file.Pager.ReadPage(1)
+	b, _ := file.Pager.ReadPage(1)
+
+	var buf [16]byte
+	if _, err = a.Read(buf[:]); err != nil || string(buf[:]) != Magic {
+		t.Fatalf("expected to read the magic string; got %q (%v)", buf, err)
+	} else if _, err = b.Read(buf[:]); err != nil || string(buf[:]) != Magic {
+		t.Errorf("expected to read the magic string again; got %q (%v)", buf, err)
+	}
+}
+
+func TestWithPageCache_disabled(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	if _, err := file.Pager.ReadPage(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := file.Pager.CacheStats(); stats != (CacheStats{}) {
+		t.Errorf("expected no stats without a cache; got %+v", stats)
+	}
+}
+
+func TestWithPageCache_mutated_values(t *testing.T) {
+	file, err := Open("testdata/large-blob.db", WithPageCache(1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// row 3 stores its blob on the leaf page; rows 1 and 2 spill onto overflow pages
+	var read = func() (blobs [][]byte) {
+		err := file.ForEach("b", func(rec *Record) error {
+			var blob, err = rec.AsBlob(2)
+			blobs = append(blobs, blob)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return blobs
+	}
+
+	var first = read()
+	var expected = make([][]byte, len(first))
+	for i, blob := range first {
+		expected[i] = append([]byte(nil), blob...)
+		for j := range blob { // scribble over the value returned by the first read
+			blob[j] = 0xff
+		}
+	}
+
+	var stats = file.Pager.CacheStats()
+	if second := read(); !reflect.DeepEqual(second, expected) {
+		t.Errorf("expected the second read to be unaffected by changes to the values of the first")
+	}
+
+	if after := file.Pager.CacheStats(); after.Misses != stats.Misses || after.Hits <= stats.Hits {
+		t.Errorf("expected the second read to be served from the cache; got %+v after %+v", after, stats)
+	}
+}
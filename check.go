@@ -0,0 +1,537 @@
+package dotlite
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CheckError is reported by ValidateRow for a CHECK constraint that a row violates
+// or for one that couldn't be evaluated.
+type CheckError struct {
+	Name    string // name of the constraint, if one was given
+	Expr    string // source of the CHECK expression
+	Skipped bool   // true if the expression isn't supported and was not evaluated
+	Reason  string // why the expression was skipped
+}
+
+func (e *CheckError) Error() string {
+	var expr = e.Expr
+	if e.Name != "" {
+		expr = e.Name
+	}
+
+	if e.Skipped {
+		return fmt.Sprintf("CHECK constraint skipped: %s: %s", expr, e.Reason)
+	}
+	return fmt.Sprintf("CHECK constraint failed: %s", expr)
+}
+
+// ValidateRow evaluates the table's CHECK constraints against the row in rec and returns
+// an error for every constraint that the row violates.
+//
+// The evaluation is best-effort and only supports simple expressions made up of column references,
+// literals, comparisons, AND / OR / NOT, IS [NOT] NULL, BETWEEN and IN with a list of literals.
+// Values are compared using sqlite's BINARY collation and without applying column affinity.
+// Constraints using any other construct are not evaluated and are reported with a *CheckError
+// that has Skipped set to true.
+func (obj *Object) ValidateRow(rec *Record) (errs []error) {
+	var table, err = obj.schema()
+	if err != nil {
+		return []error{err}
+	}
+
+	var row = make(map[string]any, len(table.columns))
+	for i, col := range table.columns {
+		var val any
+		if i == table.rowid {
+			val = rec.cell.Rowid
+		} else if i < rec.NumValues() {
+			if val, err = rec.ValueAt(i); err != nil {
+				return []error{err}
+			}
+		}
+
+		row[strings.ToLower(col.Name)] = val
+	}
+
+	for _, c := range table.checks {
+		var ok bool
+		if ok, err = c.eval(row); err != nil {
+			errs = append(errs, &CheckError{Name: c.name, Expr: c.expr, Skipped: true, Reason: err.Error()})
+		} else if !ok {
+			errs = append(errs, &CheckError{Name: c.name, Expr: c.expr})
+		}
+	}
+
+	return errs
+}
+
+// check is a single CHECK constraint defined on a table
+type check struct {
+	name string // name of the constraint
+	expr string // source of the expression
+
+	once     sync.Once // guards compiling the expression, as the table's checks are shared by goroutines
+	compiled expr      // compiled expression; lazily populated
+	err      error     // error from compiling the expression
+}
+
+// eval evaluates the check against the given row, keyed by lower-cased column name.
+// As in sqlite, the check is only considered violated if the expression evaluates to false; NULL passes.
+func (c *check) eval(row map[string]any) (_ bool, err error) {
+	c.once.Do(func() { c.compiled, c.err = compileExpr(c.expr) })
+
+	if c.err != nil {
+		return false, c.err
+	}
+
+	var val any
+	if val, err = c.compiled(row); err != nil {
+		return false, err
+	}
+
+	return val == nil || truthy(val), nil
+}
+
+// expr is a compiled expression that can be evaluated against a row
+type expr func(row map[string]any) (any, error)
+
+// compileExpr compiles the given expression source into an evaluable expr
+func compileExpr(src string) (_ expr, err error) {
+	var tokens []token
+	if tokens, err = tokenize(src); err != nil {
+		return nil, err
+	}
+
+	var c = &compiler{parser{tokens: tokens}}
+
+	var e expr
+	if e, err = c.or(); err != nil {
+		return nil, err
+	}
+
+	if !c.done() {
+		return nil, fmt.Errorf("unsupported expression at %q", c.peek().text)
+	}
+
+	return e, nil
+}
+
+// compiler compiles expressions using recursive-descent, in increasing order of precedence
+type compiler struct{ parser }
+
+func (c *compiler) or() (_ expr, err error) {
+	var left, right expr
+	if left, err = c.and(); err != nil {
+		return nil, err
+	}
+
+	for c.keyword("OR") {
+		if right, err = c.and(); err != nil {
+			return nil, err
+		}
+		left = logical(left, right, true)
+	}
+
+	return left, nil
+}
+
+func (c *compiler) and() (_ expr, err error) {
+	var left, right expr
+	if left, err = c.not(); err != nil {
+		return nil, err
+	}
+
+	for c.keyword("AND") {
+		if right, err = c.not(); err != nil {
+			return nil, err
+		}
+		left = logical(left, right, false)
+	}
+
+	return left, nil
+}
+
+func (c *compiler) not() (_ expr, err error) {
+	if !c.keyword("NOT") {
+		return c.comparison()
+	}
+
+	var e expr
+	if e, err = c.not(); err != nil {
+		return nil, err
+	}
+	return negate(e), nil
+}
+
+func (c *compiler) comparison() (_ expr, err error) {
+	var left expr
+	if left, err = c.operand(); err != nil {
+		return nil, err
+	}
+
+	switch tok := c.peek(); {
+	case tok.is("="), tok.is("=="), tok.is("!="), tok.is("<>"), tok.is("<"), tok.is("<="), tok.is(">"), tok.is(">="):
+		c.next()
+
+		var right expr
+		if right, err = c.operand(); err != nil {
+			return nil, err
+		}
+		return compare(left, right, tok.text), nil
+
+	case tok.keyword("ISNULL"), tok.keyword("NOTNULL"):
+		c.next()
+		return isNull(left, tok.keyword("NOTNULL")), nil
+
+	case tok.keyword("IS"):
+		c.next()
+
+		var not = c.keyword("NOT")
+		if c.keyword("NULL") {
+			return isNull(left, not), nil
+		}
+
+		var right expr
+		if right, err = c.operand(); err != nil {
+			return nil, err
+		}
+		return is(left, right, not), nil
+	}
+
+	var not = c.keyword("NOT")
+	switch {
+	case not && c.keyword("NULL"):
+		return isNull(left, true), nil
+
+	case c.keyword("BETWEEN"):
+		var lo, hi expr
+		if lo, err = c.operand(); err != nil {
+			return nil, err
+		}
+
+		if !c.keyword("AND") {
+			return nil, fmt.Errorf("unsupported expression: expected AND in BETWEEN")
+		}
+
+		if hi, err = c.operand(); err != nil {
+			return nil, err
+		}
+
+		var e = logical(compare(left, lo, ">="), compare(left, hi, "<="), false)
+		if not {
+			e = negate(e)
+		}
+		return e, nil
+
+	case c.keyword("IN"):
+		if !c.op("(") {
+			return nil, fmt.Errorf("unsupported expression: expected a list of values in IN")
+		}
+
+		var list []expr
+		for !c.op(")") {
+			var e expr
+			if e, err = c.literal(); err != nil {
+				return nil, err
+			}
+			list = append(list, e)
+
+			if !c.op(",") && !c.peek().is(")") {
+				return nil, fmt.Errorf("unsupported expression: unterminated IN list")
+			}
+		}
+
+		var e = in(left, list)
+		if not {
+			e = negate(e)
+		}
+		return e, nil
+
+	case not:
+		return nil, fmt.Errorf("unsupported expression at NOT %q", c.peek().text)
+	}
+
+	return left, nil
+}
+
+func (c *compiler) operand() (_ expr, err error) {
+	if c.op("(") {
+		var e expr
+		if e, err = c.or(); err != nil {
+			return nil, err
+		}
+
+		if !c.op(")") {
+			return nil, fmt.Errorf("unsupported expression: unterminated (")
+		}
+		return e, nil
+	}
+
+	if tok := c.peek(); tok.kind == tokenIdent && !c.peek().keyword("NULL") && !c.peek().keyword("TRUE") && !c.peek().keyword("FALSE") {
+		c.next()
+		if c.peek().is("(") || c.peek().is(".") {
+			return nil, fmt.Errorf("unsupported expression at %q", tok.text)
+		}
+
+		var name = strings.ToLower(tok.text)
+		return func(row map[string]any) (any, error) {
+			if val, ok := row[name]; ok {
+				return val, nil
+			}
+			return nil, fmt.Errorf("no such column: %s", tok.text)
+		}, nil
+	}
+
+	return c.literal()
+}
+
+// literal compiles a (possibly signed) literal value
+func (c *compiler) literal() (_ expr, err error) {
+	var val any
+	switch tok := c.next(); {
+	case tok.keyword("NULL"):
+		val = nil
+	case tok.keyword("TRUE"):
+		val = int64(1)
+	case tok.keyword("FALSE"):
+		val = int64(0)
+	case tok.kind == tokenString:
+		val = tok.text
+	case tok.kind == tokenBlob:
+		var b = make([]byte, len(tok.text)/2)
+		for i := range b {
+			var n uint64
+			if n, err = strconv.ParseUint(tok.text[2*i:2*i+2], 16, 8); err != nil {
+				return nil, fmt.Errorf("malformed blob literal: %w", err)
+			}
+			b[i] = byte(n)
+		}
+		val = b
+	case tok.kind == tokenNumber:
+		if val, err = number(tok.text); err != nil {
+			return nil, err
+		}
+	case tok.is("-"), tok.is("+"):
+		var num = c.next()
+		if num.kind != tokenNumber {
+			return nil, fmt.Errorf("unsupported expression at %q", tok.text)
+		}
+
+		if val, err = number(tok.text + num.text); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported expression at %q", tok.text)
+	}
+
+	return func(map[string]any) (any, error) { return val, nil }, nil
+}
+
+// number parses a numeric literal into either an int64 or a float64
+func number(s string) (any, error) {
+	if i, err := strconv.ParseInt(s, 0, 64); err == nil {
+		return i, nil
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+
+	return nil, fmt.Errorf("malformed numeric literal %q", s)
+}
+
+// logical combines left and right using sqlite's three-valued AND / OR logic
+func logical(left, right expr, or bool) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var l, r any
+		if l, err = left(row); err != nil {
+			return nil, err
+		}
+
+		if r, err = right(row); err != nil {
+			return nil, err
+		}
+
+		// for OR a single true decides the result; for AND a single false does
+		if (l != nil && truthy(l) == or) || (r != nil && truthy(r) == or) {
+			return boolean(or), nil
+		} else if l == nil || r == nil {
+			return nil, nil
+		}
+
+		return boolean(!or), nil
+	}
+}
+
+func negate(e expr) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var v any
+		if v, err = e(row); err != nil || v == nil {
+			return nil, err
+		}
+		return boolean(!truthy(v)), nil
+	}
+}
+
+func compare(left, right expr, op string) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var l, r any
+		if l, err = left(row); err != nil {
+			return nil, err
+		}
+
+		if r, err = right(row); err != nil {
+			return nil, err
+		}
+
+		if l == nil || r == nil {
+			return nil, nil
+		}
+
+		var n = compareValues(l, r)
+		switch op {
+		case "=", "==":
+			return boolean(n == 0), nil
+		case "!=", "<>":
+			return boolean(n != 0), nil
+		case "<":
+			return boolean(n < 0), nil
+		case "<=":
+			return boolean(n <= 0), nil
+		case ">":
+			return boolean(n > 0), nil
+		default: // ">="
+			return boolean(n >= 0), nil
+		}
+	}
+}
+
+func isNull(e expr, not bool) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var v any
+		if v, err = e(row); err != nil {
+			return nil, err
+		}
+		return boolean((v == nil) != not), nil
+	}
+}
+
+// is implements the IS / IS NOT operators which, unlike =, treat NULLs as equal
+func is(left, right expr, not bool) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var l, r any
+		if l, err = left(row); err != nil {
+			return nil, err
+		}
+
+		if r, err = right(row); err != nil {
+			return nil, err
+		}
+
+		var eq = (l == nil && r == nil) || (l != nil && r != nil && compareValues(l, r) == 0)
+		return boolean(eq != not), nil
+	}
+}
+
+func in(e expr, list []expr) expr {
+	return func(row map[string]any) (_ any, err error) {
+		var v any
+		if v, err = e(row); err != nil || v == nil {
+			return nil, err
+		}
+
+		var null = false
+		for _, item := range list {
+			var i any
+			if i, err = item(row); err != nil {
+				return nil, err
+			}
+
+			if i == nil {
+				null = true
+			} else if compareValues(v, i) == 0 {
+				return boolean(true), nil
+			}
+		}
+
+		if null {
+			return nil, nil
+		}
+		return boolean(false), nil
+	}
+}
+
+// compareValues compares two non-NULL values following sqlite's sort order,
+// where numeric values sort before text, which sorts before blobs.
+//
+// see: https://www.sqlite.org/datatype3.html#sort_order
+func compareValues(a, b any) int {
+	var class = func(v any) int {
+		switch v.(type) {
+		case int64, float64:
+			return 0
+		case string:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	if ca, cb := class(a), class(b); ca != cb {
+		return ca - cb
+	}
+
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return compareOrdered(a, b)
+		}
+		return compareOrdered(float64(a), b.(float64))
+	case float64:
+		if b, ok := b.(int64); ok {
+			return compareOrdered(a, float64(b))
+		}
+		return compareOrdered(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	default:
+		var x, _ = a.([]byte)
+		var y, _ = b.([]byte)
+		return bytes.Compare(x, y)
+	}
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+// truthy reports whether a non-NULL value is considered true, which, in sqlite, is any non-zero numeric value
+func truthy(v any) bool {
+	switch v := v.(type) {
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		var f, _ = strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f != 0
+	default:
+		return false
+	}
+}
+
+func boolean(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package dotlite
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestObject_ValidateRow(t *testing.T) {
+	var file = open(t, "testdata/check-constraints.db")
+	defer file.Close()
+
+	table, err := file.Object("product")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expected (non-skipped) violations, keyed by rowid
+	var expected = map[int64][]string{
+		1: nil,
+		2: {"CHECK constraint failed: positive_price", "CHECK constraint failed: status IN ('active', 'retired')"},
+		3: {"CHECK constraint failed: status = 'retired' OR price > 0"},
+		4: nil,
+	}
+
+	err = table.ForEach(func(rec *Record) error {
+		var violations []string
+		for _, err := range table.ValidateRow(rec) {
+			var ce *CheckError
+			if !errors.As(err, &ce) {
+				t.Errorf("unexpected error: %v", err)
+			} else if ce.Skipped {
+				if ce.Expr != "length(code) = 4" {
+					t.Errorf("unexpected skipped constraint: %v", err)
+				}
+			} else {
+				violations = append(violations, err.Error())
+			}
+		}
+
+		var want = expected[rec.cell.Rowid]
+		if len(violations) != len(want) {
+			t.Fatalf("row(%d): expected violations %q; got %q", rec.cell.Rowid, want, violations)
+		}
+
+		for i := range want {
+			if violations[i] != want[i] {
+				t.Errorf("row(%d): expected violation %q; got %q", rec.cell.Rowid, want[i], violations[i])
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheck_eval_concurrent(t *testing.T) {
+	// a table's checks are shared by every goroutine validating its rows, and are compiled on first use (run with -race)
+	var c = &check{expr: "price > 0"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(price int64) {
+			defer wg.Done()
+			if ok, err := c.eval(map[string]any{"price": price}); err != nil || ok != (price > 0) {
+				t.Errorf("price %d: expected %t; got %t (err=%v)", price, price > 0, ok, err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+}
+
+func TestCompileExpr(t *testing.T) {
+	var row = map[string]any{"a": int64(5), "b": "text", "c": nil, "d": 2.5}
+
+	var cases = map[string]any{
+		"a > 4":                   int64(1),
+		"a = 5.0":                 int64(1),
+		"a <> 5 OR b == 'text'":   int64(1),
+		"a > 4 AND c > 1":         nil,
+		"a > 10 AND c > 1":        int64(0),
+		"c IS NULL":               int64(1),
+		"c NOTNULL":               int64(0),
+		"b IS NOT 'text'":         int64(0),
+		"NOT (d BETWEEN 2 AND 3)": int64(0),
+		"a NOT IN (1, 2, NULL)":   nil,
+		"a IN (-1, 5)":            int64(1),
+		"b > 100":                 int64(1),
+	}
+
+	for src, want := range cases {
+		var e, err = compileExpr(src)
+		if err != nil {
+			t.Errorf("%q: failed to compile: %v", src, err)
+			continue
+		}
+
+		if got, err := e(row); err != nil {
+			t.Errorf("%q: failed to evaluate: %v", src, err)
+		} else if got != want {
+			t.Errorf("%q: expected %v; got %v", src, want, got)
+		}
+	}
+
+	for _, src := range []string{"length(b) > 1", "a + 1 > 2", "b LIKE 'x%'", "a IN (SELECT 1)"} {
+		if _, err := compileExpr(src); err == nil {
+			t.Errorf("%q: expected expression to be unsupported", src)
+		}
+	}
+}
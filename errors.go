@@ -0,0 +1,32 @@
+package dotlite
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors describing the kind of a failure. Errors returned while opening and reading a file wrap one of these,
+// so that callers can tell them apart with errors.Is; the message of the returned error holds the details.
+var (
+	ErrInvalidMagic       = errors.New("file doesn't start with the sqlite magic string")
+	ErrUnsupportedVersion = errors.New("file format version isn't supported")
+	ErrInvalidHeader      = errors.New("database header holds invalid values")
+	ErrPageOutOfRange     = errors.New("page is out of range")
+	ErrCorruptPage        = errors.New("page is corrupt")
+	ErrCorruptCell        = errors.New("cell is corrupt")
+	ErrCorruptRecord      = errors.New("record is corrupt")
+)
+
+// kindError is an error of a kind given by one of the errors above, with a message of its own
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string { return e.msg }
+func (e *kindError) Unwrap() error { return e.kind }
+
+// errorf formats an error of the given kind; unlike wrapping with fmt.Errorf, the kind isn't part of the message
+func errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,57 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	chinook, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// patched returns a copy of chinook.db changed by fn
+	var patched = func(fn func(b []byte)) []byte {
+		var b = append([]byte{}, chinook...)
+		fn(b)
+		return b
+	}
+
+	// walk opens b and walks the Track table
+	var walk = func(b []byte) error {
+		file, err := OpenBytes(b)
+		if err != nil {
+			return err
+		}
+		return file.ForEach("Track", func(*Record) error { return nil })
+	}
+
+	for _, test := range []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		// the payload fractions are changed too, else the file would be taken for an encrypted one
+		{"invalid magic", walk(patched(func(b []byte) { b[0], b[21] = 0, 0 })), ErrInvalidMagic},
+		{"unsupported version", walk(patched(func(b []byte) { b[19] = 3 })), ErrUnsupportedVersion},
+		{"invalid page size", walk(patched(func(b []byte) { binary.BigEndian.PutUint16(b[16:], 1000) })), ErrInvalidHeader},
+		{"invalid payload fractions", walk(patched(func(b []byte) { b[21] = 42 })), ErrInvalidHeader},
+		{"invalid page type", walk(patched(func(b []byte) { b[408*1024] = 0 })), ErrCorruptPage},
+		{"cycle", walk(patched(func(b []byte) { binary.BigEndian.PutUint32(b[251*1024+8:], 409) })), ErrCorruptPage},
+		{"malformed record", func() error { _, err := DecodeRecord(UTF8, []byte{5, 1}); return err }(), ErrCorruptRecord},
+		{"page out of range", func() error {
+			file, _ := OpenBytes(chinook)
+			_, err := file.Pager.ReadPage(0)
+			return err
+		}(), ErrPageOutOfRange},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if !errors.Is(test.err, test.expected) {
+				t.Errorf("expected an error wrapping %q; got %v", test.expected, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Freelist is the list of unused pages of the database file: a chain of trunk pages, each listing a number of
+// free leaf pages. Freed pages aren't cleared unless secure_delete is on, so they may still hold deleted content.
+//
+// see: https://www.sqlite.org/fileformat.html#the_freelist
+type Freelist struct {
+	file  *File
+	first int // first trunk page; zero if the freelist is empty
+	count int // total number of free pages, according to the header
+}
+
+// Freelist returns the freelist of the database, as described by the database header
+func (f *File) Freelist() (*Freelist, error) {
+	var first, count = int(f.Header.FreePage), int(f.Header.TotalFreePages)
+	if first < 0 || first > f.NumPages() {
+		return nil, errorf(ErrCorruptPage, "first freelist trunk page %d is out of range", first)
+	}
+
+	return &Freelist{file: f, first: first, count: count}, nil
+}
+
+// First returns the page number of the first trunk page; zero if the freelist is empty
+func (list *Freelist) First() int { return list.first }
+
+// Count returns the total number of free pages, trunk and leaf pages included, according to the database header
+func (list *Freelist) Count() int { return list.count }
+
+// Walk invokes fn with the page number of every page of the freelist, following the chain of trunk pages.
+// Each trunk page is passed before the leaf pages it lists. As with Tree.Walk, fn may return ErrStopIteration
+// to end the walk early.
+//
+// A page appearing twice in the freelist is reported as an error, which also guards against cycles in the chain.
+func (list *Freelist) Walk(fn func(pageID int) error) (err error) {
+	if err = list.walk(func(page int, _ PageKind) error { return fn(page) }); errors.Is(err, ErrStopIteration) {
+		err = nil
+	}
+	return err
+}
+
+// walk invokes fn with every page of the freelist, along with whether it is a trunk or a leaf page
+func (list *Freelist) walk(fn func(page int, kind PageKind) error) (err error) {
+	var max = list.file.Header.usableSize()/4 - 2 // maximum number of leaves a trunk page can list
+	var seen = make(map[int]bool)
+
+	var visit = func(page int, kind PageKind) error {
+		if page < 1 || page > list.file.NumPages() {
+			return errorf(ErrCorruptPage, "freelist page %d is out of range", page)
+		} else if seen[page] {
+			return errorf(ErrCorruptPage, "page %d appears twice in the freelist", page)
+		}
+
+		seen[page] = true
+		return fn(page, kind)
+	}
+
+	for trunk := list.first; trunk != 0; {
+		if err = visit(trunk, PageFreelistTrunk); err != nil {
+			return err
+		}
+
+		var page *Page
+		if page, err = list.file.Pager.ReadPage(trunk); err != nil {
+			return err
+		}
+
+		var header [2]uint32 // next trunk page and number of leaf pages
+		if err = binary.Read(page, binary.BigEndian, &header); err != nil {
+			return err
+		} else if int(header[1]) > max {
+			return errorf(ErrCorruptPage, "freelist trunk page %d lists %d leaves; at most %d fit", trunk, header[1], max)
+		}
+
+		var leaves = make([]uint32, header[1])
+		if err = binary.Read(page, binary.BigEndian, leaves); err != nil {
+			return err
+		}
+
+		for _, leaf := range leaves {
+			if err = visit(int(leaf), PageFreelistLeaf); err != nil {
+				return err
+			}
+		}
+
+		trunk = int(header[0])
+	}
+
+	return nil
+}
+
+// Pages returns the free leaf pages of the freelist, in the order Walk visits them, to scan for the remains of
+// deleted content. Trunk pages, which hold the list itself, aren't included.
+//
+// Pages are read straight from the pager: a free page holds whatever was last written to it (or zeroes), and
+// is never expected to be a valid b-tree page.
+func (list *Freelist) Pages() (pages []*Page, err error) {
+	err = list.walk(func(id int, kind PageKind) (err error) {
+		if kind != PageFreelistLeaf {
+			return nil
+		}
+
+		var page *Page
+		if page, err = list.file.Pager.ReadPage(id); err != nil {
+			return err
+		}
+
+		pages = append(pages, page)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
@@ -0,0 +1,142 @@
+package dotlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile_Freelist(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	list, err := file.Freelist()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// values as reported by PRAGMA freelist_count and the database header
+	if list.First() != 8 || list.Count() != 187 {
+		t.Errorf("expected first trunk page 8 and 187 free pages; got %d and %d", list.First(), list.Count())
+	}
+
+	var free = make(map[int]bool)
+	err = list.Walk(func(page int) error {
+		if len(free) == 0 && page != list.First() {
+			t.Errorf("expected the walk to start at the first trunk page; got %d", page)
+		}
+		free[page] = true
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(free) != list.Count() {
+		t.Errorf("expected %d distinct free pages; got %d", list.Count(), len(free))
+	}
+
+	// no free page may belong to a table or an index
+	objects, err := file.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, obj := range objects {
+		pages, err := obj.tree.Pages()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, page := range pages {
+			if free[page] {
+				t.Errorf("page %d of %s is on the freelist", page, obj.Name())
+			}
+		}
+	}
+
+	var n int
+	if err = list.Walk(func(int) error { n++; return ErrStopIteration }); err != nil || n != 1 {
+		t.Errorf("expected the walk to stop after one page; got %d pages and %v", n, err)
+	}
+}
+
+func TestFreelist_Pages(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	list, err := file.Freelist()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pages, err := list.Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// all free pages but the single trunk page
+	if len(pages) != 186 {
+		t.Fatalf("expected 186 free leaf pages; got %d", len(pages))
+	}
+
+	var b = read(t, "testdata/chinook.db")
+	for _, page := range pages {
+		if page.ID == list.First() {
+			t.Errorf("unexpected trunk page %d", page.ID)
+		}
+
+		var buf = make([]byte, file.PageSize())
+		if _, err = io.ReadFull(page, buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if offset := (page.ID - 1) * file.PageSize(); !bytes.Equal(buf, b[offset:offset+file.PageSize()]) {
+			t.Errorf("content of page %d doesn't match the database file", page.ID)
+		}
+	}
+}
+
+func TestFile_Freelist_empty(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	list, err := file.Freelist()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = list.Walk(func(page int) error { t.Errorf("unexpected free page %d", page); return nil }); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFile_Freelist_cycle(t *testing.T) {
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// point the only trunk page (page 8) back at itself
+	binary.BigEndian.PutUint32(b[7*1024:], 8)
+
+	var name = filepath.Join(t.TempDir(), "cycle.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var file = open(t, name)
+	defer file.Close()
+
+	list, err := file.Freelist()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = "page 8 appears twice in the freelist"
+	if err = list.Walk(func(int) error { return nil }); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
+	}
+}
@@ -0,0 +1,77 @@
+package dotlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzOpen feeds arbitrary bytes to Open and exercises the schema and a bounded number of records
+// of every object, ensuring that a malformed file results in an error rather than a panic (or an attempt to allocate
+// whatever size the file declares), and that the error wraps one of the exported errors describing its kind.
+//
+// Run it using: go test -run=^$ -fuzz=FuzzOpen
+func FuzzOpen(f *testing.F) {
+	for _, name := range []string{"all-kinds.db", "overflow.db", "without-rowid.db", "indexes.db", "added-columns.db", "not-a-database.txt", "oversized-payload.db"} {
+		var b, err = os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var name = filepath.Join(t.TempDir(), "fuzz.db")
+		if err := os.WriteFile(name, b, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var file, err = Open(name)
+		if err != nil {
+			checkKind(t, err)
+			return
+		}
+		defer file.Close()
+
+		var objects []*Object
+		if objects, err = file.Schema(); err != nil {
+			checkKind(t, err)
+			return
+		}
+
+		for _, obj := range objects {
+			var n = 0
+			err = obj.ForEach(func(rec *Record) error {
+				for i := 0; i < rec.NumValues(); i++ {
+					if _, err := rec.ValueAt(i); err != nil {
+						return err
+					}
+				}
+
+				if n++; n >= 100 {
+					return ErrStopIteration
+				}
+				return nil
+			})
+
+			if err != nil && !errors.Is(err, ErrStopIteration) {
+				checkKind(t, err)
+			}
+		}
+	})
+}
+
+// checkKind fails the test if err doesn't wrap one of the exported errors describing the kind of a failure
+func checkKind(t *testing.T, err error) {
+	t.Helper()
+	for _, kind := range []error{
+		ErrInvalidMagic, ErrUnsupportedVersion, ErrInvalidHeader, ErrPageOutOfRange, ErrCorruptPage, ErrCorruptCell,
+		ErrCorruptRecord, ErrTruncatedHeader, ErrEncrypted, ErrHotJournal, ErrNotFound,
+	} {
+		if errors.Is(err, kind) {
+			return
+		}
+	}
+	t.Errorf("error doesn't wrap any of the exported errors: %v", err)
+}
@@ -0,0 +1,60 @@
+package dotlite
+
+import (
+	"math"
+	"math/bits"
+)
+
+// hyperLogLog is a HyperLogLog sketch estimating the number of distinct 64-bit hashes added to it,
+// using a fixed amount of memory regardless of how many hashes are added.
+//
+// see: https://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf
+type hyperLogLog struct {
+	p         uint8   // number of bits of the hash used to select a register
+	registers []uint8 // largest rank observed by each register
+}
+
+// newHyperLogLog returns a sketch with 2^p registers; its standard error is about 1.04/sqrt(2^p)
+func newHyperLogLog(p uint8) *hyperLogLog {
+	return &hyperLogLog{p: p, registers: make([]uint8, 1<<p)}
+}
+
+// add adds the hash x to the sketch
+func (h *hyperLogLog) add(x uint64) {
+	var i = x >> (64 - h.p)
+	var rank = uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// estimate returns the estimated number of distinct hashes added to the sketch
+func (h *hyperLogLog) estimate() int64 {
+	var m = float64(len(h.registers))
+
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	var e = 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 { // use linear counting for small cardinalities
+		e = m * math.Log(m/float64(zeros))
+	}
+
+	return int64(math.Round(e))
+}
+
+// mix64 scrambles the bits of x (using splitmix64's finalizer), so that every bit of the result depends on every bit of x
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,346 @@
+package dotlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// indexSchema holds information parsed from a CREATE INDEX statement
+type indexSchema struct {
+	name    string
+	table   string // name of the table the index is defined on
+	unique  bool
+	columns []*indexedColumn
+	where   string // predicate of a partial index; empty if the index covers all rows
+}
+
+// indexedColumn is a single column (or expression) in an index definition
+type indexedColumn struct {
+	name    string // name of the column, or source of the expression
+	expr    bool   // true if the indexed value is an expression rather than a column
+	collate string
+	desc    bool
+}
+
+// parseIndex parses the CREATE INDEX statement in sql.
+// see: https://www.sqlite.org/lang_createindex.html
+func parseIndex(sql string) (_ *indexSchema, err error) {
+	var tokens []token
+	if tokens, err = tokenize(sql); err != nil {
+		return nil, err
+	}
+
+	var p = &parser{tokens: tokens}
+	if !p.keyword("CREATE") {
+		return nil, fmt.Errorf("invalid index schema: expected CREATE")
+	}
+
+	var index = &indexSchema{unique: p.keyword("UNIQUE")}
+	if !p.keyword("INDEX") {
+		return nil, fmt.Errorf("invalid index schema: expected INDEX")
+	}
+	if p.keyword("IF") && !(p.keyword("NOT") && p.keyword("EXISTS")) {
+		return nil, fmt.Errorf("invalid index schema: malformed IF NOT EXISTS clause")
+	}
+
+	if index.name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.op(".") {
+		if index.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.keyword("ON") {
+		return nil, fmt.Errorf("invalid index schema: expected ON")
+	}
+
+	if index.table, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if !p.op("(") {
+		return nil, fmt.Errorf("invalid index schema: expected indexed columns for %q", index.name)
+	}
+
+	for {
+		var def = p.element()
+		if len(def) == 0 {
+			return nil, fmt.Errorf("invalid index schema: empty column in %q", index.name)
+		}
+
+		// trailing ASC / DESC and COLLATE clauses are peeled off; whatever remains is the indexed column or expression
+		var col = &indexedColumn{}
+		if n := len(def); def[n-1].keyword("DESC") || def[n-1].keyword("ASC") {
+			col.desc = def[n-1].keyword("DESC")
+			def = def[:n-1]
+		}
+		if n := len(def); n > 2 && def[n-2].keyword("COLLATE") {
+			col.collate = def[n-1].text
+			def = def[:n-2]
+		}
+
+		if len(def) == 1 && (def[0].kind == tokenIdent || def[0].kind == tokenString) {
+			col.name = def[0].text
+		} else if len(def) > 0 {
+			col.name, col.expr = def[0].src[def[0].pos:def[len(def)-1].end], true
+		} else {
+			return nil, fmt.Errorf("invalid index schema: missing column in %q", index.name)
+		}
+		index.columns = append(index.columns, col)
+
+		if p.op(",") {
+			continue
+		} else if p.op(")") {
+			break
+		}
+
+		return nil, fmt.Errorf("invalid index schema: unterminated column list in %q", index.name)
+	}
+
+	if p.keyword("WHERE") {
+		if p.done() {
+			return nil, fmt.Errorf("invalid index schema: missing WHERE predicate in %q", index.name)
+		}
+
+		var start = p.peek()
+		var end = tokens[len(tokens)-1]
+		if end.is(";") {
+			end = tokens[len(tokens)-2]
+		}
+		index.where = strings.TrimSpace(start.src[start.pos:end.end])
+	}
+
+	return index, nil
+}
+
+// Index describes an index as defined by its CREATE INDEX statement
+type Index struct{ schema *indexSchema }
+
+// IndexedColumn is a single column (or expression) in an index definition
+type IndexedColumn struct {
+	Name    string // name of the column, or source of the expression
+	Expr    bool   // true if the indexed value is an expression rather than a column
+	Collate string // collation sequence named in the definition; empty if none is
+	Desc    bool   // true if the values are sorted in descending order
+}
+
+// Index parses the CREATE INDEX statement of the object, describing the table and columns it covers.
+//
+// Automatic indexes (named sqlite_autoindex_*) are not described by any sql and are not supported.
+func (obj *Object) Index() (_ *Index, err error) {
+	if obj.kind != ObjectIndex {
+		return nil, fmt.Errorf("object %q is not an index", obj.name)
+	} else if obj.sql == "" {
+		return nil, fmt.Errorf("index %q has no sql definition (automatic index?)", obj.name)
+	}
+
+	var index *indexSchema
+	if index, err = parseIndex(obj.sql); err != nil {
+		return nil, fmt.Errorf("failed to parse schema for %q: %w", obj.name, err)
+	}
+
+	return &Index{schema: index}, nil
+}
+
+// Name returns the name of the index
+func (idx *Index) Name() string { return idx.schema.name }
+
+// Table returns the name of the table the index is defined on
+func (idx *Index) Table() string { return idx.schema.table }
+
+// Unique reports whether the index is a UNIQUE index
+func (idx *Index) Unique() bool { return idx.schema.unique }
+
+// Where returns the predicate of a partial index (the expression following WHERE, as written in its definition),
+// or an empty string if the index covers every row of the table
+func (idx *Index) Where() string { return idx.schema.where }
+
+// Columns returns the indexed columns (or expressions), in the order they are declared
+func (idx *Index) Columns() []IndexedColumn {
+	var columns = make([]IndexedColumn, len(idx.schema.columns))
+	for i, col := range idx.schema.columns {
+		columns[i] = IndexedColumn{Name: col.name, Expr: col.expr, Collate: col.collate, Desc: col.desc}
+	}
+	return columns
+}
+
+// IndexColumnRole describes the value stored at a single position of an index record
+type IndexColumnRole struct {
+	Name    string // name of the table column (or source of the expression) whose value is stored at this position
+	Collate string // collation sequence used to order the value; empty if the default (BINARY) is used
+	Desc    bool   // true if the values are sorted in descending order
+
+	// Key is true if the value is part of the table's key that sqlite appends to every index record
+	// to locate the row: the rowid (named "rowid") for ordinary tables, or the primary key columns
+	// for WITHOUT ROWID tables.
+	Key bool
+}
+
+// RecordLayout describes the shape of the records stored in an index: the explicitly indexed columns
+// (or expressions) followed by the key of the table's row, so that values in a Record read from the
+// index can be interpreted without guessing which position holds the rowid.
+//
+// Automatic indexes (named sqlite_autoindex_*) are not described by any sql and are not supported.
+func (obj *Object) RecordLayout() (_ []IndexColumnRole, err error) {
+	if obj.kind != ObjectIndex {
+		return nil, fmt.Errorf("object %q is not an index", obj.name)
+	} else if obj.sql == "" {
+		return nil, fmt.Errorf("index %q has no sql definition (automatic index?)", obj.name)
+	}
+
+	var index *indexSchema
+	if index, err = parseIndex(obj.sql); err != nil {
+		return nil, fmt.Errorf("failed to parse schema for %q: %w", obj.name, err)
+	}
+
+	var tableObj *Object
+	if tableObj, err = obj.tree.file.Object(index.table); err != nil {
+		return nil, err
+	}
+
+	var table *tableSchema
+	if table, err = tableObj.schema(); err != nil {
+		return nil, err
+	}
+
+	var column = func(name string) *Column {
+		for _, col := range table.columns {
+			if strings.EqualFold(col.Name, name) {
+				return col
+			}
+		}
+		return nil
+	}
+
+	var layout []IndexColumnRole
+	for _, ic := range index.columns {
+		var role = IndexColumnRole{Name: ic.name, Collate: ic.collate, Desc: ic.desc}
+		if col := column(ic.name); col != nil && !ic.expr {
+			role.Name = col.Name
+			if role.Collate == "" { // index inherits the column's collation
+				role.Collate = col.Collate
+			}
+		}
+		layout = append(layout, role)
+	}
+
+	if !table.withoutRowid {
+		return append(layout, IndexColumnRole{Name: "rowid", Key: true}), nil
+	}
+
+	// primary key columns of a WITHOUT ROWID table that aren't already part of the index are appended
+next:
+	for _, name := range table.primaryKey {
+		for _, ic := range index.columns {
+			if !ic.expr && strings.EqualFold(ic.name, name) {
+				continue next
+			}
+		}
+
+		var role = IndexColumnRole{Name: name, Key: true}
+		if col := column(name); col != nil {
+			role.Name, role.Collate = col.Name, col.Collate
+		}
+		layout = append(layout, role)
+	}
+
+	return layout, nil
+}
+
+// RowidOrder walks the index in order and returns the rowid of every entry, that is, the rowids of the table's rows
+// sorted the way the index sorts them. Reading the rows in that order (eg. with Tree.LeafPageFor) reads the table
+// in the index's order without sorting it.
+//
+// The rowids are collected in memory, which takes 8 bytes for every entry of the index; for large indexes, consider
+// walking the index with ForEach instead. RowidOrder returns an error for an index on a WITHOUT ROWID table,
+// whose rows have no rowid; see KeyOrder.
+func (obj *Object) RowidOrder() (_ []int64, err error) {
+	var layout []IndexColumnRole
+	if layout, err = obj.RecordLayout(); err != nil {
+		return nil, err
+	}
+
+	if last := layout[len(layout)-1]; !last.Key || last.Name != "rowid" {
+		return nil, fmt.Errorf("index %q is defined on a WITHOUT ROWID table", obj.name)
+	}
+
+	var rowids []int64
+	err = obj.ForEach(func(rec *Record) (err error) {
+		var rowid int64
+		if rowid, err = rec.AsInt64(rec.NumValues() - 1); err != nil {
+			return err
+		}
+		rowids = append(rowids, rowid)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return rowids, nil
+}
+
+// KeyOrder is like RowidOrder, but returns the key of the table's row for every entry of the index:
+// a single rowid for an ordinary table, or the values of the primary key columns (in the order they are
+// declared in the primary key) for a WITHOUT ROWID table.
+//
+// As with RowidOrder, every key is held in memory.
+func (obj *Object) KeyOrder() (_ [][]any, err error) {
+	var layout []IndexColumnRole
+	if layout, err = obj.RecordLayout(); err != nil {
+		return nil, err
+	}
+
+	// positions in the index record holding the values of the table's key
+	var positions []int
+	if last := layout[len(layout)-1]; last.Key && last.Name == "rowid" {
+		positions = []int{len(layout) - 1}
+	} else {
+		var index *indexSchema
+		if index, err = parseIndex(obj.sql); err != nil {
+			return nil, err
+		}
+
+		var table *Object
+		if table, err = obj.tree.file.Object(index.table); err != nil {
+			return nil, err
+		}
+
+		var schema *tableSchema
+		if schema, err = table.schema(); err != nil {
+			return nil, err
+		}
+
+	next:
+		for _, name := range schema.primaryKey {
+			for i, role := range layout {
+				if strings.EqualFold(role.Name, name) {
+					positions = append(positions, i)
+					continue next
+				}
+			}
+			return nil, fmt.Errorf("primary key column %q not found in index %q", name, obj.name)
+		}
+	}
+
+	var keys [][]any
+	err = obj.ForEach(func(rec *Record) (err error) {
+		var key = make([]any, len(positions))
+		for i, pos := range positions {
+			if key[i], err = rec.ValueAt(pos); err != nil {
+				return err
+			}
+		}
+		keys = append(keys, key)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,188 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIndex(t *testing.T) {
+	var index, err = parseIndex(`CREATE UNIQUE INDEX IF NOT EXISTS main.idx ON "order" (a, b COLLATE NOCASE DESC, lower(c) ASC) WHERE a > 0 AND b IS NOT NULL;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if index.name != "idx" || index.table != "order" || !index.unique {
+		t.Errorf("unexpected index definition: %+v", *index)
+	}
+
+	var columns = []indexedColumn{{name: "a"}, {name: "b", collate: "NOCASE", desc: true}, {name: "lower(c)", expr: true}}
+	if len(index.columns) != len(columns) {
+		t.Fatalf("expected %d columns; got %d", len(columns), len(index.columns))
+	}
+
+	for i, col := range index.columns {
+		if *col != columns[i] {
+			t.Errorf("expected column(%d) to be %+v; got %+v", i, columns[i], *col)
+		}
+	}
+
+	if index.where != "a > 0 AND b IS NOT NULL" {
+		t.Errorf("unexpected partial index predicate %q", index.where)
+	}
+}
+
+func TestObject_Index(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	obj, err := file.Object("person_email")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var index *Index
+	if index, err = obj.Index(); err != nil {
+		t.Fatal(err)
+	}
+
+	if index.Name() != "person_email" || index.Table() != "person" || index.Unique() {
+		t.Errorf("unexpected index definition: name=%q table=%q unique=%v", index.Name(), index.Table(), index.Unique())
+	}
+
+	var columns = []IndexedColumn{{Name: "lower(email)", Expr: true, Collate: "BINARY"}}
+	if !reflect.DeepEqual(index.Columns(), columns) {
+		t.Errorf("expected columns %+v; got %+v", columns, index.Columns())
+	}
+
+	if where := index.Where(); where != "email IS NOT NULL" {
+		t.Errorf("unexpected partial index predicate %q", where)
+	}
+
+	if obj, err = file.Object("person_name_age"); err != nil {
+		t.Fatal(err)
+	} else if index, err = obj.Index(); err != nil {
+		t.Fatal(err)
+	}
+
+	columns = []IndexedColumn{{Name: "name"}, {Name: "age", Desc: true}}
+	if !reflect.DeepEqual(index.Columns(), columns) || index.Where() != "" {
+		t.Errorf("expected columns %+v and no predicate; got %+v and %q", columns, index.Columns(), index.Where())
+	}
+
+	if obj, _ = file.Object("person"); obj != nil {
+		if _, err = obj.Index(); err == nil {
+			t.Errorf("expected error for a table")
+		}
+	}
+}
+
+func TestObject_RecordLayout(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	for name, expected := range map[string][]IndexColumnRole{
+		"person_name_age": {{Name: "name", Collate: "NOCASE"}, {Name: "age", Desc: true}, {Name: "rowid", Key: true}},
+		"person_email":    {{Name: "lower(email)", Collate: "BINARY"}, {Name: "rowid", Key: true}},
+		"membership_role": {{Name: "role"}, {Name: "org"}, {Name: "member", Key: true}},
+	} {
+		index, err := file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		layout, err := index.RecordLayout()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(layout, expected) {
+			t.Errorf("%s: expected layout %+v; got %+v", name, expected, layout)
+		}
+
+		// every record in the index must match the described layout
+		err = index.ForEach(func(rec *Record) error {
+			if rec.NumValues() != len(layout) {
+				t.Errorf("%s: expected %d values in record; got %d", name, len(layout), rec.NumValues())
+			}
+			return nil
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	if table, _ := file.Object("person"); table != nil {
+		if _, err := table.RecordLayout(); err == nil {
+			t.Errorf("expected error for a table")
+		}
+	}
+}
+
+func TestObject_RowidOrder(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// GenreId of every track, by rowid
+	var genres = make(map[int64]int64)
+	err := file.ForEach("Track", func(rec *Record) (err error) {
+		var rowid, _ = rec.Rowid()
+		genres[rowid], err = rec.AsInt64(4)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := file.Object("IFK_TrackGenreId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rowids, err := index.RowidOrder()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rowids) != len(genres) {
+		t.Fatalf("expected %d rowids; got %d", len(genres), len(rowids))
+	}
+
+	// SELECT TrackId FROM Track ORDER BY GenreId, TrackId
+	for i := 1; i < len(rowids); i++ {
+		var prev, cur = rowids[i-1], rowids[i]
+		if genres[prev] > genres[cur] || (genres[prev] == genres[cur] && prev >= cur) {
+			t.Fatalf("rowid %d (genre %d) is ordered before rowid %d (genre %d)", prev, genres[prev], cur, genres[cur])
+		}
+	}
+
+	keys, err := index.KeyOrder()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(keys) != len(rowids) || keys[0][0] != rowids[0] {
+		t.Errorf("expected keys to be the rowids")
+	}
+}
+
+func TestObject_KeyOrder_without_rowid(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	// membership_role is on (role, org) while the primary key is (org, member)
+	index, err := file.Object("membership_role")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = index.RowidOrder(); err == nil {
+		t.Errorf("expected error for an index on a WITHOUT ROWID table")
+	}
+
+	keys, err := index.KeyOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SELECT org, member FROM membership ORDER BY role, org, member
+	var expected = [][]any{{"acme", int64(1)}, {"acme", int64(2)}, {"globex", int64(1)}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v; got %v", expected, keys)
+	}
+}
@@ -0,0 +1,219 @@
+package dotlite
+
+import "hash/fnv"
+
+// InferOptions controls how many rows InferTypes examines
+type InferOptions struct {
+	// MaxRows stops the scan after examining this many rows; zero means no limit.
+	MaxRows int
+
+	// StableRows stops the scan once no column has seen a new storage class for this
+	// many consecutive rows, ie. once the inference has stabilised; zero disables it.
+	StableRows int
+}
+
+// ColumnType holds the storage classes observed for values of a single column
+type ColumnType struct {
+	Counts [5]int64 // number of values seen, indexed by StorageClass
+}
+
+// Type returns the declared type best describing the observed values: INTEGER, REAL, TEXT or BLOB
+// if all non-NULL values share that storage class (REAL if integers are mixed with reals), or
+// an empty string if values are either all NULL or of mixed storage classes.
+func (ct ColumnType) Type() string {
+	var seen []StorageClass
+	for sc := StorageInteger; sc <= StorageBlob; sc++ {
+		if ct.Counts[sc] > 0 {
+			seen = append(seen, sc)
+		}
+	}
+
+	if len(seen) == 1 {
+		return seen[0].String()
+	} else if len(seen) == 2 && seen[0] == StorageInteger && seen[1] == StorageReal {
+		return StorageReal.String()
+	}
+
+	return ""
+}
+
+// TypeInference is the result of InferTypes
+type TypeInference struct {
+	Columns []ColumnType // observed types of each column, by position in the record
+	Rows    int64        // number of rows examined
+
+	// Sampled is true if the scan stopped (because of MaxRows or StableRows) before examining every row,
+	// in which case the result is only an estimate.
+	Sampled bool
+}
+
+// InferTypes scans the rows of the object and reports the storage classes observed for each column.
+// It only reads the record headers and doesn't decode any values.
+//
+// On large tables, the scan can be cut short using opts, in which case the result is from a sample of the
+// rows, and TypeInference.Sampled is set so callers know how confident to be in the result.
+//
+// The column aliasing the rowid of a table (see RowidColumn) holds the rowid, and is counted as INTEGER
+// even though sqlite stores a NULL in its place.
+func (obj *Object) InferTypes(opts InferOptions) (_ *TypeInference, err error) {
+	var rowid = -1
+	if obj.kind == ObjectTable {
+		if table, err := obj.schema(); err == nil {
+			rowid = table.rowid
+		}
+	}
+
+	var result = &TypeInference{}
+	var stable int // number of consecutive rows without a new storage class
+
+	err = obj.ForEach(func(rec *Record) (err error) {
+		if (opts.MaxRows > 0 && result.Rows >= int64(opts.MaxRows)) || (opts.StableRows > 0 && stable >= opts.StableRows) {
+			result.Sampled = true
+			return ErrStopIteration
+		}
+
+		for len(result.Columns) < rec.NumValues() {
+			result.Columns = append(result.Columns, ColumnType{})
+		}
+
+		var changed = false
+		for i := 0; i < rec.NumValues(); i++ {
+			var sc StorageClass
+			if sc, err = rec.StorageClass(i); err != nil {
+				return err
+			}
+
+			if sc == StorageNull && i == rowid {
+				sc = StorageInteger
+			}
+
+			if result.Columns[i].Counts[sc] == 0 {
+				changed = true
+			}
+			result.Columns[i].Counts[sc]++
+		}
+
+		if stable++; changed {
+			stable = 0
+		}
+
+		result.Rows++
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// NullCounts scans the rows of the object and returns the number of NULL values found in each column, keyed by
+// the column's position in the record. It only reads the record headers and doesn't decode any values.
+//
+// Trailing columns omitted from a stored record count as NULL. The column aliasing the rowid of a table
+// (see RowidColumn) is never NULL, even though sqlite stores a NULL in its place.
+func (obj *Object) NullCounts() (_ map[int]int64, err error) {
+	var rowid = -1
+	if obj.kind == ObjectTable {
+		if table, err := obj.schema(); err == nil {
+			rowid = table.rowid
+		}
+	}
+
+	var counts = make(map[int]int64)
+	err = obj.ForEach(func(rec *Record) (err error) {
+		for i := 0; i < rec.NumValues(); i++ {
+			var null bool
+			if null, err = rec.IsNull(i); err != nil {
+				return err
+			}
+
+			if null && i != rowid {
+				counts[i]++
+			} else if _, ok := counts[i]; !ok {
+				counts[i] = 0
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// ApproxDistinct scans the rows of the object and returns an estimate of the number of distinct non-NULL values
+// in the column at position c, the way COUNT(DISTINCT c) would count them.
+//
+// The estimate comes from a HyperLogLog sketch, which uses a few kilobytes of memory however large the table is,
+// and is typically within 1% of the exact count. Values are compared by their canonical form (see Record.Checksum),
+// so an integer and a float with the same value are counted once.
+func (obj *Object) ApproxDistinct(c int) (_ int64, err error) {
+	var rowid = -1
+	if obj.kind == ObjectTable {
+		if table, err := obj.schema(); err == nil {
+			rowid = table.rowid
+		}
+	}
+
+	var sketch = newHyperLogLog(14)
+	var h = fnv.New64a()
+	var buf [9]byte
+
+	err = obj.ForEach(func(rec *Record) (err error) {
+		var val any
+		if c == rowid {
+			val = rec.cell.Rowid
+		} else if val, err = rec.ValueAt(c); err != nil {
+			return err
+		}
+
+		if val != nil {
+			h.Reset()
+			writeValue(h, &buf, val)
+			sketch.add(mix64(h.Sum64()))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return sketch.estimate(), nil
+}
+
+// ValueLengths scans the rows of the object and returns, for each row in order, the number of bytes occupied by
+// the value of the column at position c (see Record.ValueLength), or -1 if the value is NULL. Lengths are computed
+// from the record headers alone and no value is decoded, making it cheap to find the largest values of a column.
+//
+// The column aliasing the rowid of a table isn't stored in the record, and always has a length of 0.
+func (obj *Object) ValueLengths(c int) (_ []int64, err error) {
+	var rowid = -1
+	if obj.kind == ObjectTable {
+		if table, err := obj.schema(); err == nil {
+			rowid = table.rowid
+		}
+	}
+
+	var lengths []int64
+	err = obj.ForEach(func(rec *Record) (err error) {
+		var n int64
+		if c != rowid {
+			if n, err = rec.ValueLength(c); err != nil {
+				return err
+			}
+		}
+		lengths = append(lengths, n)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lengths, nil
+}
@@ -0,0 +1,154 @@
+package dotlite
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestObject_InferTypes(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	full, err := table.InferTypes(InferOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if full.Sampled || full.Rows != 3503 {
+		t.Errorf("expected a full scan of %d rows; got %d rows (sampled=%v)", 3503, full.Rows, full.Sampled)
+	}
+
+	// TrackId, Name, AlbumId, MediaTypeId, GenreId, Composer, Milliseconds, Bytes, UnitPrice
+	var types = []string{"INTEGER", "TEXT", "INTEGER", "INTEGER", "INTEGER", "TEXT", "INTEGER", "INTEGER", "REAL"}
+	for i, typ := range types {
+		if got := full.Columns[i].Type(); got != typ {
+			t.Errorf("column(%d): expected type %q; got %q", i, typ, got)
+		}
+	}
+
+	if nulls := full.Columns[5].Counts[StorageNull]; nulls == 0 {
+		t.Errorf("expected Composer to contain NULLs")
+	}
+
+	limited, err := table.InferTypes(InferOptions{MaxRows: 10})
+	if err != nil {
+		t.Fatal(err)
+	} else if !limited.Sampled || limited.Rows != 10 {
+		t.Errorf("expected a sample of %d rows; got %d rows (sampled=%v)", 10, limited.Rows, limited.Sampled)
+	}
+
+	stable, err := table.InferTypes(InferOptions{StableRows: 5})
+	if err != nil {
+		t.Fatal(err)
+	} else if !stable.Sampled || stable.Rows >= full.Rows {
+		t.Errorf("expected scan to stop once stable; examined %d rows (sampled=%v)", stable.Rows, stable.Sampled)
+	}
+}
+
+func TestObject_NullCounts(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := table.NullCounts()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// TrackId aliases the rowid; only Composer has NULLs
+	var expected = map[int]int64{0: 0, 1: 0, 2: 0, 3: 0, 4: 0, 5: 978, 6: 0, 7: 0, 8: 0}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("expected %v; got %v", expected, counts)
+	}
+
+	var added = open(t, "testdata/added-columns.db")
+	defer added.Close()
+
+	if table, err = added.Object("t"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the first row was inserted before c was added, and omits it from its record
+	if counts, err = table.NullCounts(); err != nil {
+		t.Fatal(err)
+	} else if counts[2] != 1 {
+		t.Errorf("expected omitted column to count as NULL; got %v", counts)
+	}
+}
+
+func TestObject_ApproxDistinct(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SELECT COUNT(DISTINCT ...) FROM Track for TrackId, Name, GenreId, Composer and Milliseconds
+	for c, exact := range map[int]int64{0: 3503, 1: 3257, 4: 25, 5: 852, 6: 3080} {
+		var estimate int64
+		if estimate, err = table.ApproxDistinct(c); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := math.Abs(float64(estimate-exact)) / float64(exact); diff > 0.02 {
+			t.Errorf("column(%d): expected about %d distinct values; got %d", c, exact, estimate)
+		}
+	}
+
+	if _, err = table.ApproxDistinct(9); err == nil {
+		t.Errorf("expected out of range error")
+	}
+}
+
+func TestObject_ValueLengths(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total int64
+	names, err := table.ValueLengths(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range names {
+		total += n
+	}
+
+	if len(names) != 3503 || total != 55979 {
+		t.Errorf("expected %d names totalling %d bytes; got %d names totalling %d bytes", 3503, 55979, len(names), total)
+	}
+
+	var nulls int
+	var longest int64
+	composers, err := table.ValueLengths(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range composers {
+		if n < 0 {
+			nulls++
+		} else if n > longest {
+			longest = n
+		}
+	}
+
+	if nulls != 978 || longest != 188 {
+		t.Errorf("expected %d NULLs and a longest value of %d bytes; got %d NULLs and %d bytes", 978, 188, nulls, longest)
+	}
+}
@@ -0,0 +1,313 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// CheckIntegrity checks the structure of the database file, in the spirit of sqlite's PRAGMA integrity_check,
+// and returns every problem it finds rather than stopping at the first. It checks that:
+//
+//   - every b-tree page (of sqlite_schema and of every table and index) has a valid type, matching the tree it
+//     belongs to, and all leaves of a tree are at the same depth
+//   - the cell pointer array fits before the cell content area, and every cell lies within the usable part of
+//     its page without overlapping another cell
+//   - the rowids of a table b-tree are in increasing order, and within the bounds set by the parent pages
+//   - every overflow chain holds exactly as many pages as the payload it stores requires, and then ends
+//   - the freelist holds as many pages as the header says
+//   - no page is used twice, and every page is used by something
+//
+// An empty result means no problem was found. Unlike sqlite, the content of records isn't checked against
+// the schema, and the entries of an index aren't checked against its table.
+func (f *File) CheckIntegrity() (errs []error) {
+	var c = &integrityChecker{file: f, owner: make(map[int]string)}
+
+	if lock := pendingByte/f.PageSize() + 1; lock <= f.NumPages() {
+		c.owner[lock] = "lock-byte page"
+	}
+
+	if ptrmap, _ := f.PtrMap(); ptrmap != nil {
+		for _, page := range ptrmap.Pages() {
+			c.claim(page, "pointer map")
+		}
+	}
+
+	var objects = []*Object{NewObject("sqlite_schema", "table", "", NewTree(f, f.Pager, 1))}
+	if err := f.schema(func(obj *Object) error { objects = append(objects, obj); return nil }); err != nil {
+		c.report("failed to read the schema: %v", err)
+	}
+
+	for _, obj := range objects {
+		if obj.hasTree() && obj.tree.root != 0 { // virtual tables have no b-tree either
+			c.tree(obj)
+		}
+	}
+
+	c.freelist()
+
+	for page := 1; page <= f.NumPages(); page++ {
+		if _, ok := c.owner[page]; !ok {
+			c.report("page %d is never used", page)
+		}
+	}
+
+	return c.errs
+}
+
+// integrityChecker collects the problems found by CheckIntegrity
+type integrityChecker struct {
+	file  *File
+	owner map[int]string // description of the structure using each page seen so far
+	errs  []error
+}
+
+func (c *integrityChecker) report(format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf(format, args...))
+}
+
+// claim records that the page is used by owner; it reports (and returns false) if the page is out of range or
+// already in use, in which case the page mustn't be checked any further
+func (c *integrityChecker) claim(page int, owner string) bool {
+	if page < 1 || page > c.file.NumPages() {
+		c.report("%s: page %d is out of range: database has %d pages", owner, page, c.file.NumPages())
+		return false
+	}
+
+	if other, ok := c.owner[page]; ok {
+		c.report("%s: page %d is already used by %s", owner, page, other)
+		return false
+	}
+
+	c.owner[page] = owner
+	return true
+}
+
+// bounds are the limits on the rowids of a table b-tree node, set by the keys of its ancestors
+type bounds struct {
+	lo, hi       int64
+	hasLo, hasHi bool
+}
+
+// tree checks the b-tree of the object
+func (c *integrityChecker) tree(obj *Object) {
+	var owner = fmt.Sprintf("%s %q", obj.typ, obj.name)
+
+	// the pages of a table are table b-tree pages, unless it is a WITHOUT ROWID table; if the schema
+	// can't be parsed (as for sqlite_schema itself), the type of the root page is expected throughout
+	var table = obj.kind == ObjectTable
+	if schema, err := obj.schema(); err == nil {
+		table = table && !schema.withoutRowid
+	} else if root, err := obj.tree.node(obj.tree.root); err == nil && table {
+		table = root.Kind() == NodeTableInt || root.Kind() == NodeTableLeaf
+	}
+
+	var leaves = -1 // depth of the leaves, once the first leaf is reached
+	c.node(obj.tree, obj.tree.root, 0, owner, table, bounds{}, &leaves)
+}
+
+func (c *integrityChecker) node(tree *Tree, id, depth int, owner string, table bool, b bounds, leaves *int) {
+	if err := tree.checkDepth(depth); err != nil {
+		c.report("%s: %v", owner, err)
+		return
+	}
+
+	if !c.claim(id, owner) {
+		return
+	}
+
+	var node, err = tree.node(id)
+	if err != nil { // the error names the page
+		c.report("%s: %v", owner, err)
+		return
+	}
+
+	if isTable := node.Kind() == NodeTableInt || node.Kind() == NodeTableLeaf; isTable != table {
+		var expected = "an index"
+		if table {
+			expected = "a table"
+		}
+		c.report("%s: page %d: unexpected %s page in %s b-tree", owner, id, node.pageKind(), expected)
+		return
+	}
+
+	if node.IsLeaf() {
+		if *leaves < 0 {
+			*leaves = depth
+		} else if *leaves != depth {
+			c.report("%s: page %d: leaf at depth %d, while other leaves are at depth %d", owner, id, depth, *leaves)
+		}
+	}
+
+	c.cells(node, owner)
+
+	var keys []int64
+	if table {
+		keys = c.rowids(node, owner, b)
+	}
+
+	if node.IsLeaf() {
+		return
+	}
+
+	for i := 0; i <= node.NumCells(); i++ {
+		var child int
+		if child, err = node.ChildPage(i); err != nil {
+			c.report("%s: page %d: %v", owner, id, err)
+			continue
+		}
+
+		var cb = b // child i holds rowids greater than key i-1, up to key i
+		if keys != nil && i > 0 {
+			cb.lo, cb.hasLo = keys[i-1], true
+		}
+		if keys != nil && i < len(keys) {
+			cb.hi, cb.hasHi = keys[i], true
+		}
+
+		c.node(tree, child, depth+1, owner, table, cb, leaves)
+	}
+}
+
+// cells checks the layout of the node's cells, and the overflow chains of their payload
+func (c *integrityChecker) cells(node *TreeNode, owner string) {
+	var id, usable = node.PageID(), c.file.Header.usableSize()
+
+	var start = 8 // end of the cell pointer array, which follows the page header
+	if !node.IsLeaf() {
+		start = 12
+	}
+	if id == 1 {
+		start += 100
+	}
+	start += 2 * node.NumCells()
+
+	var content = int(node.header.CellsOffset)
+	if content == 0 {
+		content = 65536
+	}
+
+	if content < start || content > usable {
+		c.report("%s: page %d: cell content area starts at %d, outside of %d to %d", owner, id, content, start, usable)
+	}
+
+	type extent struct{ cell, start, end int }
+	var extents []extent
+	for i := 0; i < node.NumCells(); i++ {
+		var offset = int(node.cells[i])
+		if offset < start || offset >= usable {
+			c.report("%s: page %d: cell %d at offset %d lies outside of %d to %d", owner, id, i, offset, start, usable)
+			continue
+		}
+
+		var cell, err = node.LoadCell(i)
+		if errors.Is(err, errCellOverrun) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			c.report("%s: page %d: cell %d extends past the end of the page", owner, id, i)
+			continue
+		} else if err != nil {
+			c.report("%s: page %d: cell %d: %v", owner, id, i, err)
+			continue
+		}
+
+		var end, _ = node.page.Seek(0, io.SeekCurrent)
+		if int(end) > usable {
+			c.report("%s: page %d: cell %d extends to offset %d, past the usable size %d", owner, id, i, end, usable)
+			continue
+		}
+		extents = append(extents, extent{i, offset, int(end)})
+
+		if cell.chain != nil {
+			c.overflow(int(cell.chain.page), cell.chain.size, owner, id, i)
+		}
+	}
+
+	sort.Slice(extents, func(i, j int) bool { return extents[i].start < extents[j].start })
+	for i := 1; i < len(extents); i++ {
+		if prev := extents[i-1]; extents[i].start < prev.end {
+			c.report("%s: page %d: cells %d and %d overlap", owner, id, prev.cell, extents[i].cell)
+		}
+	}
+}
+
+// rowids checks that the rowids of a table b-tree node are increasing and within bounds, returning them
+func (c *integrityChecker) rowids(node *TreeNode, owner string, b bounds) (keys []int64) {
+	var id = node.PageID()
+	for i := 0; i < node.NumCells(); i++ {
+		var rowid, err = node.Rowid(i)
+		if err != nil {
+			c.report("%s: page %d: cell %d: %v", owner, id, i, err)
+			return nil
+		}
+
+		switch {
+		case i > 0 && rowid <= keys[i-1]:
+			c.report("%s: page %d: rowid %d of cell %d isn't greater than the previous rowid %d", owner, id, rowid, i, keys[i-1])
+		case b.hasLo && rowid <= b.lo:
+			c.report("%s: page %d: rowid %d of cell %d isn't greater than the parent's key %d", owner, id, rowid, i, b.lo)
+		case b.hasHi && rowid > b.hi:
+			c.report("%s: page %d: rowid %d of cell %d is greater than the parent's key %d", owner, id, rowid, i, b.hi)
+		}
+
+		keys = append(keys, rowid)
+	}
+
+	return keys
+}
+
+// overflow checks the overflow chain starting at page, which holds size bytes of the payload of a cell
+func (c *integrityChecker) overflow(page, size int, owner string, node, cell int) {
+	var per = c.file.Header.usableSize() - 4 // payload bytes held by each page of the chain
+	for left := size; left > 0; left -= per {
+		if page == 0 {
+			c.report("%s: page %d: cell %d: overflow chain ends with %d bytes of payload missing", owner, node, cell, left)
+			return
+		}
+
+		if !c.claim(page, owner) {
+			return
+		}
+
+		var p, err = c.file.Pager.ReadPage(page)
+		if err != nil {
+			c.report("%s: overflow page %d: %v", owner, page, err)
+			return
+		}
+
+		var next uint32
+		if err = binary.Read(p, binary.BigEndian, &next); err != nil {
+			c.report("%s: overflow page %d: %v", owner, page, err)
+			return
+		}
+
+		if left <= per && next != 0 {
+			c.report("%s: page %d: cell %d: overflow chain continues past its last page %d, to page %d", owner, node, cell, page, next)
+		}
+
+		page = int(next)
+	}
+}
+
+// freelist checks the pages of the freelist, and its size
+func (c *integrityChecker) freelist() {
+	var list, err = c.file.Freelist()
+	if err != nil {
+		c.report("freelist: %v", err)
+		return
+	}
+
+	var count int
+	err = list.walk(func(page int, _ PageKind) error {
+		if count++; !c.claim(page, "freelist") {
+			return ErrStopIteration // don't follow a chain that runs into other structures
+		}
+		return nil
+	})
+
+	if err != nil && err != ErrStopIteration {
+		c.report("freelist: %v", err)
+	} else if err == nil && count != list.Count() {
+		c.report("freelist: header says it holds %d pages, but %d were found", list.Count(), count)
+	}
+}
@@ -0,0 +1,161 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFile_CheckIntegrity(t *testing.T) {
+	for _, name := range []string{"chinook.db", "incremental-vacuum.db", "large-blob.db", "without-rowid.db", "indexes.db"} {
+		var file = open(t, filepath.Join("testdata", name))
+		if errs := file.CheckIntegrity(); len(errs) != 0 {
+			t.Errorf("%s: unexpected errors: %v", name, errs)
+		}
+		_ = file.Close()
+	}
+}
+
+func TestFile_CheckIntegrity_corrupt(t *testing.T) {
+	// locate the pages to corrupt in a clean copy of the database
+	var file = open(t, "testdata/chinook.db")
+	track, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := track.tree.RootNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	interior, err := track.tree.Child(root, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, err := track.tree.Child(interior, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	var pageSize = 1024
+	var offset = func(page int) int { return (page - 1) * pageSize }
+
+	for _, test := range []struct {
+		name     string
+		patch    func(b []byte)
+		expected []string // substrings of the errors expected, in order
+	}{
+		{
+			name:     "cycle",
+			patch:    func(b []byte) { binary.BigEndian.PutUint32(b[offset(interior.PageID())+8:], uint32(root.PageID())) },
+			expected: []string{`table "Track": page 409 is already used by table "Track"`, "is never used"},
+		},
+		{
+			name:     "invalid page type",
+			patch:    func(b []byte) { b[offset(leaf.PageID())] = 0 },
+			expected: []string{fmt.Sprintf(`table "Track": page %d is not a b-tree page`, leaf.PageID())},
+		},
+		{
+			name:     "index page in a table",
+			patch:    func(b []byte) { b[offset(leaf.PageID())] = NodeIndexLeaf },
+			expected: []string{"unexpected index leaf page in a table b-tree"},
+		},
+		{
+			name:     "cell outside of the page",
+			patch:    func(b []byte) { binary.BigEndian.PutUint16(b[offset(leaf.PageID())+8:], 1020) },
+			expected: []string{"cell 0 extends past the end of the page"},
+		},
+		{
+			name: "overlapping cells",
+			patch: func(b []byte) {
+				copy(b[offset(leaf.PageID())+8:], b[offset(leaf.PageID())+10:offset(leaf.PageID())+12])
+			},
+			expected: []string{"cells 0 and 1 overlap", "rowid"},
+		},
+		{
+			name:     "freelist count",
+			patch:    func(b []byte) { binary.BigEndian.PutUint32(b[36:], 100) },
+			expected: []string{"freelist: header says it holds 100 pages, but 187 were found"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := os.ReadFile("testdata/chinook.db")
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.patch(b)
+
+			var name = filepath.Join(t.TempDir(), "corrupt.db")
+			if err = os.WriteFile(name, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			var file = open(t, name)
+			defer file.Close()
+
+			var errs = file.CheckIntegrity()
+			if len(errs) < len(test.expected) {
+				t.Fatalf("expected at least %d errors; got %v", len(test.expected), errs)
+			}
+
+			for i, expected := range test.expected {
+				if !strings.Contains(errs[i].Error(), expected) {
+					t.Errorf("expected error %d to contain %q; got %v", i, expected, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestFile_CheckIntegrity_overflow(t *testing.T) {
+	b, err := os.ReadFile("testdata/large-blob.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// end the overflow chain of the first row after its first page
+	var file = open(t, "testdata/large-blob.db")
+	table, err := file.Object("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first int32
+	err = table.tree.Walk(func(c *Cell) error {
+		first = c.chain.page
+		return ErrStopIteration
+	})
+	_ = file.Close()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary.BigEndian.PutUint32(b[(first-1)*512:], 0)
+
+	var name = filepath.Join(t.TempDir(), "corrupt.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	file = open(t, name)
+	defer file.Close()
+
+	var errs = file.CheckIntegrity()
+	if len(errs) == 0 || !strings.Contains(errs[0].Error(), "overflow chain ends with") {
+		t.Fatalf("expected a truncated overflow chain; got %v", errs)
+	}
+
+	// every other page of the chain is now unused
+	for _, err := range errs[1:] {
+		if !strings.Contains(err.Error(), "is never used") {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
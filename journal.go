@@ -0,0 +1,47 @@
+package dotlite
+
+import (
+	"errors"
+	"os"
+)
+
+// journalMagic is the magic string at the start of a valid rollback journal header
+// see: https://www.sqlite.org/fileformat.html#the_rollback_journal
+const journalMagic = "\xd9\xd5\x05\xf9\x20\xa1\x63\xd7"
+
+// ErrHotJournal is returned by Open, for a file opened WithRejectHotJournal, when the database has a hot journal
+var ErrHotJournal = errors.New("database has a hot rollback journal and may be inconsistent")
+
+// WithRejectHotJournal makes Open fail with ErrHotJournal if the database has a hot journal (see File.HasHotJournal),
+// rather than reading a database that may hold a partially written transaction.
+func WithRejectHotJournal() Option { return func(f *File) { f.rejectHotJournal = true } }
+
+// HasHotJournal reports whether the rollback journal next to the database file (the name-journal file) is hot,
+// ie. whether a writer was interrupted in the middle of a transaction. sqlite rolls such a transaction back using
+// the journal the next time the database is opened; until then, the database file may hold a torn mix of old
+// and new pages, and reading it may return inconsistent data or fail.
+//
+// The journal is considered hot if it exists and starts with a valid header; a journal that is empty, or whose
+// header was zeroed (as sqlite does to commit in PERSIST journal mode), isn't. As locks aren't checked, a journal
+// that belongs to a transaction still in progress in another process is reported as hot as well.
+//
+// The journal is never applied: the database is always read as it is on disk. A database opened with
+// OpenReaderAt never has a hot journal.
+func (f *File) HasHotJournal() bool {
+	if f.file == nil {
+		return false
+	}
+
+	var journal, err = os.Open(f.file.Name() + "-journal")
+	if err != nil {
+		return false
+	}
+	defer journal.Close()
+
+	var magic [len(journalMagic)]byte
+	if _, err = journal.ReadAt(magic[:], 0); err != nil {
+		return false
+	}
+
+	return string(magic[:]) == journalMagic
+}
@@ -0,0 +1,56 @@
+package dotlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFile_HasHotJournal(t *testing.T) {
+	b, err := os.ReadFile("testdata/indexes.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var header = append([]byte(journalMagic), make([]byte, 20)...)
+	for _, test := range []struct {
+		name    string
+		journal []byte // nil if there is no journal
+		hot     bool
+	}{
+		{"no journal", nil, false},
+		{"empty journal", []byte{}, false},
+		{"zeroed header", make([]byte, 28), false},
+		{"valid header", header, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var name = filepath.Join(t.TempDir(), "copy.db")
+			if err = os.WriteFile(name, b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.journal != nil {
+				if err = os.WriteFile(name+"-journal", test.journal, 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var file = open(t, name)
+			defer file.Close()
+
+			if hot := file.HasHotJournal(); hot != test.hot {
+				t.Errorf("expected HasHotJournal() to be %v", test.hot)
+			}
+
+			file, err = Open(name, WithRejectHotJournal())
+			if test.hot && !errors.Is(err, ErrHotJournal) {
+				t.Errorf("expected ErrHotJournal; got %v", err)
+			} else if !test.hot && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if err == nil {
+				_ = file.Close()
+			}
+		})
+	}
+}
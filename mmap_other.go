@@ -0,0 +1,7 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package dotlite
+
+// OpenFileMmap opens the database file at name like Open. Memory-mapping files isn't supported on this platform,
+// so pages are read from the file as usual.
+func OpenFileMmap(name string, opts ...Option) (*File, error) { return Open(name, opts...) }
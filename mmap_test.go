@@ -0,0 +1,43 @@
+package dotlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenFileMmap(t *testing.T) {
+	file, err := OpenFileMmap("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows int
+	if err = file.ForEach("Track", func(*Record) error { rows++; return nil }); err != nil {
+		t.Fatal(err)
+	} else if rows != 3503 {
+		t.Errorf("expected 3503 rows in Track; got %d", rows)
+	}
+
+	if err = file.VerifySize(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err = file.Close(); err != nil {
+		t.Errorf("failed to close file: %v", err)
+	}
+
+	var empty = filepath.Join(t.TempDir(), "empty.db")
+	if err = os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = OpenFileMmap(empty); !errors.Is(err, ErrTruncatedHeader) {
+		t.Errorf("expected truncated header error; got %v", err)
+	}
+
+	if _, err = OpenFileMmap("testdata/not-a-database.txt"); err == nil {
+		t.Errorf("expected an error opening a file that isn't a database")
+	}
+}
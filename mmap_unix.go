@@ -0,0 +1,69 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package dotlite
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// OpenFileMmap opens the database file at name like Open, but maps the file into memory and reads pages
+// from the mapping, saving a system call for every page read. Closing the File unmaps the file.
+//
+// The file must not be truncated while it is mapped, as reading a page past the new end of the file then
+// crashes the process instead of returning an error.
+func OpenFileMmap(name string, opts ...Option) (_ *File, err error) {
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+		}
+	}()
+
+	var info os.FileInfo
+	if info, err = f.Stat(); err != nil {
+		return nil, err
+	} else if info.Size() == 0 { // an empty file can't be mapped
+		return nil, ErrTruncatedHeader
+	}
+
+	var m mmap
+	if m, err = syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED); err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: name, Err: err}
+	}
+
+	var file *File
+	if file, err = OpenReaderAt(m, int64(len(m)), opts...); err != nil {
+		_ = m.Close()
+		return nil, err
+	}
+	file.file, file.closer = f, multiCloser{m, f}
+
+	if file.rejectHotJournal && file.HasHotJournal() {
+		_ = m.Close()
+		return nil, ErrHotJournal
+	}
+
+	return file, nil
+}
+
+// mmap is an io.ReaderAt over a memory-mapped file
+type mmap []byte
+
+func (m mmap) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 || off >= int64(len(m)) {
+		return 0, io.EOF
+	}
+
+	if n = copy(p, m[off:]); n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m mmap) Close() error { return syscall.Munmap(m) }
@@ -1,34 +1,480 @@
 package dotlite
 
-// Object represents either a table or an index stored in the database file
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ObjectType is the type of an object of the schema
+type ObjectType int
+
+const (
+	ObjectUnknown ObjectType = iota // any type sqlite doesn't define
+	ObjectTable
+	ObjectIndex
+	ObjectView
+	ObjectTrigger
+)
+
+// parseObjectType returns the ObjectType named by typ, as found in the type column of sqlite_schema
+func parseObjectType(typ string) ObjectType {
+	switch typ {
+	case "table":
+		return ObjectTable
+	case "index":
+		return ObjectIndex
+	case "view":
+		return ObjectView
+	case "trigger":
+		return ObjectTrigger
+	}
+	return ObjectUnknown
+}
+
+func (t ObjectType) String() string {
+	switch t {
+	case ObjectTable:
+		return "table"
+	case ObjectIndex:
+		return "index"
+	case ObjectView:
+		return "view"
+	case ObjectTrigger:
+		return "trigger"
+	}
+	return fmt.Sprintf("unknown (%d)", int(t))
+}
+
+// Object represents a table, an index, a view or a trigger defined in the database file.
+// Only tables and indexes have rows; views and triggers consist of their sql alone.
 type Object struct {
 	name string // name of the object
 	typ  string // type of the object
+	kind ObjectType
 	sql  string // raw sql to containing the object's schema
 	tree *Tree  // tree holding the object
+	tbl  string // name of the table the object belongs to, as recorded in sqlite_schema
+
+	strict bool // never skip rows, even if the file was opened WithLenientRows; set for sqlite_schema
+
+	mu    sync.Mutex   // guards table, as an object may be shared by goroutines
+	table *tableSchema // parsed table schema; lazily populated
 }
 
 func NewObject(name, typ, sql string, tree *Tree) *Object {
-	return &Object{name: name, typ: typ, sql: sql, tree: tree}
+	return &Object{name: name, typ: typ, kind: parseObjectType(typ), sql: sql, tree: tree}
 }
 
 // Name returns the table's name
 func (obj *Object) Name() string { return obj.name }
 
-// SQL returns the table's raw sql schema
+// Tree returns the b-tree holding the rows of a table or the entries of an index, for lookups and traversals
+// beyond those offered by Object (eg. Tree.Search by rowid, or Tree.Count). Its root page is given by Tree.Root.
+// It is nil for views and triggers, which aren't stored in a b-tree.
+func (obj *Object) Tree() *Tree {
+	if !obj.hasTree() {
+		return nil
+	}
+	return obj.tree
+}
+
+// SQL returns the object's raw sql schema, exactly as it is stored in sqlite_schema
 func (obj *Object) SQL() string { return obj.sql }
 
+// NormalizedSQL returns the object's sql schema in a canonical form, such that two schemas that only differ
+// in whitespace, comments, the case of keywords and identifiers (including type names) or the quoting of
+// identifiers have the same normalized sql. Double-quoted identifiers keep their case, as sqlite also accepts
+// double-quoted string literals; so "Name" and name are normalized differently.
+// It is useful for comparing schemas across databases.
+//
+// If the sql cannot be tokenized, it is returned unchanged.
+func (obj *Object) NormalizedSQL() string {
+	if sql, err := normalizeSQL(obj.sql); err == nil {
+		return sql
+	}
+	return obj.sql
+}
+
+// TableName returns the name of the table the object is associated with, as recorded in the tbl_name column
+// of sqlite_schema: the table an index is defined on, or the table's own name for a table.
+// It is empty for an object that wasn't read from sqlite_schema.
+func (obj *Object) TableName() string { return obj.tbl }
+
+// hasTree reports whether the object is stored in a b-tree; views and triggers only consist of their sql
+func (obj *Object) hasTree() bool { return obj.kind != ObjectView && obj.kind != ObjectTrigger }
+
 // Type is the type of object, like, table / index / view, etc.
 func (obj *Object) Type() string { return obj.typ }
 
+// Kind returns the type of the object as an ObjectType; ObjectUnknown if Type isn't one sqlite defines
+func (obj *Object) Kind() ObjectType { return obj.kind }
+
+// Columns returns the columns defined in the table's schema.
+func (obj *Object) Columns() (_ []*Column, err error) {
+	var table *tableSchema
+	if table, err = obj.schema(); err != nil {
+		return nil, err
+	}
+	return table.columns, nil
+}
+
+// ForeignKeys returns all foreign key constraints defined on the table, either inline on a column or on the table itself.
+func (obj *Object) ForeignKeys() (_ []*ForeignKey, err error) {
+	var table *tableSchema
+	if table, err = obj.schema(); err != nil {
+		return nil, err
+	}
+	return table.foreignKeys, nil
+}
+
+// RowidColumn returns the column that is an alias for the rowid, ie. the table's INTEGER PRIMARY KEY
+// (with or without AUTOINCREMENT). It returns nil if the table has no such column.
+//
+// see: https://www.sqlite.org/lang_createtable.html#rowid
+func (obj *Object) RowidColumn() (_ *Column, err error) {
+	var table *tableSchema
+	if table, err = obj.schema(); err != nil {
+		return nil, err
+	}
+
+	if table.rowid < 0 {
+		return nil, nil
+	}
+	return table.columns[table.rowid], nil
+}
+
+// Sequence returns the value recorded in sqlite_sequence for an AUTOINCREMENT table,
+// that is, the largest rowid ever used by the table. The next row inserted into the table
+// gets a rowid of at least seq+1, as sqlite never reuses rowids of an AUTOINCREMENT table.
+//
+// ok is false if the table doesn't use AUTOINCREMENT or if no row was ever inserted into it.
+func (obj *Object) Sequence() (seq int64, ok bool, err error) {
+	var col *Column
+	if col, err = obj.RowidColumn(); err != nil || col == nil || !col.AutoIncrement {
+		return 0, false, err
+	}
+
+	var sequences map[string]int64
+	if sequences, err = obj.tree.file.Sequences(); err != nil {
+		return 0, false, err
+	}
+
+	for name, seq := range sequences {
+		if strings.EqualFold(name, obj.name) {
+			return seq, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// MaxRowid returns the largest rowid currently in use by the table, or zero if the table is empty.
+// It only reads the pages along the right-most path of the table's b-tree, without scanning the table.
+//
+// For an AUTOINCREMENT table, the value recorded in sqlite_sequence (see Sequence) may be larger
+// than MaxRowid if the rows with the largest rowids were deleted, as sqlite never reuses those rowids.
+//
+// MaxRowid returns an error for indexes and WITHOUT ROWID tables, which have no rowid.
+func (obj *Object) MaxRowid() (_ int64, err error) {
+	if obj.kind != ObjectTable {
+		return 0, fmt.Errorf("object %q is not a table", obj.name)
+	}
+
+	var node *TreeNode
+	if node, err = obj.tree.RootNode(); err != nil {
+		return 0, err
+	}
+
+	var visited = map[int]bool{node.PageID(): true}
+	for depth := 1; node.Kind() == NodeTableInt; depth++ {
+		if node, err = obj.tree.child(node, node.NumCells(), depth, visited); err != nil {
+			return 0, err
+		}
+	}
+
+	if node.Kind() != NodeTableLeaf {
+		return 0, fmt.Errorf("table %q has no rowid", obj.name)
+	} else if node.NumCells() == 0 {
+		return 0, nil
+	}
+
+	return node.Rowid(node.NumCells() - 1)
+}
+
+// schema parses and returns the table's schema
+func (obj *Object) schema() (_ *tableSchema, err error) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	if obj.table != nil {
+		return obj.table, nil
+	}
+
+	if obj.kind != ObjectTable {
+		return nil, fmt.Errorf("object %q is not a table", obj.name)
+	}
+
+	if obj.table, err = parseTable(obj.sql); err != nil {
+		return nil, fmt.Errorf("failed to parse schema for %q: %w", obj.name, err)
+	}
+
+	return obj.table, nil
+}
+
 // ForEach iterates over each row in the table in order, invoking callback.
+//
+// Records of a table are padded with NULL values for any trailing columns omitted from the stored record.
+// If the file was opened WithStrictColumns, a record holding fewer (or more) values than there are columns
+// in the table is reported as an error instead.
+//
+// If the file was opened WithTypedValues, values of a table's record are converted to their column's affinity.
+//
+// If the file was opened WithLenientRows, rows that cannot be decoded are skipped and reported through a *SkippedRowsError.
 func (obj *Object) ForEach(fn func(*Record) error) error {
-	return obj.tree.Walk(func(cell *Cell) (err error) {
+	return obj.ForEachContext(context.Background(), fn)
+}
+
+// lenient reports whether rows of the object that fail to decode are skipped; see WithLenientRows
+func (obj *Object) lenient() bool { return obj.tree.file.lenient && !obj.strict }
+
+// ForEachContext iterates over each row like ForEach, checking ctx before reading each page of the object.
+// If ctx is cancelled (or its deadline passes) the iteration ends, returning ctx's error.
+func (obj *Object) ForEachContext(ctx context.Context, fn func(*Record) error) error {
+	var file = obj.tree.file
+
+	var decode, err = obj.decoder()
+	if err != nil {
+		return err
+	}
+
+	var skipped []error
+	var skip func(error) error
+	if obj.lenient() {
+		skip = func(err error) error {
+			if file.onRowError != nil {
+				file.onRowError(err)
+			}
+			skipped = append(skipped, err)
+			return nil
+		}
+	}
+
+	err = obj.tree.walkWith(ctx, func(_, _ int, cell *Cell) (err error) {
 		var rec *Record
-		if rec, err = NewRecord(obj.tree.file.Encoding(), cell); err != nil {
-			return err
+		if rec, err = decode(cell); err != nil {
+			if skip == nil {
+				return err
+			}
+			return skip(err)
 		}
 
 		return fn(rec)
+	}, skip)
+
+	if err == nil && len(skipped) > 0 {
+		return &SkippedRowsError{Errors: skipped}
+	}
+
+	return err
+}
+
+// ForEachMap iterates over each row like ForEach, passing fn the row's values keyed by the name of their column:
+// the columns of a table's schema, or the columns described by RecordLayout for an index. The column aliasing
+// the rowid of a table holds the row's rowid.
+//
+// If the names can't be determined (eg. for an automatic index), or a record holds more or fewer values than
+// there are columns, the values of that record are keyed by position instead, as col0, col1 and so on.
+func (obj *Object) ForEachMap(fn func(map[string]any) error) (err error) {
+	var names []string
+	var rowid = -1
+	switch obj.kind {
+	case ObjectTable:
+		var table *tableSchema
+		if table, err = obj.schema(); err != nil {
+			return err
+		}
+
+		for _, col := range table.columns {
+			names = append(names, col.Name)
+		}
+		rowid = table.rowid
+	case ObjectIndex:
+		if layout, err := obj.RecordLayout(); err == nil {
+			for _, role := range layout {
+				names = append(names, role.Name)
+			}
+		}
+	}
+
+	return obj.ForEach(func(rec *Record) (err error) {
+		var row = make(map[string]any, rec.NumValues())
+		for i := 0; i < rec.NumValues(); i++ {
+			var val any
+			if val, err = rec.ValueAt(i); err != nil {
+				return err
+			}
+
+			if len(names) != rec.NumValues() {
+				row[fmt.Sprintf("col%d", i)] = val
+				continue
+			}
+
+			if i == rowid && val == nil {
+				val, _ = rec.Rowid()
+			}
+			row[names[i]] = val
+		}
+
+		return fn(row)
 	})
 }
+
+// Head invokes fn for the first n rows of the object, in the order ForEach visits them; the equivalent of
+// SELECT * FROM t LIMIT n. The walk ends as soon as the n-th row is returned, so only the pages needed to
+// reach it are read. If n is zero (or negative) fn is never invoked.
+func (obj *Object) Head(n int, fn func(*Record) error) error {
+	if n <= 0 {
+		return nil
+	}
+
+	var seen int
+	return obj.ForEach(func(rec *Record) (err error) {
+		if err = fn(rec); err != nil {
+			return err
+		}
+
+		if seen++; seen == n {
+			return ErrStopIteration
+		}
+		return nil
+	})
+}
+
+// Slice invokes fn for at most limit rows of the object, after skipping the first offset rows in the order ForEach
+// visits them; the equivalent of SELECT * FROM t LIMIT limit OFFSET offset, which allows paging through an object.
+// A negative limit reads every row past the offset, and a negative offset is taken as zero, as sqlite does.
+//
+// On a rowid table, leaf pages lying entirely before the offset are skipped using the cell count in their header,
+// without decoding any of their rows. Indexes and WITHOUT ROWID tables (and files opened WithLenientRows)
+// fall back to counting rows during the walk.
+func (obj *Object) Slice(offset, limit int, fn func(*Record) error) (err error) {
+	if limit == 0 {
+		return nil
+	} else if offset < 0 {
+		offset = 0
+	}
+
+	var seen int
+	var visit = func(rec *Record) (err error) {
+		if err = fn(rec); err != nil {
+			return err
+		}
+
+		if seen++; seen == limit {
+			return ErrStopIteration
+		}
+		return nil
+	}
+
+	if !obj.hasTree() {
+		return obj.ForEach(fn) // fails, as there are no rows to slice
+	}
+
+	var root *TreeNode
+	if root, err = obj.tree.RootNode(); err != nil {
+		return err
+	}
+
+	if k := root.Kind(); obj.lenient() || (k != NodeTableInt && k != NodeTableLeaf) {
+		var skipped int
+		return obj.ForEach(func(rec *Record) error {
+			if skipped < offset {
+				skipped++
+				return nil
+			}
+			return visit(rec)
+		})
+	}
+
+	var decode func(*Cell) (*Record, error)
+	if decode, err = obj.decoder(); err != nil {
+		return err
+	}
+
+	var skip = offset
+	err = obj.tree.skipLeaves(root, 0, map[int]bool{root.PageID(): true}, &skip, func(cell *Cell) (err error) {
+		var rec *Record
+		if rec, err = decode(cell); err != nil {
+			return err
+		}
+		return visit(rec)
+	})
+
+	if errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+
+	return err
+}
+
+// decoder returns a function that decodes a cell of the object into a Record, configured according to the file's options
+func (obj *Object) decoder() (func(*Cell) (*Record, error), error) {
+	if !obj.hasTree() {
+		return nil, fmt.Errorf("%s %q has no rows: only tables and indexes are stored in the file", obj.typ, obj.name)
+	}
+
+	var file = obj.tree.file
+
+	var columns = -1 // number of columns in the table; -1 if unknown
+	var affinities []Affinity
+	var order []int
+	var names []string
+	var alias = -1
+	if obj.kind == ObjectTable {
+		if table, err := obj.schema(); err == nil {
+			columns = len(table.columns)
+			order = table.storageOrder()
+			alias = table.rowid
+			for _, col := range table.columns {
+				names = append(names, col.Name)
+			}
+			if file.typedValues {
+				for _, col := range table.columns {
+					affinities = append(affinities, col.Affinity)
+				}
+			}
+		} else if file.strictColumns || file.typedValues {
+			return nil, err
+		}
+	} else if obj.kind == ObjectIndex {
+		if layout, err := obj.RecordLayout(); err == nil {
+			for _, role := range layout {
+				names = append(names, role.Name)
+			}
+		}
+	}
+
+	return func(cell *Cell) (_ *Record, err error) {
+		var rec *Record
+		if rec, err = NewRecord(file.Encoding(), cell); err != nil {
+			return nil, err
+		}
+
+		if columns >= 0 {
+			if file.strictColumns && rec.NumValues() != columns {
+				return nil, fmt.Errorf("record(rowid=%d) has %d values but table %q has %d columns", cell.Rowid, rec.NumValues(), obj.name, columns)
+			}
+			rec.columns = columns
+		}
+		rec.affinities = affinities
+		rec.invalidText = file.invalidText
+		rec.uncheckedJSON = file.uncheckedJSON
+		rec.order = order
+		rec.names, rec.alias = names, alias
+
+		return rec, nil
+	}, nil
+}
@@ -1,6 +1,15 @@
 package dotlite
 
-import "testing"
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
 
 func TestTable(t *testing.T) {
 	var file = open(t, "testdata/all-kinds.db") // well technically most 😅
@@ -79,3 +88,505 @@ func TestIndex_without_rowid(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTable_omitted_columns(t *testing.T) {
+	var file = open(t, "testdata/added-columns.db")
+	defer file.Close()
+
+	table, err := file.Object("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.ForEach(func(record *Record) error {
+		if n := record.NumValues(); n != 3 {
+			t.Errorf("expected %d values; got %d", 3, n)
+		}
+
+		if val, err := record.ValueAt(2); err != nil {
+			return err
+		} else if record.cell.Rowid == 1 && val != nil {
+			t.Errorf("expected omitted value to be NULL; got %v", val)
+		}
+
+		if _, err := record.ValueAt(3); err == nil {
+			t.Errorf("expected out of range error")
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTable_omitted_columns_strict(t *testing.T) {
+	var file, err = Open("testdata/added-columns.db", WithStrictColumns())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if err = file.ForEach("t", func(*Record) error { return nil }); err == nil {
+		t.Errorf("expected error for record with omitted columns")
+	}
+}
+
+func TestTable_autoincrement(t *testing.T) {
+	var file = open(t, "testdata/autoincrement.db")
+	defer file.Close()
+
+	for name, expected := range map[string]struct {
+		autoincrement bool
+		seq           int64
+	}{"event": {true, 4}, "plain": {false, 0}} {
+		table, err := file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		col, err := table.RowidColumn()
+		if err != nil {
+			t.Fatal(err)
+		} else if col == nil || col.Name != "id" {
+			t.Fatalf("%s: expected id to alias the rowid; got %+v", name, col)
+		}
+
+		if col.AutoIncrement != expected.autoincrement {
+			t.Errorf("%s: expected AutoIncrement to be %v", name, expected.autoincrement)
+		}
+
+		seq, ok, err := table.Sequence()
+		if err != nil {
+			t.Error(err)
+		} else if ok != expected.autoincrement || seq != expected.seq {
+			t.Errorf("%s: expected sequence to be %d (ok=%v); got %d (ok=%v)", name, expected.seq, expected.autoincrement, seq, ok)
+		}
+	}
+}
+
+func TestTable_MaxRowid(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	for name, expected := range map[string]int64{"Track": 3503, "InvoiceLine": 2240, "Artist": 275} {
+		table, err := file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if max, err := table.MaxRowid(); err != nil {
+			t.Error(err)
+		} else if max != expected {
+			t.Errorf("%s: expected max rowid to be %d; got %d", name, expected, max)
+		}
+	}
+
+	if index, err := file.Object("IFK_TrackAlbumId"); err != nil {
+		t.Fatal(err)
+	} else if _, err = index.MaxRowid(); err == nil {
+		t.Errorf("expected error for an index")
+	}
+
+	var without = open(t, "testdata/without-rowid.db")
+	defer without.Close()
+
+	if table, err := without.Object("wordcount"); err != nil {
+		t.Fatal(err)
+	} else if _, err = table.MaxRowid(); err == nil {
+		t.Errorf("expected error for a WITHOUT ROWID table")
+	}
+
+	// point the right-most child of Track's root (page 409) back at itself
+	var b = read(t, "testdata/chinook.db")
+	binary.BigEndian.PutUint32(b[408*1024+8:], 409)
+
+	cycle, err := OpenBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table, err := cycle.Object("Track"); err != nil {
+		t.Fatal(err)
+	} else if _, err = table.MaxRowid(); !errors.Is(err, ErrCorruptPage) {
+		t.Errorf("expected a cycle to be reported; got %v", err)
+	}
+
+	// the leaves of Track are two levels below the root
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table.tree.MaxDepth = 1
+	if _, err = table.MaxRowid(); err == nil {
+		t.Errorf("expected MaxDepth to be honoured")
+	}
+}
+
+func TestForEach_without_rowid_column_order(t *testing.T) {
+	// sqlite stores integral REAL values as integers; typed values make the output match SELECT *
+	var file, err = Open("testdata/without-rowid-pk.db", WithTypedValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// enrollment is declared as (note, term, student, grade) with PRIMARY KEY (student, term)
+	var rows [][]any
+	err = file.ForEach("enrollment", func(rec *Record) (err error) {
+		var values []any
+		if values, err = rec.AppendValues(nil); err != nil {
+			return err
+		}
+		rows = append(rows, values)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SELECT * FROM enrollment
+	var expected = [][]any{
+		{nil, int64(2021), "alice", 2.5},
+		{"retake", int64(2022), "alice", 4.0},
+		{"first", int64(2021), "bob", 3.5},
+	}
+
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v; got %v", expected, rows)
+	}
+}
+
+func TestForEach_integer_shortcuts(t *testing.T) {
+	// the values 0 and 1 are stored using serial types 8 and 9, valid as the file uses schema format 4
+	var file = open(t, "testdata/integer-shortcuts.db")
+	defer file.Close()
+
+	var enabled []any
+	err := file.ForEach("flag", func(rec *Record) error {
+		var val, err = rec.ValueAt(1)
+		enabled = append(enabled, val)
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := []any{int64(0), int64(1), int64(2)}; !reflect.DeepEqual(enabled, expected) {
+		t.Errorf("expected %v; got %v", expected, enabled)
+	}
+
+	// make a copy of the database that claims to use schema format 1
+	b, err := os.ReadFile("testdata/integer-shortcuts.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[47] = 1
+
+	var name = filepath.Join(t.TempDir(), "legacy.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var legacy = open(t, name)
+	defer legacy.Close()
+
+	if err = legacy.ForEach("flag", func(*Record) error { return nil }); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("expected serial types 8 and 9 to be rejected in schema format 1; got %v", err)
+	}
+
+	// lookups by rowid decode the record without going through the table's decoder
+	table, err := legacy.Object("flag")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cell, err := table.tree.Search(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewRecord(legacy.Encoding(), cell); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("expected serial types 8 and 9 to be rejected in schema format 1; got %v", err)
+	}
+}
+
+func TestForEach_lenient(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var total, page int
+	if err = table.ForEach(func(*Record) error { total++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if page, err = table.tree.LeafPageFor(1); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	// make a copy of the database with an invalid node type on the leaf holding the first row
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[(page-1)*file.PageSize()] = 0xff
+
+	var name = filepath.Join(t.TempDir(), "corrupt.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var reported int
+	if file, err = Open(name, WithLenientRows(func(error) { reported++ })); err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var rows int
+	err = file.ForEach("Track", func(*Record) error { rows++; return nil })
+
+	var skipped *SkippedRowsError
+	if !errors.As(err, &skipped) {
+		t.Fatalf("expected a *SkippedRowsError; got %v", err)
+	}
+
+	if len(skipped.Errors) != 1 || reported != 1 {
+		t.Errorf("expected 1 failure to be reported; got %d (callback called %d times)", len(skipped.Errors), reported)
+	}
+
+	if rows == 0 || rows >= total {
+		t.Errorf("expected only rows on the corrupt page to be skipped; got %d of %d rows", rows, total)
+	}
+
+	// rows of sqlite_schema are never skipped: corrupt its right-most leaf (page 419)
+	b[418*file.PageSize()] = 0xff
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var schema *File
+	if schema, err = Open(name, WithLenientRows(func(error) { reported++ })); err != nil {
+		t.Fatal(err)
+	}
+	defer schema.Close()
+
+	reported = 0
+	if _, err = schema.Schema(); !errors.Is(err, ErrCorruptPage) || errors.As(err, &skipped) {
+		t.Errorf("expected ErrCorruptPage; got %v", err)
+	} else if reported != 0 {
+		t.Errorf("expected no failures to be reported for sqlite_schema; got %d", reported)
+	}
+}
+
+// pageRecorder records the pages read through it
+type pageRecorder struct {
+	r     io.ReaderAt
+	size  int64
+	pages map[int64]bool
+}
+
+func (p *pageRecorder) ReadAt(b []byte, off int64) (int, error) {
+	p.pages[off/p.size+1] = true
+	return p.r.ReadAt(b, off)
+}
+
+func TestHead(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var recorder = &pageRecorder{r: file.Pager.file, size: int64(file.PageSize()), pages: make(map[int64]bool)}
+	file.Pager.file = recorder
+
+	var ids []int64
+	if err = table.Head(5, func(rec *Record) error { id, _ := rec.Rowid(); ids = append(ids, id); return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3, 4, 5}) {
+		t.Errorf("expected rows %v; got %v", []int64{1, 2, 3, 4, 5}, ids)
+	}
+
+	// the first rows are reached by reading the path from the root to the left-most leaf only
+	if n := len(recorder.pages); n > 3 {
+		t.Errorf("expected the walk to stop early; %d pages were read", n)
+	}
+
+	var n int
+	if err = table.Head(0, func(*Record) error { n++; return nil }); err != nil || n != 0 {
+		t.Errorf("expected no rows; got %d (err=%v)", n, err)
+	}
+}
+
+func TestSlice(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// a rowid table, whose leaves are skipped, and an index, which falls back to counting rows
+	for _, name := range []string{"Track", "IFK_TrackAlbumId"} {
+		var obj, err = file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// rows are identified by their values, as entries of an index have no rowid
+		var key = func(rows *[]string) func(*Record) error {
+			return func(rec *Record) error {
+				var values, err = rec.AppendValues(nil)
+				var rowid, _ = rec.Rowid()
+				*rows = append(*rows, fmt.Sprint(rowid, values))
+				return err
+			}
+		}
+
+		var all []string
+		if err = obj.ForEach(key(&all)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, c := range [][2]int{{0, 10}, {10, 10}, {1000, 25}, {len(all) - 3, 10}, {len(all), 10}, {2000, -1}, {5, 0}, {-1, 3}, {-10, -1}} {
+			var offset, limit = c[0], c[1]
+
+			var expected []string
+			if offset < 0 { // a negative offset is taken as zero
+				expected = all
+			} else if offset < len(all) {
+				expected = all[offset:]
+			}
+			if limit >= 0 && limit < len(expected) {
+				expected = expected[:limit]
+			}
+
+			var got []string
+			if err = obj.Slice(offset, limit, key(&got)); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(expected) || (len(got) > 0 && !reflect.DeepEqual(got, expected)) {
+				t.Errorf("%s: offset %d, limit %d: expected %d rows; got %d", name, offset, limit, len(expected), len(got))
+			}
+		}
+	}
+}
+
+func TestForEachMap(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Genre")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []map[string]any
+	err = table.ForEachMap(func(row map[string]any) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rows) != 25 {
+		t.Fatalf("expected %d rows; got %d", 25, len(rows))
+	}
+
+	// GenreId aliases the rowid, and is stored as NULL in the record
+	if expected := map[string]any{"GenreId": int64(1), "Name": "Rock"}; !reflect.DeepEqual(rows[0], expected) {
+		t.Errorf("expected %v; got %v", expected, rows[0])
+	}
+
+	index, err := file.Object("IFK_TrackGenreId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first map[string]any
+	err = index.ForEachMap(func(row map[string]any) error {
+		first = row
+		return ErrStopIteration
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := map[string]any{"GenreId": int64(1), "rowid": int64(1)}; !reflect.DeepEqual(first, expected) {
+		t.Errorf("expected %v; got %v", expected, first)
+	}
+
+	// an automatic index has no sql describing its columns
+	if index, err = file.Object("sqlite_autoindex_PlaylistTrack_1"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = index.ForEachMap(func(row map[string]any) error {
+		first = row
+		return ErrStopIteration
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if expected := map[string]any{"col0": int64(1), "col1": int64(1), "col2": int64(1911)}; !reflect.DeepEqual(first, expected) {
+		t.Errorf("expected %v; got %v", expected, first)
+	}
+}
+
+func TestForEachMap_without_rowid(t *testing.T) {
+	var file = open(t, "testdata/without-rowid.db")
+	defer file.Close()
+
+	table, err := file.Object("wordcount")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the primary key is stored in the record, and there is no rowid to add
+	var rows []map[string]any
+	err = table.ForEachMap(func(row map[string]any) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	var expected = []map[string]any{{"word": "hello", "cnt": int64(5)}, {"word": "world", "cnt": int64(5)}}
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %v; got %v", expected, rows)
+	}
+}
+
+func TestObject_Kind(t *testing.T) {
+	var file = open(t, "testdata/views.db")
+	defer file.Close()
+
+	objects, err := file.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []ObjectType{ObjectTable, ObjectTable, ObjectView, ObjectTrigger}
+	for i, obj := range objects {
+		if kind := obj.Kind(); kind != expected[i] {
+			t.Errorf("expected %s to be a %s; got %s", obj.Name(), expected[i], kind)
+		} else if kind.String() != obj.Type() {
+			t.Errorf("expected kind of %s to be named %q; got %q", obj.Name(), obj.Type(), kind)
+		}
+
+		// only tables and indexes are stored in a b-tree
+		if tree := obj.Tree(); (tree != nil) != (expected[i] == ObjectTable) {
+			t.Errorf("%s: unexpected tree %v", obj.Name(), tree)
+		}
+	}
+
+	if kind := NewObject("x", "something", "", nil).Kind(); kind != ObjectUnknown {
+		t.Errorf("expected unknown kind; got %s", kind)
+	}
+}
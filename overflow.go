@@ -15,6 +15,7 @@ type overflow struct {
 	usable int // configured usable size of the page
 	size   int // total size of the overflow content
 	left   int // bytes left to read in overflow
+	pages  int // number of overflow pages visited so far
 }
 
 func newOverflowReader(pager *Pager, page int32, usable, size int) *overflow {
@@ -22,15 +23,21 @@ func newOverflowReader(pager *Pager, page int32, usable, size int) *overflow {
 }
 
 func (o *overflow) Read(buf []byte) (n int, err error) {
-	if (o.page == nil || o.page.Remaining() == 0 || o.left == 0) && o.next == 0 {
-		if o.left != 0 {
-			return 0, io.ErrUnexpectedEOF
-		}
+	if o.left <= 0 { // read everything we were asked for; ignore any remaining link in the chain
 		return 0, io.EOF
 	}
 
+	if (o.page == nil || o.page.Remaining() == 0) && o.next == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
 fetch:
 	if o.page == nil || o.page.Remaining() == 0 {
+		// a chain can't be longer than the database itself; if it is, it must contain a cycle
+		if o.pages++; o.pages > o.pager.pages {
+			return 0, errorf(ErrCorruptCell, "overflow chain exceeds database size (possible cycle at page %d)", o.next)
+		}
+
 		if o.page, err = o.pager.ReadPage(int(o.next)); err != nil {
 			return 0, err
 		}
@@ -24,3 +24,48 @@ func TestOverflow(t *testing.T) {
 
 	t.Logf("content: \n%s", hex.Dump(sink.Bytes()))
 }
+
+func TestOverflow_shared_pages(t *testing.T) {
+	var file = open(t, "testdata/overflow.db")
+	defer file.Close()
+
+	table, err := file.Object("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := table.tree.RootNode()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, size, err := root.overflowPage(0)
+	if err != nil {
+		t.Fatal(err)
+	} else if first == 0 {
+		t.Fatalf("expected the first cell to overflow")
+	}
+
+	// read the same chain through two readers, interleaving their reads so they visit each page at the same time
+	var usable = file.Header.usableSize()
+	var readers = []*overflow{newOverflowReader(file.Pager, first, usable, size), newOverflowReader(file.Pager, first, usable, size)}
+	var sinks = make([]bytes.Buffer, len(readers))
+
+	var buf = make([]byte, 100)
+	for done := 0; done < len(readers); {
+		done = 0
+		for i, r := range readers {
+			n, err := r.Read(buf)
+			sinks[i].Write(buf[:n])
+			if err == io.EOF {
+				done++
+			} else if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if sinks[0].Len() != size || !bytes.Equal(sinks[0].Bytes(), sinks[1].Bytes()) {
+		t.Errorf("expected both readers to read the same %d bytes; got %d and %d bytes", size, sinks[0].Len(), sinks[1].Len())
+	}
+}
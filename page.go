@@ -1,8 +1,11 @@
 package dotlite
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
 // Page represents a single page in the sqlite database file
@@ -16,18 +19,110 @@ func (page *Page) Remaining() int64 {
 	return page.Size() - read
 }
 
-// Pager is a service used to fetch pages from the database file
+// Reserved returns the last n bytes of the page, where n usually is the number of reserved bytes per page
+// configured in the database header (see Header.PageReserved). Extensions (such as checksum or encryption VFS)
+// use this space to store per-page trailers like checksums or nonces.
+//
+// Reading the trailer doesn't affect the current read position of the page.
+func (page *Page) Reserved(n int) (_ []byte, err error) {
+	if n < 0 || int64(n) > page.Size() {
+		return nil, fmt.Errorf("invalid reserved size %d for page of %d bytes", n, page.Size())
+	}
+
+	var buf = make([]byte, n)
+	if n == 0 {
+		return buf, nil
+	}
+
+	if _, err = page.ReadAt(buf, page.Size()-int64(n)); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// Pager is a service used to fetch pages from the database file.
+//
+// A Pager is safe for concurrent use: every Page it returns is an independent reader, the underlying file is only
+// ever read with ReadAt, and the page cache (if any) is guarded by a mutex.
 type Pager struct {
 	size, pages int
 	file        io.ReaderAt
+	length      int64      // size of file in bytes; zero if it isn't known
+	wal         *WAL       // write-ahead log holding newer versions of pages; nil if there is none
+	cache       *pageCache // recently read pages; nil if caching is disabled
 }
 
-// ReadPage reads a single page, identified by its location / id, from the database file
+// ReadPage reads a single page, identified by its location / id, from the database file.
+//
+// If the database was opened along with its write-ahead log (see OpenWithWAL), the latest committed version of
+// the page is read from the log when it holds one. A page that ends past the end of the database file is reported
+// as an error. If the pager has a cache (see WithPageCache), the page is served from the cache when possible,
+// and read in full and added to it otherwise.
+//
+// Every call returns a Page with its own read position, even when the same page is read more than once,
+// so callers may read (and seek within) a page without affecting any other reader of that page.
 func (pager *Pager) ReadPage(i int) (_ *Page, err error) {
-	if i > pager.pages {
-		return nil, fmt.Errorf("page index out of range (%d > %d)", i, pager.pages)
+	if i < 1 || i > pager.pages {
+		return nil, errorf(ErrPageOutOfRange, "page index %d out of range: valid pages are 1 to %d", i, pager.pages)
+	}
+
+	// pages in the log don't depend on the size of the database file
+	if _, logged := pager.wal.lookup(i); !logged && pager.length > 0 && int64(i)*int64(pager.size) > pager.length {
+		var offset, available = int64(i-1) * int64(pager.size), int64(0)
+		if offset < pager.length {
+			available = pager.length - offset
+		}
+		return nil, errorf(ErrPageOutOfRange, "page %d is incomplete: expected %d bytes at offset %d but only %d are available", i, pager.size, offset, available)
+	}
+
+	var r, offset = pager.source(i)
+	if pager.cache == nil {
+		return &Page{ID: i, SectionReader: io.NewSectionReader(r, offset, int64(pager.size))}, nil
 	}
 
-	var pageOffset = int64((i - 1) * pager.size)
-	return &Page{ID: i, SectionReader: io.NewSectionReader(pager.file, pageOffset, int64(pager.size))}, nil
+	var buf, ok = pager.cache.get(i)
+	if !ok {
+		buf = make([]byte, pager.size)
+		if _, err = r.ReadAt(buf, offset); err != nil {
+			if errors.Is(err, io.EOF) { // an incomplete last page is read as is, without being cached
+				return &Page{ID: i, SectionReader: io.NewSectionReader(r, offset, int64(pager.size))}, nil
+			}
+			return nil, err
+		}
+		pager.cache.add(i, buf)
+	}
+
+	return &Page{ID: i, SectionReader: io.NewSectionReader(bytes.NewReader(buf), 0, int64(len(buf)))}, nil
+}
+
+// source returns the reader holding the latest version of the given page, and the offset of the page within it
+func (pager *Pager) source(i int) (io.ReaderAt, int64) {
+	if offset, ok := pager.wal.lookup(i); ok {
+		return pager.wal.file, offset
+	}
+	return pager.file, int64(i-1) * int64(pager.size)
+}
+
+// retryReader is an io.ReaderAt that retries failed reads from the underlying reader,
+// waiting for an exponentially increasing backoff between each attempt.
+type retryReader struct {
+	r        io.ReaderAt
+	attempts int           // maximum number of attempts for a single read
+	backoff  time.Duration // delay before the first retry; doubled after every attempt
+}
+
+func (r *retryReader) ReadAt(p []byte, off int64) (n int, err error) {
+	for i := 0; ; i++ {
+		// io.EOF means we've read past the end; retrying won't change that
+		if n, err = r.r.ReadAt(p, off); err == nil || errors.Is(err, io.EOF) {
+			return n, err
+		}
+
+		if i+1 >= r.attempts {
+			return n, fmt.Errorf("read at offset %d failed after %d attempt(s): %w", off, r.attempts, err)
+		}
+
+		time.Sleep(r.backoff << i)
+	}
 }
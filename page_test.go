@@ -28,12 +28,33 @@ func TestPager(t *testing.T) {
 	var reader = bytes.NewReader(buf)
 	var pager = &Pager{size: 512, pages: 4, file: reader}
 
-	if _, err := pager.ReadPage(5); err == nil {
-		t.Errorf("expected index out of range; got nothing")
+	for _, i := range []int{-1, 0, 5} {
+		var expected = fmt.Sprintf("page index %d out of range: valid pages are 1 to 4", i)
+		if _, err := pager.ReadPage(i); err == nil || err.Error() != expected {
+			t.Errorf("expected %q; got %v", expected, err)
+		}
 	}
 
-	if page, err := pager.ReadPage(1); err != nil || page == nil {
-		t.Errorf("failed to read page #1")
+	for _, i := range []int{1, 4} {
+		if page, err := pager.ReadPage(i); err != nil || page == nil || page.ID != i {
+			t.Errorf("failed to read page #%d: %v", i, err)
+		} else if b, _ := io.ReadAll(page); !bytes.Equal(b, buf[(i-1)*512:i*512]) {
+			t.Errorf("expected page #%d to hold bytes %d to %d of the file", i, (i-1)*512, i*512)
+		}
+	}
+}
+
+func TestPager_incomplete_page(t *testing.T) {
+	var buf = read(t, "testdata/only-pages.bin")[:3*512+100]
+	var pager = &Pager{size: 512, pages: 4, file: bytes.NewReader(buf), length: int64(len(buf))}
+
+	if _, err := pager.ReadPage(3); err != nil {
+		t.Errorf("failed to read page #3: %v", err)
+	}
+
+	var expected = "page 4 is incomplete: expected 512 bytes at offset 1536 but only 100 are available"
+	if _, err := pager.ReadPage(4); err == nil || err.Error() != expected {
+		t.Errorf("expected %q; got %v", expected, err)
 	}
 }
 
@@ -78,3 +99,61 @@ func TestPage_Read(t *testing.T) {
 		t.Errorf("content not equal")
 	}
 }
+
+func TestPage_Reserved(t *testing.T) {
+	var buf = read(t, "testdata/only-pages.bin")
+	var reader = bytes.NewReader(buf)
+	var pager = &Pager{size: 512, pages: 4, file: reader}
+
+	var page, _ = pager.ReadPage(2)
+	if trailer, err := page.Reserved(8); err != nil {
+		t.Error(err)
+	} else if !bytes.Equal(trailer, buf[1024-8:1024]) {
+		t.Errorf("trailer not equal")
+	}
+
+	if sz := page.Remaining(); sz != 512 {
+		t.Errorf("expected reading trailer to not affect position; %d bytes remaining", sz)
+	}
+
+	if trailer, err := page.Reserved(0); err != nil || len(trailer) != 0 {
+		t.Errorf("expected empty trailer; got %v (err: %v)", trailer, err)
+	}
+
+	if _, err := page.Reserved(513); err == nil {
+		t.Errorf("expected error for reserved size larger than page")
+	}
+}
+
+// flakyReader fails the first few reads before delegating to the underlying reader
+type flakyReader struct {
+	io.ReaderAt
+	failures int
+}
+
+func (f *flakyReader) ReadAt(p []byte, off int64) (int, error) {
+	if f.failures > 0 {
+		f.failures--
+		return 0, fmt.Errorf("transient failure")
+	}
+	return f.ReaderAt.ReadAt(p, off)
+}
+
+func TestPager_retry(t *testing.T) {
+	var buf = read(t, "testdata/only-pages.bin")
+
+	var reader = &retryReader{r: &flakyReader{ReaderAt: bytes.NewReader(buf), failures: 2}, attempts: 3}
+	var pager = &Pager{size: 512, pages: 4, file: reader}
+
+	var page, _ = pager.ReadPage(1)
+	if trailer, err := page.Reserved(4); err != nil {
+		t.Errorf("expected read to succeed after retrying; got %v", err)
+	} else if !bytes.Equal(trailer, buf[508:512]) {
+		t.Errorf("content not equal")
+	}
+
+	reader.r = &flakyReader{ReaderAt: bytes.NewReader(buf), failures: 3}
+	if _, err := page.Reserved(4); err == nil {
+		t.Errorf("expected read to fail once attempts are exhausted")
+	}
+}
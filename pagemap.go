@@ -0,0 +1,262 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// PageKind describes what a page of the database file is used for
+type PageKind int
+
+const (
+	PageUnused        PageKind = iota // not referenced by any structure in the file
+	PageTableInterior                 // interior node of a table b-tree
+	PageTableLeaf                     // leaf node of a table b-tree
+	PageIndexInterior                 // interior node of an index b-tree
+	PageIndexLeaf                     // leaf node of an index b-tree
+	PageOverflow                      // holds the overflowing payload of a cell
+	PageFreelistTrunk                 // freelist trunk page, listing free leaf pages
+	PageFreelistLeaf                  // free page
+	PagePtrMap                        // pointer map page of an auto-vacuum database
+	PageLockByte                      // page holding the lock bytes; never used by sqlite
+)
+
+func (k PageKind) String() string {
+	switch k {
+	case PageUnused:
+		return "unused"
+	case PageTableInterior:
+		return "table interior"
+	case PageTableLeaf:
+		return "table leaf"
+	case PageIndexInterior:
+		return "index interior"
+	case PageIndexLeaf:
+		return "index leaf"
+	case PageOverflow:
+		return "overflow"
+	case PageFreelistTrunk:
+		return "freelist trunk"
+	case PageFreelistLeaf:
+		return "freelist leaf"
+	case PagePtrMap:
+		return "ptrmap"
+	case PageLockByte:
+		return "lock-byte"
+	}
+	return "unknown"
+}
+
+// PageMapEntry describes the use of a single page of the database file
+type PageMapEntry struct {
+	Page   int      // page number
+	Kind   PageKind // what the page is used for
+	Cells  int      // number of cells on a b-tree page; zero for any other kind of page
+	Object string   // name of the table or index owning a b-tree or overflow page; empty for any other kind of page
+}
+
+// pendingByte is the offset of the first lock byte; the page containing it is never used
+const pendingByte = 0x40000000
+
+// PageMap classifies every page of the database file, returning one entry per page, ordered by page number.
+//
+// Pages are attributed by walking the b-tree (and overflow chains) of every table and index, including
+// sqlite_schema, and the freelist. In an auto-vacuum database, pointer map pages are located from their fixed positions.
+// Any page not reached this way is reported as PageUnused.
+//
+// As every page must have a single owner, a page reached twice is reported as an error.
+func (f *File) PageMap() (_ []PageMapEntry, err error) {
+	var m = &pageMap{file: f, entries: make([]PageMapEntry, f.NumPages())}
+	for i := range m.entries {
+		m.entries[i].Page = i + 1
+	}
+
+	if lock := pendingByte/f.PageSize() + 1; lock <= f.NumPages() {
+		m.entries[lock-1].Kind = PageLockByte
+	}
+
+	if ptrmap, _ := f.PtrMap(); ptrmap != nil {
+		for _, page := range ptrmap.Pages() {
+			if err = m.claim(page, PagePtrMap, 0, ""); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var objects = []*Object{NewObject("sqlite_schema", "table", "", NewTree(f, f.Pager, 1))}
+	err = f.schema(func(obj *Object) error {
+		objects = append(objects, obj)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, obj := range objects {
+		if !obj.hasTree() {
+			continue
+		}
+
+		if err = m.tree(obj.tree, obj.name); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = m.freelist(); err != nil {
+		return nil, err
+	}
+
+	return m.entries, nil
+}
+
+// Pages returns the sorted page numbers of every node of the tree, along with the overflow pages of its cells.
+// As with File.PageMap, a page reached twice is reported as an error.
+func (tree *Tree) Pages() (_ []int, err error) {
+	var m = &pageMap{file: tree.file, entries: make([]PageMapEntry, tree.file.NumPages())}
+	if err = m.tree(tree, ""); err != nil {
+		return nil, err
+	}
+
+	var pages []int
+	for i, entry := range m.entries {
+		if entry.Kind != PageUnused {
+			pages = append(pages, i+1)
+		}
+	}
+	return pages, nil
+}
+
+// pageKind returns the kind of page holding the node
+func (node *TreeNode) pageKind() PageKind {
+	switch node.Kind() {
+	case NodeTableInt:
+		return PageTableInterior
+	case NodeTableLeaf:
+		return PageTableLeaf
+	case NodeIndexInt:
+		return PageIndexInterior
+	case NodeIndexLeaf:
+		return PageIndexLeaf
+	}
+	return PageUnused
+}
+
+// ptrmapPage returns the pointer map page holding the entry for the given page, in an auto-vacuum database
+//
+// see: https://www.sqlite.org/fileformat.html#pointer_map_or_ptrmap_pages
+func (f *File) ptrmapPage(page int) int {
+	var span = f.Header.usableSize()/5 + 1 // a ptrmap page followed by the pages it holds entries for
+	var ptrmap = (page-2)/span*span + 2
+	if ptrmap == pendingByte/f.PageSize()+1 {
+		ptrmap++
+	}
+	return ptrmap
+}
+
+// pageMap collects the entries of File.PageMap
+type pageMap struct {
+	file    *File
+	entries []PageMapEntry
+}
+
+// claim records the use of the given page, failing if the page is out of range or already used
+func (m *pageMap) claim(page int, kind PageKind, cells int, object string) error {
+	if page < 1 || page > len(m.entries) {
+		return fmt.Errorf("page %d is out of range: database has %d pages", page, len(m.entries))
+	}
+
+	var entry = &m.entries[page-1]
+	if entry.Kind != PageUnused {
+		return fmt.Errorf("page %d is used as both %s and %s", page, entry.Kind, kind)
+	}
+
+	entry.Kind, entry.Cells, entry.Object = kind, cells, object
+	return nil
+}
+
+// tree claims every node of the b-tree, along with the overflow pages of its cells
+func (m *pageMap) tree(tree *Tree, object string) (err error) {
+	var node *TreeNode
+	if node, err = tree.RootNode(); err != nil {
+		return err
+	}
+	return m.node(tree, node, object)
+}
+
+func (m *pageMap) node(tree *Tree, node *TreeNode, object string) (err error) {
+	// claiming a page twice fails, which also stops the walk if the tree contains a cycle
+	if err = m.claim(node.PageID(), node.pageKind(), node.NumCells(), object); err != nil {
+		return err
+	}
+
+	for i := 0; i < node.NumCells(); i++ {
+		var first int32
+		var size int
+		if first, size, err = node.overflowPage(i); err != nil {
+			return err
+		}
+
+		if err = m.overflow(int(first), size, object); err != nil {
+			return err
+		}
+	}
+
+	if node.IsLeaf() {
+		return nil
+	}
+
+	for i := 0; i <= node.NumCells(); i++ {
+		var child *TreeNode
+		if child, err = tree.Child(node, i); err != nil {
+			return err
+		}
+
+		if err = m.node(tree, child, object); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// overflow claims the pages of an overflow chain starting at page and holding size bytes of payload
+func (m *pageMap) overflow(page, size int, object string) (err error) {
+	var usable = m.file.Header.usableSize()
+	for left := size; left > 0; left -= usable - 4 {
+		if err = m.claim(page, PageOverflow, 0, object); err != nil {
+			return err
+		}
+
+		if page, err = m.next(page); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// freelist claims the trunk and leaf pages of the freelist
+func (m *pageMap) freelist() (err error) {
+	var list *Freelist
+	if list, err = m.file.Freelist(); err != nil {
+		return err
+	}
+
+	return list.walk(func(page int, kind PageKind) error { return m.claim(page, kind, 0, "") })
+}
+
+// next returns the page following page in an overflow chain; zero if it is the last page of the chain
+func (m *pageMap) next(page int) (_ int, err error) {
+	var p *Page
+	if p, err = m.file.Pager.ReadPage(page); err != nil {
+		return 0, err
+	}
+
+	var next int32
+	if err = binary.Read(p, binary.BigEndian, &next); err != nil {
+		return 0, err
+	}
+
+	return int(next), nil
+}
@@ -0,0 +1,86 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFile_PageMap(t *testing.T) {
+	// page counts as reported by sqlite's dbstat virtual table and PRAGMA freelist_count
+	var expectations = map[string]struct {
+		interior, leaf, overflow, free, ptrmap int
+	}{
+		"testdata/chinook.db":            {25, 830, 0, 187, 0},
+		"testdata/overflow.db":           {0, 2, 2, 0, 0},
+		"testdata/auto-vacuum.db":        {0, 4, 0, 0, 1},
+		"testdata/incremental-vacuum.db": {2, 13, 20, 29, 1},
+	}
+
+	for name, expected := range expectations {
+		var file = open(t, name)
+
+		var entries, err = file.PageMap()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			_ = file.Close()
+			continue
+		}
+
+		if len(entries) != file.NumPages() {
+			t.Errorf("%s: expected %d entries; got %d", name, file.NumPages(), len(entries))
+		}
+
+		var counts = make(map[PageKind]int)
+		for i, entry := range entries {
+			if entry.Page != i+1 {
+				t.Fatalf("%s: expected entry %d to describe page %d; got page %d", name, i, i+1, entry.Page)
+			}
+
+			counts[entry.Kind]++
+			if (entry.Kind == PageTableLeaf || entry.Kind == PageIndexLeaf || entry.Kind == PageOverflow) && entry.Object == "" {
+				t.Errorf("%s: expected page %d to have an owner", name, entry.Page)
+			}
+		}
+
+		var got = struct{ interior, leaf, overflow, free, ptrmap int }{
+			counts[PageTableInterior] + counts[PageIndexInterior],
+			counts[PageTableLeaf] + counts[PageIndexLeaf],
+			counts[PageOverflow],
+			counts[PageFreelistTrunk] + counts[PageFreelistLeaf],
+			counts[PagePtrMap],
+		}
+
+		if got != expected || counts[PageUnused] != 0 {
+			t.Errorf("%s: expected %+v; got %+v (with %d unused pages)", name, expected, got, counts[PageUnused])
+		}
+
+		_ = file.Close()
+	}
+}
+
+func TestTree_Pages(t *testing.T) {
+	var file = open(t, "testdata/incremental-vacuum.db")
+	defer file.Close()
+
+	// pages of every object as reported by sqlite's dbstat virtual table; the index holds overflowing entries
+	var expectations = map[string][]int{
+		"t":      {3, 5, 6, 7, 8, 9, 10, 11, 12, 13, 24},
+		"t_body": {4, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46},
+	}
+
+	for name, expected := range expectations {
+		var obj, err = file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var pages []int
+		if pages, err = obj.tree.Pages(); err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(pages, expected) {
+			t.Errorf("%s: expected pages %v; got %v", name, expected, pages)
+		}
+	}
+}
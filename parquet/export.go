@@ -0,0 +1,138 @@
+package parquet
+
+import (
+	"fmt"
+	"io"
+
+	"go.riyazali.net/dotlite"
+)
+
+// Options configures Export
+type Options struct {
+	// Columns overrides the columns of the parquet file, which are otherwise derived using InferColumns.
+	// It must describe every column of the table, in order.
+	Columns []Column
+
+	// RowGroupSize is the number of rows written in each row group; zero means DefaultRowGroupSize
+	RowGroupSize int
+}
+
+// Export writes every row of the table to w as a parquet file.
+//
+// Rows are streamed from the table one row group at a time, so tables of any size can be exported.
+// A value that can't be converted to the type of its column fails the export; see Options.Columns
+// to map such a column to a more suitable type (eg. String).
+func Export(w io.Writer, obj *dotlite.Object, opts Options) (err error) {
+	var columns = opts.Columns
+	if columns == nil {
+		if columns, err = InferColumns(obj); err != nil {
+			return err
+		}
+	}
+
+	var rowid int
+	if rowid, err = rowidColumn(obj); err != nil {
+		return err
+	}
+
+	var pw *Writer
+	if pw, err = NewWriter(w, columns, opts.RowGroupSize); err != nil {
+		return err
+	}
+
+	var row []any
+	err = obj.ForEach(func(rec *dotlite.Record) (err error) {
+		if row, err = rec.AppendValues(row[:0]); err != nil {
+			return err
+		}
+
+		if rowid >= 0 && rowid < len(row) {
+			row[rowid], _ = rec.Rowid()
+		}
+
+		return pw.Write(row)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return pw.Close()
+}
+
+// InferColumns returns the columns used to export the table, named after the table's columns.
+//
+// A column's type is derived from its affinity: INTEGER columns are written as Int64, REAL columns as Double,
+// TEXT columns as String and columns declared as BLOB as Bytes. The type of any other column (with NUMERIC affinity,
+// or without a declared type) is inferred from the values it holds (see dotlite.Object.InferTypes), which requires
+// a scan of the table; columns holding values of mixed types, or only NULLs, are written as String.
+func InferColumns(obj *dotlite.Object) (_ []Column, err error) {
+	var cols []*dotlite.Column
+	if cols, err = obj.Columns(); err != nil {
+		return nil, err
+	}
+
+	var columns = make([]Column, len(cols))
+	var inferred *dotlite.TypeInference
+	for i, col := range cols {
+		columns[i].Name = col.Name
+
+		switch col.Affinity {
+		case dotlite.AffinityInteger:
+			columns[i].Type = Int64
+			continue
+		case dotlite.AffinityReal:
+			columns[i].Type = Double
+			continue
+		case dotlite.AffinityText:
+			columns[i].Type = String
+			continue
+		case dotlite.AffinityBlob:
+			if col.Type != "" {
+				columns[i].Type = Bytes
+				continue
+			}
+		}
+
+		if inferred == nil {
+			if inferred, err = obj.InferTypes(dotlite.InferOptions{}); err != nil {
+				return nil, err
+			}
+		}
+
+		columns[i].Type = String
+		if i < len(inferred.Columns) {
+			switch inferred.Columns[i].Type() {
+			case "INTEGER":
+				columns[i].Type = Int64
+			case "REAL":
+				columns[i].Type = Double
+			case "BLOB":
+				columns[i].Type = Bytes
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// rowidColumn returns the position of the column aliasing the table's rowid; -1 if there is none
+func rowidColumn(obj *dotlite.Object) (_ int, err error) {
+	var alias *dotlite.Column
+	if alias, err = obj.RowidColumn(); err != nil || alias == nil {
+		return -1, err
+	}
+
+	var cols []*dotlite.Column
+	if cols, err = obj.Columns(); err != nil {
+		return -1, err
+	}
+
+	for i, col := range cols {
+		if col == alias {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("column %q aliasing the rowid not found in table %q", alias.Name, obj.Name())
+}
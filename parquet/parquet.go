@@ -0,0 +1,417 @@
+// Package parquet exports the rows of a table to a file in the Apache Parquet format,
+// for consumption by analytics tools that cannot read sqlite files.
+//
+// It contains a minimal Parquet writer of its own, so it doesn't pull in any dependency.
+// Values are written uncompressed using the PLAIN encoding, and every column is nullable.
+//
+// see: https://parquet.apache.org/docs/file-format/
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Type is the type a column is written as
+type Type int
+
+const (
+	Int64  Type = iota // 64-bit signed integer
+	Double             // IEEE 64-bit floating point number
+	String             // UTF-8 encoded string
+	Bytes              // arbitrary byte array
+)
+
+func (t Type) String() string {
+	switch t {
+	case Int64:
+		return "INT64"
+	case Double:
+		return "DOUBLE"
+	case String:
+		return "STRING"
+	case Bytes:
+		return "BYTES"
+	}
+	return "unknown"
+}
+
+// Column describes a single column of the parquet file
+type Column struct {
+	Name string // name of the column
+	Type Type   // type values of the column are converted to
+}
+
+// DefaultRowGroupSize is the number of rows written in each row group, unless configured otherwise
+const DefaultRowGroupSize = 10000
+
+// constants from parquet's thrift definitions;
+// see: https://github.com/apache/parquet-format/blob/master/src/main/thrift/parquet.thrift
+const (
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionOptional = 1
+
+	convertedUTF8 = 0
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+	pageTypeData      = 0
+)
+
+// magic is written at the start and at the end of every parquet file
+const magic = "PAR1"
+
+// Writer writes rows to a parquet file. Rows are buffered in memory until a row group is complete,
+// so the memory used is bounded by the size of a row group rather than by the number of rows written.
+type Writer struct {
+	w      io.Writer
+	offset int64 // number of bytes written so far
+
+	columns []Column
+	size    int // number of rows in each row group
+
+	chunks []chunk // data of each column in the current row group
+	rows   int     // number of rows in the current row group
+	row    []any   // scratch space holding the converted values of a row
+
+	groups []rowGroup // row groups written so far
+	total  int64      // number of rows written so far
+	err    error      // error from a previous write; the writer can't be used after an error
+}
+
+// chunk holds the buffered data of a column in the current row group
+type chunk struct {
+	levels []byte       // definition level of each value: 0 if it is NULL, 1 otherwise
+	values bytes.Buffer // non-NULL values, in the PLAIN encoding
+}
+
+// rowGroup describes a row group already written to the file
+type rowGroup struct {
+	rows    int64
+	columns []columnChunk
+}
+
+// columnChunk describes the data of a column within a row group
+type columnChunk struct {
+	offset int64 // offset of the column's data page in the file
+	size   int64 // size of the data page, including its header
+	values int64 // number of values, including NULLs
+}
+
+// NewWriter returns a Writer writing a parquet file with the given columns to w.
+// If rowGroupSize is zero, DefaultRowGroupSize is used.
+//
+// The file is only complete once Close is called.
+func NewWriter(w io.Writer, columns []Column, rowGroupSize int) (_ *Writer, err error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: at least one column is required")
+	}
+
+	for _, col := range columns {
+		if col.Name == "" {
+			return nil, errors.New("parquet: column name cannot be empty")
+		} else if col.Type < Int64 || col.Type > Bytes {
+			return nil, fmt.Errorf("parquet: column %q has an unknown type %d", col.Name, col.Type)
+		}
+	}
+
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	var pw = &Writer{w: w, columns: columns, size: rowGroupSize, chunks: make([]chunk, len(columns)), row: make([]any, len(columns))}
+	if err = pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// Write adds a row to the file. The row must hold a value for every column: nil for NULL, or an int64, float64,
+// string or []byte (as returned by dotlite.Record), which is converted to the column's type.
+// Values that can't be converted are reported as an error, and the row isn't written.
+func (pw *Writer) Write(row []any) (err error) {
+	if pw.err != nil {
+		return pw.err
+	}
+
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("parquet: row has %d values but there are %d columns", len(row), len(pw.columns))
+	}
+
+	for i, val := range row {
+		if pw.row[i], err = convert(val, pw.columns[i].Type); err != nil {
+			return fmt.Errorf("parquet: column %q: %w", pw.columns[i].Name, err)
+		}
+	}
+
+	for i, val := range pw.row {
+		pw.chunks[i].append(val)
+	}
+
+	if pw.rows++; pw.rows >= pw.size {
+		return pw.flush()
+	}
+
+	return nil
+}
+
+// Close writes any buffered rows and the file's footer. It doesn't close the underlying writer.
+func (pw *Writer) Close() (err error) {
+	if pw.err != nil {
+		return pw.err
+	}
+
+	if pw.rows > 0 {
+		if err = pw.flush(); err != nil {
+			return err
+		}
+	}
+
+	var footer = pw.footer()
+
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(footer)))
+
+	for _, b := range [][]byte{footer, length[:], []byte(magic)} {
+		if err = pw.write(b); err != nil {
+			return err
+		}
+	}
+
+	pw.err = errors.New("parquet: writer is closed")
+	return nil
+}
+
+// flush writes the buffered rows as a row group, with a single data page for every column
+func (pw *Writer) flush() (err error) {
+	var group = rowGroup{rows: int64(pw.rows)}
+	for i := range pw.chunks {
+		var c = &pw.chunks[i]
+
+		var page bytes.Buffer
+		var defs = levels(c.levels)
+		_ = binary.Write(&page, binary.LittleEndian, uint32(len(defs)))
+		page.Write(defs)
+		page.Write(c.values.Bytes())
+
+		var header = pageHeader(len(c.levels), page.Len())
+		group.columns = append(group.columns, columnChunk{offset: pw.offset, size: int64(len(header) + page.Len()), values: int64(len(c.levels))})
+
+		if err = pw.write(header); err != nil {
+			return err
+		}
+
+		if err = pw.write(page.Bytes()); err != nil {
+			return err
+		}
+
+		c.levels, c.values = c.levels[:0], bytes.Buffer{}
+	}
+
+	pw.groups = append(pw.groups, group)
+	pw.total += int64(pw.rows)
+	pw.rows = 0
+
+	return nil
+}
+
+// write writes b to the underlying writer, keeping track of the offset
+func (pw *Writer) write(b []byte) (err error) {
+	var n int
+	n, err = pw.w.Write(b)
+	pw.offset += int64(n)
+	if err != nil {
+		pw.err = err
+	}
+	return err
+}
+
+// footer returns the file's metadata, encoded as a FileMetaData struct
+func (pw *Writer) footer() []byte {
+	var c compact
+	c.begin(0)
+	c.i32(1, 1) // version
+
+	c.list(2, typeStruct, len(pw.columns)+1)
+	c.begin(0) // the root of the schema, holding every column
+	c.binary(4, "schema")
+	c.i32(5, int32(len(pw.columns)))
+	c.end()
+	for _, col := range pw.columns {
+		c.begin(0)
+		c.i32(1, col.Type.physical())
+		c.i32(3, repetitionOptional)
+		c.binary(4, col.Name)
+		if col.Type == String {
+			c.i32(6, convertedUTF8)
+		}
+		c.end()
+	}
+
+	c.i64(3, pw.total)
+
+	c.list(4, typeStruct, len(pw.groups))
+	for _, group := range pw.groups {
+		var size int64
+		c.begin(0)
+		c.list(1, typeStruct, len(group.columns))
+		for i, chunk := range group.columns {
+			size += chunk.size
+
+			c.begin(0)
+			c.i64(2, chunk.offset)
+			c.begin(3) // ColumnMetaData
+			c.i32(1, pw.columns[i].Type.physical())
+			c.list(2, typeI32, 2)
+			c.varint(encodingPlain)
+			c.varint(encodingRLE)
+			c.list(3, typeBinary, 1)
+			c.str(pw.columns[i].Name)
+			c.i32(4, codecUncompressed)
+			c.i64(5, chunk.values)
+			c.i64(6, chunk.size)
+			c.i64(7, chunk.size)
+			c.i64(9, chunk.offset)
+			c.end()
+			c.end()
+		}
+		c.i64(2, size)
+		c.i64(3, group.rows)
+		c.end()
+	}
+
+	c.binary(6, "go.riyazali.net/dotlite")
+	c.end()
+
+	return c.buf.Bytes()
+}
+
+// pageHeader returns the header of a data page holding n values (including NULLs) in size bytes
+func pageHeader(n, size int) []byte {
+	var c compact
+	c.begin(0)
+	c.i32(1, pageTypeData)
+	c.i32(2, int32(size))
+	c.i32(3, int32(size))
+	c.begin(5) // DataPageHeader
+	c.i32(1, int32(n))
+	c.i32(2, encodingPlain)
+	c.i32(3, encodingRLE)
+	c.i32(4, encodingRLE)
+	c.end()
+	c.end()
+	return c.buf.Bytes()
+}
+
+// levels encodes definition levels using the RLE / bit-packing hybrid encoding, with a bit width of 1.
+// Only RLE runs are used, which is efficient for the long runs of non-NULL values typical of most columns.
+func levels(defs []byte) []byte {
+	var buf []byte
+	for i := 0; i < len(defs); {
+		var j = i
+		for j < len(defs) && defs[j] == defs[i] {
+			j++
+		}
+
+		var header [binary.MaxVarintLen64]byte
+		buf = append(buf, header[:binary.PutUvarint(header[:], uint64(j-i)<<1)]...)
+		buf = append(buf, defs[i])
+		i = j
+	}
+	return buf
+}
+
+// append adds a value, already converted to the column's type, to the chunk
+func (c *chunk) append(val any) {
+	if val == nil {
+		c.levels = append(c.levels, 0)
+		return
+	}
+	c.levels = append(c.levels, 1)
+
+	var buf [8]byte
+	switch v := val.(type) {
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		c.values.Write(buf[:])
+	case float64:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+		c.values.Write(buf[:])
+	case []byte:
+		binary.LittleEndian.PutUint32(buf[:4], uint32(len(v)))
+		c.values.Write(buf[:4])
+		c.values.Write(v)
+	}
+}
+
+// physical returns parquet's physical type used to store values of type t
+func (t Type) physical() int32 {
+	switch t {
+	case Int64:
+		return physicalInt64
+	case Double:
+		return physicalDouble
+	}
+	return physicalByteArray
+}
+
+// convert converts val to the representation used for a value of type typ: an int64, a float64 or a []byte
+func convert(val any, typ Type) (any, error) {
+	if val == nil {
+		return nil, nil
+	}
+
+	switch typ {
+	case Int64:
+		switch v := val.(type) {
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), nil
+			}
+		case string:
+			if i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+				return i, nil
+			}
+		}
+
+	case Double:
+		switch v := val.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case string:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				return f, nil
+			}
+		}
+
+	case String, Bytes:
+		switch v := val.(type) {
+		case int64:
+			return []byte(strconv.FormatInt(v, 10)), nil
+		case float64:
+			return []byte(strconv.FormatFloat(v, 'g', -1, 64)), nil
+		case string:
+			return []byte(v), nil
+		case []byte:
+			return v, nil
+		}
+	}
+
+	return nil, fmt.Errorf("cannot convert %v (%T) to %s", val, val, typ)
+}
@@ -0,0 +1,235 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+
+	"go.riyazali.net/dotlite"
+)
+
+// decode reads a value of the given thrift compact type from r, returning structs as maps keyed by field id
+// and lists as slices. It is just enough to inspect the metadata written by Writer.
+func decode(t *testing.T, r *bytes.Reader, typ byte) any {
+	switch typ {
+	case typeI32, typeI64:
+		var v, err = binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return int64(v>>1) ^ -int64(v&1)
+
+	case typeBinary:
+		var n, err = binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b = make([]byte, n)
+		if _, err = io.ReadFull(r, b); err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+
+	case typeList:
+		var header, _ = r.ReadByte()
+		var n = uint64(header >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(r)
+		}
+		var list []any
+		for i := uint64(0); i < n; i++ {
+			list = append(list, decode(t, r, header&0x0f))
+		}
+		return list
+
+	case typeStruct:
+		var fields = make(map[int16]any)
+		var id int16
+		for {
+			var header, err = r.ReadByte()
+			if err != nil {
+				t.Fatal(err)
+			} else if header == 0 {
+				return fields
+			}
+
+			if delta := int16(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				var v, _ = binary.ReadUvarint(r)
+				id = int16(int64(v>>1) ^ -int64(v&1))
+			}
+			fields[id] = decode(t, r, header&0x0f)
+		}
+	}
+
+	t.Fatalf("unsupported type %d", typ)
+	return nil
+}
+
+// footer returns the decoded FileMetaData of the parquet file in b
+func footer(t *testing.T, b []byte) map[int16]any {
+	if !bytes.HasPrefix(b, []byte(magic)) || !bytes.HasSuffix(b, []byte(magic)) {
+		t.Fatalf("file doesn't start and end with %q", magic)
+	}
+
+	var n = int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	return decode(t, bytes.NewReader(b[len(b)-8-n:len(b)-8]), typeStruct).(map[int16]any)
+}
+
+func TestExport(t *testing.T) {
+	var file, err = dotlite.Open("../testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := InferColumns(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// UnitPrice is declared as NUMERIC(10,2), and holds REAL values
+	var types = []Type{Int64, String, Int64, Int64, Int64, String, Int64, Int64, Double}
+	for i, col := range columns {
+		if col.Type != types[i] {
+			t.Errorf("column %q: expected type %s; got %s", col.Name, types[i], col.Type)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err = Export(&buf, table, Options{RowGroupSize: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	var meta = footer(t, buf.Bytes())
+	if rows := meta[3].(int64); rows != 3503 {
+		t.Errorf("expected %d rows; got %d", 3503, rows)
+	}
+
+	var schema = meta[2].([]any)
+	if len(schema) != len(columns)+1 {
+		t.Fatalf("expected %d schema elements; got %d", len(columns)+1, len(schema))
+	}
+	for i, col := range columns {
+		if name := schema[i+1].(map[int16]any)[4]; name != col.Name {
+			t.Errorf("schema element %d: expected name %q; got %q", i+1, col.Name, name)
+		}
+	}
+
+	var groups = meta[4].([]any)
+	if len(groups) != 4 {
+		t.Fatalf("expected %d row groups; got %d", 4, len(groups))
+	}
+
+	// read back the first page of TrackId, holding rowids 1 to 1000
+	var chunk = groups[0].(map[int16]any)[1].([]any)[0].(map[int16]any)[3].(map[int16]any)
+	var r = bytes.NewReader(buf.Bytes()[chunk[9].(int64):])
+
+	var header = decode(t, r, typeStruct).(map[int16]any)
+	if n := header[5].(map[int16]any)[1]; n != int64(1000) {
+		t.Fatalf("expected page to hold %d values; got %d", 1000, n)
+	}
+
+	var levels uint32
+	_ = binary.Read(r, binary.LittleEndian, &levels)
+	_, _ = r.Seek(int64(levels), io.SeekCurrent)
+
+	var ids = make([]int64, 1000)
+	if err = binary.Read(r, binary.LittleEndian, ids); err != nil {
+		t.Fatal(err)
+	}
+	for i, id := range ids {
+		if id != int64(i+1) {
+			t.Fatalf("expected TrackId %d; got %d", i+1, id)
+		}
+	}
+}
+
+func TestExport_override(t *testing.T) {
+	var file, err = dotlite.Open("../testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	table, err := file.Object("Genre")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Name holds text that can't be converted to an integer
+	var columns = []Column{{"GenreId", String}, {"Name", Int64}}
+	if err = Export(io.Discard, table, Options{Columns: columns}); err == nil {
+		t.Errorf("expected an error converting Name to %s", Int64)
+	}
+
+	columns[1].Type = Bytes
+
+	var buf bytes.Buffer
+	if err = Export(&buf, table, Options{Columns: columns}); err != nil {
+		t.Fatal(err)
+	}
+
+	var schema = footer(t, buf.Bytes())[2].([]any)
+	if typ := schema[1].(map[int16]any)[1]; typ != int64(physicalByteArray) {
+		t.Errorf("expected GenreId to be written as a byte array; got type %d", typ)
+	}
+}
+
+func TestWriter_nulls(t *testing.T) {
+	var buf bytes.Buffer
+	var pw, err = NewWriter(&buf, []Column{{"a", Double}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, row := range [][]any{{nil}, {nil}, {int64(1)}, {2.5}, {nil}} {
+		if err = pw.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = pw.Write([]any{"x"}); err == nil {
+		t.Errorf("expected an error converting text to %s", Double)
+	}
+
+	if err = pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var meta = footer(t, buf.Bytes())
+	var chunk = meta[4].([]any)[0].(map[int16]any)[1].([]any)[0].(map[int16]any)[3].(map[int16]any)
+	if n := chunk[5]; n != int64(5) {
+		t.Errorf("expected %d values; got %d", 5, n)
+	}
+
+	var r = bytes.NewReader(buf.Bytes()[chunk[9].(int64):])
+	_ = decode(t, r, typeStruct)
+
+	// definition levels: a run of two NULLs, two values and a NULL
+	var levels = make([]byte, 10)
+	if _, err = io.ReadFull(r, levels); err != nil {
+		t.Fatal(err)
+	} else if expected := []byte{6, 0, 0, 0, 2 << 1, 0, 2 << 1, 1, 1 << 1, 0}; !bytes.Equal(levels, expected) {
+		t.Errorf("expected levels %v; got %v", expected, levels)
+	}
+
+	var values = make([]float64, 2)
+	if err = binary.Read(r, binary.LittleEndian, values); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(values, []float64{1, 2.5}) {
+		t.Errorf("expected values %v; got %v", []float64{1, 2.5}, values)
+	}
+
+	if err = pw.Write([]any{nil}); err == nil {
+		t.Errorf("expected writing to a closed writer to fail")
+	}
+}
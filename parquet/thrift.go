@@ -0,0 +1,93 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// This file contains a minimal encoder for thrift's compact protocol, used by parquet to serialize its metadata.
+// It only supports the types needed to write parquet's page headers and file footer.
+//
+// see: https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+
+// types of fields in the compact protocol
+const (
+	typeI32    = 5
+	typeI64    = 6
+	typeBinary = 8
+	typeList   = 9
+	typeStruct = 12
+)
+
+// compact encodes values using thrift's compact protocol
+type compact struct {
+	buf  bytes.Buffer
+	last []int16 // id of the last field written in each enclosing struct
+}
+
+// field writes the header of the field with the given id and type
+func (c *compact) field(id int16, typ byte) {
+	var top = &c.last[len(c.last)-1]
+	if delta := id - *top; delta > 0 && delta <= 15 {
+		c.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		c.buf.WriteByte(typ)
+		c.varint(int64(id))
+	}
+	*top = id
+}
+
+func (c *compact) i32(id int16, v int32) {
+	c.field(id, typeI32)
+	c.varint(int64(v))
+}
+
+func (c *compact) i64(id int16, v int64) {
+	c.field(id, typeI64)
+	c.varint(v)
+}
+
+func (c *compact) binary(id int16, s string) {
+	c.field(id, typeBinary)
+	c.str(s)
+}
+
+// list writes the header of a list field holding n elements of type typ
+func (c *compact) list(id int16, typ byte, n int) {
+	c.field(id, typeList)
+	if n < 15 {
+		c.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		c.buf.WriteByte(0xf0 | typ)
+		c.uvarint(uint64(n))
+	}
+}
+
+// begin starts a struct, either as a field (with the given id) of the enclosing struct or,
+// if id is zero, as the top-level value or an element of a list
+func (c *compact) begin(id int16) {
+	if id != 0 {
+		c.field(id, typeStruct)
+	}
+	c.last = append(c.last, 0)
+}
+
+// end terminates the current struct
+func (c *compact) end() {
+	c.buf.WriteByte(0) // stop field
+	c.last = c.last[:len(c.last)-1]
+}
+
+// str writes a string, on its own or as an element of a list
+func (c *compact) str(s string) {
+	c.uvarint(uint64(len(s)))
+	c.buf.WriteString(s)
+}
+
+// varint writes v as a zigzag-encoded varint, on its own or as an element of a list
+func (c *compact) varint(v int64) { c.uvarint(uint64(v<<1) ^ uint64(v>>63)) }
+
+func (c *compact) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	c.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
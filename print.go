@@ -0,0 +1,260 @@
+package dotlite
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxCellWidth is the maximum width of a value printed by WriteTable; longer values are truncated
+const maxCellWidth = 40
+
+// WriteTable prints up to limit rows of the object to w as an aligned ASCII table, similar to
+// the output of sqlite3 shell's table mode. If limit is less than or equal to zero, all rows are printed.
+//
+// Column widths are computed from the printed rows, and values wider than 40 characters are truncated
+// with an ellipsis. If the object holds more rows than were printed, a trailing line says so.
+func (obj *Object) WriteTable(w io.Writer, limit int) (err error) {
+	var header []string
+	var rowid int // position of the column aliasing the rowid; stored as NULL in the record
+	if header, rowid, err = obj.header(); err != nil {
+		return err
+	}
+
+	var rows [][]string
+	var more = false
+	err = obj.ForEach(func(rec *Record) (err error) {
+		if limit > 0 && len(rows) >= limit {
+			more = true
+			return ErrStopIteration
+		}
+
+		var row []string
+		if row, err = rec.Strings(); err != nil {
+			return err
+		}
+
+		if rowid >= 0 && rowid < len(row) && row[rowid] == "NULL" {
+			row[rowid] = fmt.Sprint(rec.cell.Rowid)
+		}
+
+		rows = append(rows, row)
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// records may hold more values than the schema describes (eg. an index on a table with a bad schema)
+	for _, row := range rows {
+		for len(header) < len(row) {
+			header = append(header, fmt.Sprintf("column%d", len(header)+1))
+		}
+	}
+
+	var widths = make([]int, len(header))
+	for _, row := range append([][]string{header}, rows...) {
+		for i, val := range row {
+			if n := utf8.RuneCountInString(displayValue(val)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var bw = bufio.NewWriter(w)
+
+	var separator = func() {
+		for _, width := range widths {
+			bw.WriteString("+" + strings.Repeat("-", width+2))
+		}
+		bw.WriteString("+\n")
+	}
+
+	var line = func(row []string) {
+		for i, width := range widths {
+			var val string
+			if i < len(row) {
+				val = displayValue(row[i])
+			}
+			bw.WriteString("| " + val + strings.Repeat(" ", width-utf8.RuneCountInString(val)) + " ")
+		}
+		bw.WriteString("|\n")
+	}
+
+	separator()
+	line(header)
+	separator()
+	for _, row := range rows {
+		line(row)
+	}
+	if len(rows) > 0 {
+		separator()
+	}
+
+	if more {
+		fmt.Fprintf(bw, "(more rows after the first %d)\n", limit)
+	}
+
+	return bw.Flush()
+}
+
+// header returns the names of the columns of a table or an index, along with the position of the column
+// aliasing the rowid of a table (or -1 if there isn't one)
+func (obj *Object) header() (header []string, rowid int, err error) {
+	rowid = -1
+	switch obj.kind {
+	case ObjectTable:
+		var table *tableSchema
+		if table, err = obj.schema(); err != nil {
+			return nil, -1, err
+		}
+
+		for _, col := range table.columns {
+			header = append(header, col.Name)
+		}
+		rowid = table.rowid
+	case ObjectIndex:
+		var layout []IndexColumnRole
+		if layout, err = obj.RecordLayout(); err != nil {
+			return nil, -1, err
+		}
+
+		for _, role := range layout {
+			header = append(header, role.Name)
+		}
+	default:
+		return nil, -1, fmt.Errorf("object %q is not a table or an index", obj.name)
+	}
+
+	return header, rowid, nil
+}
+
+// WriteCSV writes all rows of the object to w as CSV, quoted as per encoding/csv, preceded by a header row
+// with the names of the columns. NULL is written as an empty field, and BLOBs as hexadecimal strings.
+//
+// Rows are written as they are read, so the object is never held in memory as a whole.
+func (obj *Object) WriteCSV(w io.Writer) (err error) {
+	var header []string
+	var rowid int
+	if header, rowid, err = obj.header(); err != nil {
+		return err
+	}
+
+	var cw = csv.NewWriter(w)
+	if err = cw.Write(header); err != nil {
+		return err
+	}
+
+	var row []string
+	err = obj.ForEach(func(rec *Record) (err error) {
+		row = row[:0]
+		for i := 0; i < rec.NumValues(); i++ {
+			var val any
+			if val, err = rec.ValueAt(i); err != nil {
+				return err
+			}
+
+			switch v := val.(type) {
+			case nil:
+				if i == rowid {
+					var id, _ = rec.Rowid()
+					row = append(row, strconv.FormatInt(id, 10))
+				} else {
+					row = append(row, "")
+				}
+			case []byte:
+				row = append(row, hex.EncodeToString(v))
+			default:
+				row = append(row, formatValue(v))
+			}
+		}
+
+		return cw.Write(row)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSONL writes all rows of the object to w as newline-delimited JSON, one object per row holding the row's
+// values keyed by the name of their column, as passed by ForEachMap. NULL is written as null, and BLOBs as
+// base64-encoded strings. Like WriteCSV, rows are written as they are read.
+func (obj *Object) WriteJSONL(w io.Writer) error {
+	var enc = json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return obj.ForEachMap(func(row map[string]any) error { return enc.Encode(row) })
+}
+
+// jsonNode is the JSON representation of a node written by WriteJSON
+type jsonNode struct {
+	Page      int         `json:"page"`
+	Kind      string      `json:"kind"`
+	Cells     int         `json:"cells"`
+	Children  []*jsonNode `json:"children,omitempty"`
+	Truncated bool        `json:"truncated,omitempty"` // set on interior nodes whose children are past maxDepth
+}
+
+// WriteJSON writes the structure of the tree to w as nested JSON objects, one per node, holding the node's
+// page number, kind (as described by PageKind), number of cells and, for interior nodes, its children in order.
+//
+// Only nodes up to maxDepth levels below the root are written (a maxDepth of zero writes the root only);
+// interior nodes whose children are omitted are marked as truncated. A negative maxDepth writes the whole tree.
+func (tree *Tree) WriteJSON(w io.Writer, maxDepth int) (err error) {
+	var root *TreeNode
+	if root, err = tree.RootNode(); err != nil {
+		return err
+	}
+
+	var node *jsonNode
+	if node, err = tree.jsonNode(root, 0, maxDepth, map[int]bool{root.PageID(): true}); err != nil {
+		return err
+	}
+
+	return json.NewEncoder(w).Encode(node)
+}
+
+func (tree *Tree) jsonNode(node *TreeNode, depth, maxDepth int, visited map[int]bool) (_ *jsonNode, err error) {
+	var out = &jsonNode{Page: node.PageID(), Kind: node.pageKind().String(), Cells: node.NumCells()}
+	if node.IsLeaf() {
+		return out, nil
+	} else if depth == maxDepth {
+		out.Truncated = true
+		return out, nil
+	}
+
+	for i := 0; i <= node.NumCells(); i++ {
+		var child *TreeNode
+		if child, err = tree.child(node, i, depth+1, visited); err != nil {
+			return nil, err
+		}
+
+		var c *jsonNode
+		if c, err = tree.jsonNode(child, depth+1, maxDepth, visited); err != nil {
+			return nil, err
+		}
+		out.Children = append(out.Children, c)
+	}
+
+	return out, nil
+}
+
+// displayValue prepares val to be printed in a single cell of the table,
+// replacing line breaks and truncating it to maxCellWidth
+func displayValue(val string) string {
+	val = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(val)
+	if utf8.RuneCountInString(val) > maxCellWidth {
+		val = string([]rune(val)[:maxCellWidth-3]) + "..."
+	}
+	return val
+}
@@ -0,0 +1,190 @@
+package dotlite
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObject_WriteTable(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Genre")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err = table.WriteTable(&out, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = `+---------+------+
+| GenreId | Name |
++---------+------+
+| 1       | Rock |
+| 2       | Jazz |
++---------+------+
+(more rows after the first 2)
+`
+
+	if out.String() != expected {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestDisplayValue(t *testing.T) {
+	if v := displayValue("a\nb"); v != "a b" {
+		t.Errorf("expected line breaks to be replaced; got %q", v)
+	}
+
+	var v = displayValue(strings.Repeat("x", 100))
+	if n := len(v); n != maxCellWidth || !strings.HasSuffix(v, "...") {
+		t.Errorf("expected value to be truncated to %d characters; got %q", maxCellWidth, v)
+	}
+}
+
+func TestTree_WriteJSON(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	table, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err = table.tree.WriteJSON(&out, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{"page":409,"kind":"table interior","cells":1,"truncated":true}` + "\n"; out.String() != expected {
+		t.Errorf("expected %q; got %q", expected, out.String())
+	}
+
+	out.Reset()
+	if err = table.tree.WriteJSON(&out, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	var root jsonNode
+	if err = json.Unmarshal([]byte(out.String()), &root); err != nil {
+		t.Fatal(err)
+	}
+
+	// the counts reported by the dbstat virtual table
+	var interior, leaves int
+	var count func(node *jsonNode)
+	count = func(node *jsonNode) {
+		if node.Kind == PageTableLeaf.String() {
+			leaves++
+		} else {
+			interior++
+		}
+		for _, child := range node.Children {
+			count(child)
+		}
+	}
+	count(&root)
+
+	if interior != 3 || leaves != 235 {
+		t.Errorf("expected %d interior and %d leaf nodes; got %d and %d", 3, 235, interior, leaves)
+	}
+}
+
+func TestObject_WriteCSV(t *testing.T) {
+	var read = func(path, name string) [][]string {
+		var file = open(t, path)
+		defer file.Close()
+
+		obj, err := file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var out strings.Builder
+		if err = obj.WriteCSV(&out); err != nil {
+			t.Fatal(err)
+		}
+
+		records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return records
+	}
+
+	t.Run("mixed types", func(t *testing.T) {
+		var expected = [][]string{
+			{"i", "t", "r", "n", "b"},
+			{"2.7", "12", "3", "4.0", "5"}, // the REAL 3.0 is stored as an integer
+			{" 42abc", "1.5", "2.5e1x", "abc", "text"},
+			{"", "", "", "", ""},
+		}
+
+		if records := read("testdata/mixed-types.db", "m"); !reflect.DeepEqual(records, expected) {
+			t.Errorf("expected %q, got %q", expected, records)
+		}
+	})
+
+	t.Run("blobs", func(t *testing.T) {
+		var records = read("testdata/large-blob.db", "b")
+		if len(records) != 5 {
+			t.Fatalf("expected a header and 4 rows, got %d records", len(records))
+		}
+
+		if n := len(records[1][2]); n != 200000 {
+			t.Errorf("expected 100000 bytes of blob as 200000 hex digits, got %d", n)
+		}
+
+		var expected = [][]string{{"3", "small", "0102030405", "end"}, {"4", "null", "", "end"}}
+		if !reflect.DeepEqual(records[3:], expected) {
+			t.Errorf("expected %q, got %q", expected, records[3:])
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		var records = read("testdata/chinook.db", "IFK_TrackAlbumId")
+		if len(records) != 3504 {
+			t.Errorf("expected a header and 3503 rows, got %d records", len(records))
+		}
+	})
+}
+
+func TestObject_WriteJSONL(t *testing.T) {
+	var file = open(t, "testdata/large-blob.db")
+	defer file.Close()
+
+	obj, err := file.Object("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err = obj.WriteJSONL(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines = strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+
+	var expected = []string{
+		`{"data":"AQIDBAU=","head":"small","id":3,"tail":"end"}`,
+		`{"data":null,"head":"null","id":4,"tail":"end"}`,
+	}
+	if !reflect.DeepEqual(lines[2:], expected) {
+		t.Errorf("expected %q, got %q", expected, lines[2:])
+	}
+
+	var row struct{ Data []byte }
+	if err = json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatal(err)
+	} else if len(row.Data) != 100000 {
+		t.Errorf("expected 100000 bytes of blob, got %d", len(row.Data))
+	}
+}
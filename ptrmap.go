@@ -0,0 +1,131 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PtrMapType is the type of a page as recorded in its pointer map entry
+type PtrMapType byte
+
+const (
+	PtrMapRootPage  PtrMapType = iota + 1 // root page of a b-tree; has no parent
+	PtrMapFreePage                        // page on the freelist; has no parent
+	PtrMapOverflow1                       // first page of an overflow chain; the parent is the b-tree page holding the cell
+	PtrMapOverflow2                       // subsequent page of an overflow chain; the parent is the previous page of the chain
+	PtrMapBtree                           // non-root b-tree page; the parent is the b-tree page pointing at it
+)
+
+func (t PtrMapType) String() string {
+	switch t {
+	case PtrMapRootPage:
+		return "root page"
+	case PtrMapFreePage:
+		return "free page"
+	case PtrMapOverflow1:
+		return "overflow head"
+	case PtrMapOverflow2:
+		return "overflow"
+	case PtrMapBtree:
+		return "b-tree"
+	}
+	return fmt.Sprintf("unknown (%d)", byte(t))
+}
+
+// PtrMapEntry is the pointer map entry of a single page of the database file
+type PtrMapEntry struct {
+	Page   int        // page the entry describes
+	Type   PtrMapType // type of the page
+	Parent int        // page pointing at the page; zero for root and free pages
+}
+
+// PtrMap reads the pointer map of an auto-vacuum (or incremental-vacuum) database. Pointer map pages are
+// interspersed with other pages at fixed locations; each records the type and parent of the pages following it.
+//
+// see: https://www.sqlite.org/fileformat.html#pointer_map_or_ptrmap_pages
+type PtrMap struct {
+	file *File
+}
+
+// PtrMap returns the pointer map of the database; it fails if the database doesn't use auto-vacuum
+func (f *File) PtrMap() (*PtrMap, error) {
+	if f.LargestRootPage() == 0 {
+		return nil, errors.New("database doesn't use auto-vacuum and has no pointer map")
+	}
+	return &PtrMap{file: f}, nil
+}
+
+// Pages returns the page numbers of all pointer map pages in the database, in order
+func (m *PtrMap) Pages() (pages []int) {
+	for page := 2; page <= m.file.NumPages(); page++ {
+		if m.file.ptrmapPage(page) == page {
+			pages = append(pages, page)
+		}
+	}
+	return pages
+}
+
+// Entry returns the pointer map entry of the given page. Page 1, pointer map pages and the lock-byte page
+// don't have an entry.
+func (m *PtrMap) Entry(page int) (_ PtrMapEntry, err error) {
+	if page < 2 || page > m.file.NumPages() {
+		return PtrMapEntry{}, fmt.Errorf("page %d is out of range: database has %d pages", page, m.file.NumPages())
+	}
+
+	var ptrmap = m.file.ptrmapPage(page)
+	if page <= ptrmap || page == pendingByte/m.file.PageSize()+1 {
+		return PtrMapEntry{}, fmt.Errorf("page %d has no pointer map entry", page)
+	}
+
+	var entries []PtrMapEntry
+	if entries, err = m.ReadPage(ptrmap); err != nil {
+		return PtrMapEntry{}, err
+	}
+
+	for _, entry := range entries {
+		if entry.Page == page {
+			return entry, nil
+		}
+	}
+
+	return PtrMapEntry{}, fmt.Errorf("page %d has no pointer map entry", page)
+}
+
+// ReadPage decodes the pointer map page at the given page number, returning the entries of the pages that
+// follow it, up to the next pointer map page or the end of the database. The lock-byte page is skipped.
+func (m *PtrMap) ReadPage(ptrmap int) (entries []PtrMapEntry, err error) {
+	if ptrmap < 2 || ptrmap > m.file.NumPages() || m.file.ptrmapPage(ptrmap) != ptrmap {
+		return nil, fmt.Errorf("page %d is not a pointer map page", ptrmap)
+	}
+
+	var page *Page
+	if page, err = m.file.Pager.ReadPage(ptrmap); err != nil {
+		return nil, err
+	}
+
+	var buf = make([]byte, m.file.Header.usableSize()/5*5)
+	if _, err = io.ReadFull(page, buf); err != nil {
+		return nil, err
+	}
+
+	var lock = pendingByte/m.file.PageSize() + 1
+	for i := 0; i < len(buf); i += 5 {
+		var id = ptrmap + 1 + i/5
+		if id > m.file.NumPages() {
+			break
+		} else if id == lock {
+			continue
+		}
+
+		var entry = PtrMapEntry{Page: id, Type: PtrMapType(buf[i]), Parent: int(binary.BigEndian.Uint32(buf[i+1:]))}
+		if entry.Type < PtrMapRootPage || entry.Type > PtrMapBtree {
+			return nil, fmt.Errorf("pointer map page %d holds an invalid entry of type %d for page %d", ptrmap, buf[i], id)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
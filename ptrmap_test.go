@@ -0,0 +1,95 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFile_PtrMap(t *testing.T) {
+	var file = open(t, "testdata/auto-vacuum.db")
+	defer file.Close()
+
+	ptrmap, err := file.PtrMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pages := ptrmap.Pages(); !reflect.DeepEqual(pages, []int{2}) {
+		t.Errorf("expected a single pointer map page 2; got %v", pages)
+	}
+
+	// root pages of a, b and b_y
+	var expected = []PtrMapEntry{{3, PtrMapRootPage, 0}, {4, PtrMapRootPage, 0}, {5, PtrMapRootPage, 0}}
+	if entries, err := ptrmap.ReadPage(2); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v; got %v", expected, entries)
+	}
+
+	if _, err = ptrmap.ReadPage(3); err == nil {
+		t.Errorf("expected an error reading page 3 as a pointer map page")
+	}
+
+	for _, page := range []int{1, 2, 6} {
+		if _, err = ptrmap.Entry(page); err == nil {
+			t.Errorf("expected an error reading the entry of page %d", page)
+		}
+	}
+
+	var chinook = open(t, "testdata/chinook.db")
+	defer chinook.Close()
+
+	if _, err = chinook.PtrMap(); err == nil {
+		t.Errorf("expected an error reading the pointer map of a database without auto-vacuum")
+	}
+}
+
+func TestPtrMap_Entry(t *testing.T) {
+	var file = open(t, "testdata/incremental-vacuum.db")
+	defer file.Close()
+
+	ptrmap, err := file.PtrMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// check every entry against the use of the page, found by walking the file's structures
+	entries, err := file.PageMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var roots = map[int]bool{3: true, 4: true}
+	for _, page := range entries[1:] {
+		if page.Kind == PagePtrMap {
+			continue
+		}
+
+		entry, err := ptrmap.Entry(page.Page)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var parent = PageMapEntry{}
+		if entry.Parent != 0 {
+			parent = entries[entry.Parent-1]
+		}
+
+		switch page.Kind {
+		case PageFreelistTrunk, PageFreelistLeaf:
+			if entry.Type != PtrMapFreePage || entry.Parent != 0 {
+				t.Errorf("expected free page %d to have no parent; got %v", page.Page, entry)
+			}
+		case PageOverflow:
+			if entry.Type != PtrMapOverflow1 && entry.Type != PtrMapOverflow2 || parent.Object != page.Object {
+				t.Errorf("expected overflow page %d of %s; got %v (parent %v)", page.Page, page.Object, entry, parent)
+			}
+		default:
+			if roots[page.Page] && (entry.Type != PtrMapRootPage || entry.Parent != 0) {
+				t.Errorf("expected root page %d; got %v", page.Page, entry)
+			} else if !roots[page.Page] && (entry.Type != PtrMapBtree || parent.Object != page.Object) {
+				t.Errorf("expected b-tree page %d of %s; got %v (parent %v)", page.Page, page.Object, entry, parent)
+			}
+		}
+	}
+}
@@ -1,10 +1,19 @@
 package dotlite
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
+	"math"
+	"strconv"
 	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // RecordVal holds type and offset information about a single value contained in the record
@@ -13,11 +22,73 @@ type RecordVal struct {
 	Offset int64 // offset from start of cell region
 }
 
+// StorageClass is the storage class of a value as defined under https://www.sqlite.org/datatype3.html#storage_classes_and_datatypes
+type StorageClass int
+
+const (
+	StorageNull StorageClass = iota
+	StorageInteger
+	StorageReal
+	StorageText
+	StorageBlob
+)
+
+func (sc StorageClass) String() string {
+	switch sc {
+	case StorageNull:
+		return "NULL"
+	case StorageInteger:
+		return "INTEGER"
+	case StorageReal:
+		return "REAL"
+	case StorageText:
+		return "TEXT"
+	case StorageBlob:
+		return "BLOB"
+	}
+	return fmt.Sprintf("StorageClass(%d)", int(sc))
+}
+
+// storageClass returns the storage class of values with serial type t
+func storageClass(t int) StorageClass {
+	switch {
+	case t == 0:
+		return StorageNull
+	case t == 7:
+		return StorageReal
+	case t <= 9:
+		return StorageInteger
+	case t >= 12 && t%2 == 0:
+		return StorageBlob
+	case t >= 13:
+		return StorageText
+	}
+	return StorageNull // serial types 10 and 11 are reserved
+}
+
 // Record represents an individual record saved in btree in the Record Format (https://www.sqlite.org/fileformat.html#record_format)
 type Record struct {
 	encoding TextEncoding // supported text encoding for this file
 	cell     *Cell        // cell backing this record
 	values   []RecordVal  // slice of meta information about the values contained within the record
+
+	// number of columns the record is expected to have. sqlite omits trailing NULL
+	// values from a record, so a record may hold fewer values than there are columns.
+	columns int
+
+	affinities []Affinity // if set, values are converted to the affinity of their column
+
+	invalidText InvalidTextPolicy // how invalid text values are returned
+
+	uncheckedJSON bool // if set, AsJSON doesn't validate the JSON text it returns
+
+	// if set, maps the position of a column to the position of its value in the stored record.
+	// WITHOUT ROWID tables store the primary key columns first, regardless of where they are declared.
+	order []int
+
+	// names of the columns of the object holding the record, used to access values by name; nil if unknown
+	names []string
+	alias int // position of the column aliasing the rowid; -1 if there isn't one or the columns aren't known
 }
 
 // NewRecord creates a new record from the given cell
@@ -28,36 +99,140 @@ func NewRecord(enc TextEncoding, cell *Cell) (_ *Record, err error) {
 	var v int64 // reusable varint holder
 	var n = cell.Len()
 	if v, err = Varint(cell); err != nil {
+		if err == io.EOF { // the payload ends before the header does; other errors come from reading overflow pages
+			return nil, errorf(ErrCorruptRecord, "malformed record: payload of %d bytes ends in the header size", cell.size())
+		}
 		return nil, err
 	}
 
+	var size = cell.size() // size of the whole payload
+
+	// the declared size is read from the file: whatever isn't stored locally must fit on the file's overflow pages
+	if c := cell.chain; c != nil && size-int64(len(cell.s)) > int64(c.pager.pages)*int64(c.usable-4) {
+		return nil, errorf(ErrCorruptRecord, "malformed record: payload of %d bytes is larger than the file can hold", size)
+	}
+
+	if v < int64(n-cell.Len()) || v > size {
+		return nil, errorf(ErrCorruptRecord, "malformed record: header size %d is invalid for a payload of %d bytes", v, size)
+	}
+
 	var headerSize = int(v) - (n - cell.Len())
 	var body = v // offset where body starts
 
 	for i := 0; i < headerSize; {
 		n = cell.Len()
 		if v, err = Varint(cell); err != nil {
+			if err == io.EOF {
+				return nil, errorf(ErrCorruptRecord, "malformed record: payload ends in the serial type of value %d", len(values))
+			}
 			return nil, err
 		}
-		i += n - cell.Len()
+
+		if i += n - cell.Len(); i > headerSize {
+			return nil, errorf(ErrCorruptRecord, "malformed record: serial type of value %d extends past the end of the header", len(values))
+		}
+
+		// serial types 8 and 9 (the integers 0 and 1, stored without a body) were introduced with schema format 4
+		if (v == 8 || v == 9) && cell.format > 0 && cell.format < 4 {
+			return nil, errorf(ErrCorruptRecord, "malformed record: value %d has serial type %d, which is not valid in schema format %d", len(values), v, cell.format)
+		}
 
 		values = append(values, RecordVal{Type: int(v), Offset: body})
-		body += typeSize(v)
+
+		// stop as soon as the values outgrow the payload, before the sum can overflow
+		if body += typeSize(v); body > size {
+			break
+		}
 	}
 
-	return &Record{encoding: enc, cell: cell, values: values}, nil
+	// the values must fill the rest of the payload exactly
+	if body != size {
+		return nil, errorf(ErrCorruptRecord, "malformed record: header and values take %d bytes but the payload holds %d", body, size)
+	}
+
+	return &Record{encoding: enc, cell: cell, values: values, alias: -1}, nil
+}
+
+// DecodeRecord decodes a raw payload in the record format (https://www.sqlite.org/fileformat.html#record_format).
+func DecodeRecord(enc TextEncoding, payload []byte) (*Record, error) {
+	return NewRecord(enc, &Cell{Size: int64(len(payload)), s: payload})
+}
+
+// DecodeRecordFromBlob interprets the content of a BLOB value as a nested record.
+//
+// Some applications serialize sqlite records and store them inside BLOB columns; this allows
+// decoding such values recursively. The blob is expected to use the same text encoding as enc.
+func DecodeRecordFromBlob(enc TextEncoding, b []byte) (_ *Record, err error) {
+	var rec *Record
+	if rec, err = DecodeRecord(enc, b); err != nil {
+		return nil, fmt.Errorf("blob is not a valid record: %w", err)
+	}
+	return rec, nil
 }
 
 // Encoding returns the text encoding used by the record
 func (rec *Record) Encoding() TextEncoding { return rec.encoding }
 
-// NumValues return the number of values contained within this record
-func (rec *Record) NumValues() int { return len(rec.values) }
+// Rowid returns the rowid of the table row holding the record, along with whether the record has one: ok is false
+// (and rowid zero) for records of an index or a WITHOUT ROWID table, and for records decoded with DecodeRecord,
+// telling those apart from a row whose rowid is zero.
+//
+// A column aliasing the rowid (see Object.RowidColumn) is stored as NULL in the record; its value is the rowid.
+func (rec *Record) Rowid() (rowid int64, ok bool) { return rec.cell.Rowid, rec.cell.rowid }
+
+// NumValues return the number of values contained within this record.
+// It includes any trailing NULL values that were omitted when storing the record.
+func (rec *Record) NumValues() int {
+	if rec.columns > len(rec.values) {
+		return rec.columns
+	}
+	return len(rec.values)
+}
 
-// ValueAt returns the value at position c as a golang primitive type
-func (rec *Record) ValueAt(c int) (any, error) {
-	if c > rec.NumValues() {
+// ValueAt returns the value at position c as a golang primitive type.
+//
+// If the file was opened WithTypedValues, the value is converted to the affinity of its column.
+func (rec *Record) ValueAt(c int) (_ any, err error) {
+	var val any
+	if val, err = rec.value(rec.position(c)); err != nil {
+		return nil, err
+	}
+
+	// invalid text returned as raw bytes (see InvalidTextRaw) is left as-is so it doesn't turn back into a string
+	if _, raw := val.([]byte); c < len(rec.affinities) && !(raw && storageClass(rec.values[rec.position(c)].Type) == StorageText) {
+		val = cast(val, rec.affinities[c])
+	}
+
+	return val, nil
+}
+
+// AppendValues decodes every value in the record (as returned by ValueAt) and appends them to dst, returning the
+// extended slice. Reusing the same slice across records (via dst[:0]) avoids allocating a new slice for every row.
+func (rec *Record) AppendValues(dst []any) (_ []any, err error) {
+	for i := 0; i < rec.NumValues(); i++ {
+		var val any
+		if val, err = rec.ValueAt(i); err != nil {
+			return dst, err
+		}
+		dst = append(dst, val)
+	}
+	return dst, nil
+}
+
+// position returns the position in the stored record of the value for column c
+func (rec *Record) position(c int) int {
+	if c >= 0 && c < len(rec.order) {
+		return rec.order[c]
+	}
+	return c
+}
+
+// value decodes the value at position c as it is stored in the record
+func (rec *Record) value(c int) (any, error) {
+	if c < 0 || c >= rec.NumValues() {
 		return nil, fmt.Errorf("column index %d out of range", c)
+	} else if c >= len(rec.values) { // trailing value omitted from the record
+		return nil, nil
 	}
 
 	var cell, val = rec.cell, rec.values[c]
@@ -88,13 +263,11 @@ func (rec *Record) ValueAt(c int) (any, error) {
 	case 0x03: // 24-bit twos-complement integer
 		var bs = make([]byte, 4)
 		if n, _ := cell.Read(bs[1:]); n != 3 {
-			return nil, fmt.Errorf("failed to decode 24-bit integer value")
+			return nil, errorf(ErrCorruptRecord, "failed to decode 24-bit integer value")
 		}
 
-		if bs[1]&0x80 > 0 {
-			bs[0] = 0xff
-		}
-		return int64(binary.BigEndian.Uint32(bs)), nil
+		// shift the value into the top bits and back, so the sign bit is extended
+		return int64(int32(binary.BigEndian.Uint32(bs)<<8) >> 8), nil
 
 	case 0x04: // 32-bit twos-complement integer
 		var data int32
@@ -106,13 +279,10 @@ func (rec *Record) ValueAt(c int) (any, error) {
 	case 0x05: // 48-bit twos-complement integer
 		var bs = make([]byte, 8)
 		if n, _ := cell.Read(bs[2:]); n != 6 {
-			return nil, fmt.Errorf("failed to decode 48-bit integer value")
+			return nil, errorf(ErrCorruptRecord, "failed to decode 48-bit integer value")
 		}
 
-		if bs[2]&0x80 > 0 {
-			bs[0] = 0xff
-		}
-		return int64(binary.BigEndian.Uint64(bs)), nil
+		return int64(binary.BigEndian.Uint64(bs)<<16) >> 16, nil
 
 	case 0x06: // 64-bit twos-complement integer
 		var data int64
@@ -135,6 +305,11 @@ func (rec *Record) ValueAt(c int) (any, error) {
 		return int64(1), nil
 
 	default:
+		// the length comes from the record's header: make sure the payload holds it before allocating a buffer for it
+		if n := typeSize(int64(val.Type)); n > cell.size()-val.Offset {
+			return nil, errorf(ErrCorruptRecord, "column %d: value of %d bytes extends past the end of the payload", c, n)
+		}
+
 		// if the type is BLOB
 		if t := val.Type; t >= 12 && t%2 == 0 {
 			var buf = make([]byte, (t-12)/2)
@@ -152,20 +327,122 @@ func (rec *Record) ValueAt(c int) (any, error) {
 				return nil, err
 			}
 
-			if rec.encoding == UTF8 {
-				var s = string(buf)
-				if idx := strings.Index(s, "\x00"); idx >= 0 {
-					s = s[:idx]
+			var s string
+			var valid bool
+			switch rec.encoding {
+			case UTF16LE:
+				s, buf, valid = decodeUTF16(buf, binary.LittleEndian)
+			case UTF16BE:
+				s, buf, valid = decodeUTF16(buf, binary.BigEndian)
+			default:
+				if idx := bytes.IndexByte(buf, 0); idx >= 0 {
+					buf = buf[:idx]
 				}
+				s = string(buf)
+				valid = utf8.ValidString(s)
+			}
+
+			if !valid {
+				switch rec.invalidText {
+				case InvalidTextError:
+					return nil, fmt.Errorf("column %d: invalid %s text", c, rec.encoding)
+				case InvalidTextReplace:
+					return strings.ToValidUTF8(s, "\uFFFD"), nil
+				case InvalidTextRaw:
+					return buf, nil
+				}
+			}
+
+			return s, nil
+		}
+	}
 
-				return s, nil
+	return nil, errorf(ErrCorruptRecord, "unknown value type %d", rec.values[c].Type)
+}
+
+// decodeUTF16 decodes UTF-16 text stored in b using the given byte order. As with UTF-8 text, the text ends at the
+// first NUL character; a trailing odd byte is ignored. It returns the text along with the bytes it was decoded from,
+// reporting whether the text is valid, ie. has no unpaired surrogate (which is decoded as U+FFFD).
+func decodeUTF16(b []byte, order binary.ByteOrder) (_ string, _ []byte, valid bool) {
+	var units = make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		var u = order.Uint16(b[i:])
+		if u == 0 {
+			break
+		}
+		units = append(units, u)
+	}
+
+	valid = true
+	for i := 0; i < len(units); i++ {
+		if utf16.IsSurrogate(rune(units[i])) {
+			if i+1 < len(units) && utf16.DecodeRune(rune(units[i]), rune(units[i+1])) != utf8.RuneError {
+				i++
 			} else {
-				return nil, fmt.Errorf("UTF-16 is not supported")
+				valid = false
+				break
 			}
 		}
 	}
 
-	return nil, fmt.Errorf("unknown value type %d", rec.values[c].Type)
+	return string(utf16.Decode(units)), b[:2*len(units)], valid
+}
+
+// StorageClass returns the storage class of the value at position c.
+// It is determined from the record header alone, without decoding the value.
+func (rec *Record) StorageClass(c int) (StorageClass, error) {
+	if c < 0 || c >= rec.NumValues() {
+		return StorageNull, fmt.Errorf("column index %d out of range", c)
+	} else if c = rec.position(c); c >= len(rec.values) { // trailing value omitted from the record
+		return StorageNull, nil
+	}
+	return storageClass(rec.values[c].Type), nil
+}
+
+// TypedValue is a value of a record along with its exact type, as returned by Record.Tuple
+type TypedValue struct {
+	Class      StorageClass // storage class of the value
+	SerialType int          // serial type of the value in the record header; zero for trailing values omitted from the record
+	Value      any          // the value, as stored in the record
+}
+
+// Tuple decodes every value in the record along with its storage class and serial type, in a single pass.
+//
+// Unlike ValueAt, values are returned as they are stored, without converting them to their column's affinity
+// (see WithTypedValues), so the Go type of each value always matches its storage class.
+func (rec *Record) Tuple() (_ []TypedValue, err error) {
+	var tuple = make([]TypedValue, rec.NumValues())
+	for i := range tuple {
+		var pos = rec.position(i)
+		if pos < len(rec.values) {
+			tuple[i].SerialType = rec.values[pos].Type
+		}
+		tuple[i].Class = storageClass(tuple[i].SerialType)
+
+		if tuple[i].Value, err = rec.value(pos); err != nil {
+			return nil, err
+		}
+	}
+	return tuple, nil
+}
+
+// IsNull reports whether the value at position c is NULL.
+// Like StorageClass, it only looks at the record header.
+func (rec *Record) IsNull(c int) (bool, error) {
+	var sc, err = rec.StorageClass(c)
+	return sc == StorageNull, err
+}
+
+// ValueLength returns the number of bytes the value at position c occupies in the record, or -1 if the value is NULL.
+// It is computed from the record header alone, without reading the value; for TEXT and BLOB values,
+// it is the length of the content in bytes.
+func (rec *Record) ValueLength(c int) (int64, error) {
+	if c < 0 || c >= rec.NumValues() {
+		return 0, fmt.Errorf("column index %d out of range", c)
+	} else if c = rec.position(c); c >= len(rec.values) || rec.values[c].Type == 0 {
+		return -1, nil
+	}
+	return typeSize(int64(rec.values[c].Type)), nil
 }
 
 func (rec *Record) AsInt(c int) (_ int, err error) {
@@ -206,6 +483,140 @@ func (rec *Record) AsString(c int) (_ string, err error) {
 	return s, nil
 }
 
+// column returns the position of the named column, matched case-insensitively as sqlite does
+func (rec *Record) column(name string) (int, error) {
+	if rec.names == nil {
+		return 0, fmt.Errorf("cannot access column %q by name: the record's columns are unknown", name)
+	}
+
+	for i, n := range rec.names {
+		if strings.EqualFold(n, name) {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no such column %q: expected one of %s", name, strings.Join(rec.names, ", "))
+}
+
+// ByName returns the value of the named column like ValueAt, for records read through an Object (eg. with
+// Object.ForEach) whose columns are known: those of a table's schema, or those described by RecordLayout for
+// an index. Names are matched case-insensitively. As with Object.ForEachMap, the column aliasing the rowid
+// of a table holds the row's rowid.
+func (rec *Record) ByName(col string) (_ any, err error) {
+	var c int
+	if c, err = rec.column(col); err != nil {
+		return nil, err
+	}
+
+	var val any
+	if val, err = rec.ValueAt(c); err != nil {
+		return nil, err
+	}
+	return rec.withRowid(c, val), nil
+}
+
+// withRowid returns val, the value at position c, or the rowid if c is the column aliasing the rowid,
+// which is stored as NULL in the record
+func (rec *Record) withRowid(c int, val any) any {
+	if val == nil && c == rec.alias {
+		if rowid, ok := rec.Rowid(); ok {
+			return rowid
+		}
+	}
+	return val
+}
+
+// AsStringByName returns the value of the named column like AsString; see ByName
+func (rec *Record) AsStringByName(col string) (_ string, err error) {
+	var c int
+	if c, err = rec.column(col); err != nil {
+		return "", err
+	}
+	return rec.AsString(c)
+}
+
+// AsInt64ByName returns the value of the named column like AsInt64; see ByName
+func (rec *Record) AsInt64ByName(col string) (_ int64, err error) {
+	var v any
+	if v, err = rec.ByName(col); err != nil {
+		return 0, err
+	} else if n, ok := v.(float64); ok {
+		return int64(n), nil
+	}
+	n, _ := v.(int64)
+	return n, nil
+}
+
+// AsFloat64ByName returns the value of the named column like AsFloat64; see ByName
+func (rec *Record) AsFloat64ByName(col string) (_ float64, err error) {
+	var c int
+	if c, err = rec.column(col); err != nil {
+		return 0, err
+	}
+	return rec.AsFloat64(c)
+}
+
+// AsStringOr returns the value at position c like AsString, along with whether the value is present:
+// ok is false if the value is NULL, telling it apart from an empty string. As with ByName, the column
+// aliasing the rowid of a table is present and holds the row's rowid.
+func (rec *Record) AsStringOr(c int) (_ string, ok bool, err error) {
+	var s string
+	if rowid, aliased := rec.aliasedRowid(c); aliased {
+		return strconv.FormatInt(rowid, 10), true, nil
+	} else if ok, err = rec.present(c); err != nil || !ok {
+		return "", false, err
+	} else if s, err = rec.AsString(c); err != nil {
+		return "", false, err
+	}
+	return s, true, nil
+}
+
+// AsInt64Or returns the value at position c like AsInt64, along with whether the value is present:
+// ok is false if the value is NULL, telling it apart from a zero. As with ByName, the column
+// aliasing the rowid of a table is present and holds the row's rowid.
+func (rec *Record) AsInt64Or(c int) (_ int64, ok bool, err error) {
+	var n int64
+	if rowid, aliased := rec.aliasedRowid(c); aliased {
+		return rowid, true, nil
+	} else if ok, err = rec.present(c); err != nil || !ok {
+		return 0, false, err
+	} else if n, err = rec.AsInt64(c); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+// AsFloat64Or returns the value at position c like AsFloat64, along with whether the value is present:
+// ok is false if the value is NULL, telling it apart from a zero. As with ByName, the column
+// aliasing the rowid of a table is present and holds the row's rowid.
+func (rec *Record) AsFloat64Or(c int) (_ float64, ok bool, err error) {
+	var n float64
+	if rowid, aliased := rec.aliasedRowid(c); aliased {
+		return float64(rowid), true, nil
+	} else if ok, err = rec.present(c); err != nil || !ok {
+		return 0, false, err
+	} else if n, err = rec.AsFloat64(c); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+// present reports whether the value at position c isn't NULL
+func (rec *Record) present(c int) (bool, error) {
+	var null, err = rec.IsNull(c)
+	return !null, err
+}
+
+// aliasedRowid returns the rowid if c is the column aliasing the rowid and, as sqlite stores it, the value is NULL
+func (rec *Record) aliasedRowid(c int) (int64, bool) {
+	if c != rec.alias {
+		return 0, false
+	} else if null, err := rec.IsNull(c); err != nil || !null {
+		return 0, false
+	}
+	return rec.Rowid()
+}
+
 func (rec *Record) AsBlob(c int) (_ []byte, err error) {
 	var v any
 	if v, err = rec.ValueAt(c); err != nil {
@@ -215,3 +626,244 @@ func (rec *Record) AsBlob(c int) (_ []byte, err error) {
 	b, _ := v.([]byte)
 	return b, nil
 }
+
+// BlobReader returns a reader streaming the BLOB value at position c. Unlike AsBlob, the parts of the value stored
+// on overflow pages are read as the reader is consumed rather than held in memory all at once, making it suitable
+// to copy large values (eg. to a file). Any value that isn't a BLOB (including NULL) is reported as an error.
+//
+// The reader remains valid after the iteration moves on to the next record.
+func (rec *Record) BlobReader(c int) (_ io.Reader, err error) {
+	var sc StorageClass
+	if sc, err = rec.StorageClass(c); err != nil {
+		return nil, err
+	} else if sc != StorageBlob {
+		return nil, fmt.Errorf("column %d: expected a BLOB; got %s", c, sc)
+	}
+
+	var val = rec.values[rec.position(c)]
+	return rec.cell.section(val.Offset, typeSize(int64(val.Type)))
+}
+
+// AsJSON returns the TEXT value at position c as raw JSON, such as the documents stored by sqlite's JSON functions,
+// allowing it to be embedded as-is in a value marshalled with encoding/json. A NULL value is returned as a nil
+// json.RawMessage (which marshals to null), and any value that isn't TEXT is reported as an error.
+//
+// The text is checked to be well-formed JSON, unless the file was opened WithUncheckedJSON.
+func (rec *Record) AsJSON(c int) (_ json.RawMessage, err error) {
+	var sc StorageClass
+	if sc, err = rec.StorageClass(c); err != nil || sc == StorageNull {
+		return nil, err
+	} else if sc != StorageText {
+		return nil, fmt.Errorf("column %d: expected TEXT holding JSON; got %s", c, sc)
+	}
+
+	var val any
+	if val, err = rec.value(rec.position(c)); err != nil {
+		return nil, err
+	}
+
+	var msg json.RawMessage
+	switch v := val.(type) {
+	case string:
+		msg = json.RawMessage(v)
+	case []byte:
+		msg = v
+	}
+
+	if !rec.uncheckedJSON && !json.Valid(msg) {
+		return nil, fmt.Errorf("column %d: value is not valid JSON", c)
+	}
+
+	return msg, nil
+}
+
+// Strings returns every value in the record formatted as a human-readable string, suitable for display.
+//
+// Integers and floats are formatted using strconv, TEXT is returned as-is, BLOB is rendered as a hex literal (x'...')
+// and NULL as the literal NULL.
+func (rec *Record) Strings() (_ []string, err error) {
+	var values = make([]string, rec.NumValues())
+	for i := range values {
+		var val any
+		if val, err = rec.ValueAt(i); err != nil {
+			return nil, err
+		}
+		values[i] = formatValue(val)
+	}
+	return values, nil
+}
+
+// formatValue formats a decoded value for display
+func formatValue(val any) string {
+	switch v := val.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return formatReal(v)
+	case string:
+		return v
+	case []byte:
+		return "x'" + hex.EncodeToString(v) + "'"
+	}
+	return fmt.Sprint(val)
+}
+
+// Scan copies the values of the record into the values pointed at by dest, in order, like database/sql's Rows.Scan.
+// There must be exactly one destination for every value in the record.
+//
+// Supported destinations are *int64, *float64, *string, *[]byte and *any, along with any sql.Scanner
+// (eg. *sql.NullString or *sql.NullInt64), which is passed the value as returned by ValueAt. Values are converted
+// to the destination's type as database/sql does: numbers are formatted as text, and text is parsed as a number.
+// NULL can only be scanned into a *[]byte (as nil), an *any or a sql.Scanner. As with ByName, the column aliasing
+// the rowid of a table holds the row's rowid.
+func (rec *Record) Scan(dest ...any) (err error) {
+	if len(dest) != rec.NumValues() {
+		return fmt.Errorf("expected %d destination arguments in Scan; got %d", rec.NumValues(), len(dest))
+	}
+
+	for i, d := range dest {
+		var val any
+		if val, err = rec.ValueAt(i); err != nil {
+			return err
+		}
+
+		if err = scanValue(d, rec.withRowid(i, val)); err != nil {
+			return fmt.Errorf("column %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// scanValue stores val, a value returned by ValueAt, into dest; see Record.Scan
+func scanValue(dest, val any) error {
+	if s, ok := dest.(sql.Scanner); ok {
+		return s.Scan(val)
+	}
+
+	// text representation of the value, used to convert it to a string or to parse it as a number
+	var text = func() string {
+		switch v := val.(type) {
+		case string:
+			return v
+		case []byte:
+			return string(v)
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		return fmt.Sprint(val)
+	}
+
+	switch d := dest.(type) {
+	case *any:
+		*d = val
+		return nil
+	case *[]byte:
+		if b, ok := val.([]byte); ok || val == nil {
+			*d = b
+		} else {
+			*d = []byte(text())
+		}
+		return nil
+	}
+
+	if val == nil {
+		return fmt.Errorf("cannot scan NULL into %T", dest)
+	}
+
+	switch d := dest.(type) {
+	case *string:
+		*d = text()
+		return nil
+
+	case *int64:
+		switch v := val.(type) {
+		case int64:
+			*d = v
+			return nil
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				*d = int64(v)
+				return nil
+			}
+		default:
+			if n, err := strconv.ParseInt(strings.TrimSpace(text()), 10, 64); err == nil {
+				*d = n
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot convert %q to int64", text())
+
+	case *float64:
+		switch v := val.(type) {
+		case int64:
+			*d = float64(v)
+			return nil
+		case float64:
+			*d = v
+			return nil
+		default:
+			if f, err := strconv.ParseFloat(strings.TrimSpace(text()), 64); err == nil {
+				*d = f
+				return nil
+			}
+		}
+		return fmt.Errorf("cannot convert %q to float64", text())
+	}
+
+	return fmt.Errorf("unsupported Scan destination type %T", dest)
+}
+
+// Checksum feeds a canonical representation of every value in the record into h,
+// allowing callers to compute a digest of the row (eg. to detect rows that changed between two versions of a file).
+//
+// The representation is independent of how a value is stored: a float with an integral value hashes
+// the same as the equivalent integer (so 2 and 2.0 produce the same digest), text is hashed as UTF-8 regardless of
+// the database encoding, and a trailing NULL omitted from the stored record hashes the same as an explicit NULL.
+func (rec *Record) Checksum(h hash.Hash) (err error) {
+	var buf [9]byte
+	for i := 0; i < rec.NumValues(); i++ {
+		var val any
+		if val, err = rec.ValueAt(i); err != nil {
+			return err
+		}
+		writeValue(h, &buf, val)
+	}
+
+	return nil
+}
+
+// writeValue writes the canonical representation of val (as used by Checksum) into h, using buf as scratch space
+func writeValue(h hash.Hash, buf *[9]byte, val any) {
+	// integral floats within the range of int64 are canonicalized to integers
+	if f, ok := val.(float64); ok && f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+		val = int64(f)
+	}
+
+	// each value is written as a one-byte tag followed by its content
+	switch v := val.(type) {
+	case nil:
+		buf[0] = 0
+		_, _ = h.Write(buf[:1])
+	case int64:
+		buf[0] = 1
+		binary.BigEndian.PutUint64(buf[1:], uint64(v))
+		_, _ = h.Write(buf[:])
+	case float64:
+		buf[0] = 2
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+		_, _ = h.Write(buf[:])
+	case string:
+		buf[0] = 3
+		binary.BigEndian.PutUint64(buf[1:], uint64(len(v)))
+		_, _ = h.Write(buf[:])
+		_, _ = io.WriteString(h, v)
+	case []byte:
+		buf[0] = 4
+		binary.BigEndian.PutUint64(buf[1:], uint64(len(v)))
+		_, _ = h.Write(buf[:])
+		_, _ = h.Write(v)
+	}
+}
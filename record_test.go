@@ -0,0 +1,713 @@
+package dotlite
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// record builds a record from the given header (serial types) and body
+func record(t *testing.T, types []byte, body []byte) *Record {
+	var payload = append(append([]byte{byte(len(types) + 1)}, types...), body...)
+	var rec, err = DecodeRecord(UTF8, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestRecord_Checksum(t *testing.T) {
+	var sum = func(rec *Record) []byte {
+		var h = sha256.New()
+		if err := rec.Checksum(h); err != nil {
+			t.Fatal(err)
+		}
+		return h.Sum(nil)
+	}
+
+	var integer = record(t, []byte{0x01, 0x0f}, []byte{0x02, 'a'})                                         // (2, 'a')
+	var float = record(t, []byte{0x07, 0x0f}, []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'a'}) // (2.0, 'a')
+	var blob = record(t, []byte{0x01, 0x0e}, []byte{0x02, 'a'})                                            // (2, x'61')
+
+	if !bytes.Equal(sum(integer), sum(float)) {
+		t.Errorf("expected 2 and 2.0 to produce the same checksum")
+	}
+
+	if bytes.Equal(sum(integer), sum(blob)) {
+		t.Errorf("expected text and blob to produce different checksums")
+	}
+
+	var padded = record(t, []byte{0x01, 0x0f}, []byte{0x02, 'a'})
+	padded.columns = 3
+	var null = record(t, []byte{0x01, 0x0f, 0x00}, []byte{0x02, 'a'})
+
+	if !bytes.Equal(sum(padded), sum(null)) {
+		t.Errorf("expected omitted and explicit NULL to produce the same checksum")
+	}
+}
+
+func TestDecodeRecordFromBlob(t *testing.T) {
+	var nested = []byte{0x03, 0x01, 0x11, 0x2a, 'a', 'b'} // (42, 'ab')
+	var outer = record(t, []byte{0x0f, byte(12 + 2*len(nested))}, append([]byte{'x'}, nested...))
+
+	var blob, err = outer.AsBlob(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rec *Record
+	if rec, err = DecodeRecordFromBlob(UTF8, blob); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := rec.AsInt64(0); n != 42 {
+		t.Errorf("expected %d; got %d", 42, n)
+	}
+
+	if s, _ := rec.AsString(1); s != "ab" {
+		t.Errorf("expected %q; got %q", "ab", s)
+	}
+}
+
+func TestRecord_integers(t *testing.T) {
+	// the extremes of every integer serial type, encoded as sqlite does: big-endian two's complement
+	var cases = []struct {
+		typ      byte
+		body     []byte
+		expected int64
+	}{
+		{0x01, []byte{0x80}, math.MinInt8},
+		{0x01, []byte{0x7f}, math.MaxInt8},
+		{0x02, []byte{0x80, 0x00}, math.MinInt16},
+		{0x02, []byte{0x7f, 0xff}, math.MaxInt16},
+		{0x03, []byte{0x80, 0x00, 0x00}, -1 << 23},
+		{0x03, []byte{0x7f, 0xff, 0xff}, 1<<23 - 1},
+		{0x03, []byte{0xff, 0xff, 0xff}, -1},
+		{0x04, []byte{0x80, 0x00, 0x00, 0x00}, math.MinInt32},
+		{0x04, []byte{0x7f, 0xff, 0xff, 0xff}, math.MaxInt32},
+		{0x05, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00}, -1 << 47},
+		{0x05, []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff}, 1<<47 - 1},
+		{0x05, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xfe}, -2},
+		{0x06, []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}, math.MinInt64},
+		{0x06, []byte{0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, math.MaxInt64},
+		{0x06, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, -1},
+	}
+
+	for _, c := range cases {
+		var rec = record(t, []byte{c.typ}, c.body)
+		if val, err := rec.ValueAt(0); err != nil {
+			t.Error(err)
+		} else if val != c.expected {
+			t.Errorf("serial type %d (% x): expected %d; got %v", c.typ, c.body, c.expected, val)
+		}
+	}
+}
+
+func TestRecord_negative_rowids(t *testing.T) {
+	// each row has its rowid as value, both written by sqlite; negative rowids are stored as 9-byte varints
+	var file = open(t, "testdata/negative.db")
+	defer file.Close()
+
+	table, err := file.Object("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []struct {
+		rowid int64
+		typ   int // serial type sqlite chose for the value
+	}{
+		{math.MinInt64, 6},
+		{-1 << 47, 5},
+		{-1 << 23, 3},
+		{-129, 2},
+		{-1, 1},
+		{math.MaxInt64, 6},
+	}
+
+	var i int
+	err = table.tree.Walk(func(cell *Cell) error {
+		if i >= len(expected) {
+			return fmt.Errorf("unexpected cell with rowid %d", cell.Rowid)
+		} else if cell.Rowid != expected[i].rowid {
+			t.Errorf("cell %d: expected rowid %d; got %d", i, expected[i].rowid, cell.Rowid)
+		}
+
+		var rec, err = NewRecord(UTF8, cell)
+		if err != nil {
+			return err
+		}
+
+		if typ := rec.values[0].Type; typ != expected[i].typ {
+			t.Errorf("cell %d: expected serial type %d; got %d", i, expected[i].typ, typ)
+		} else if val, err := rec.ValueAt(0); err != nil || val != expected[i].rowid {
+			t.Errorf("cell %d: expected value %d; got %v (err=%v)", i, expected[i].rowid, val, err)
+		}
+
+		i++
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if i != len(expected) {
+		t.Errorf("expected %d rows; got %d", len(expected), i)
+	}
+}
+
+func TestRecord_Tuple(t *testing.T) {
+	// (0, x'', '', NULL, 1.5, -1) with the integer zero stored using serial type 8
+	var body = append([]byte{0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, 0xff)
+	var rec = record(t, []byte{0x08, 0x0c, 0x0d, 0x00, 0x07, 0x01}, body)
+
+	var tuple, err = rec.Tuple()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []TypedValue{
+		{StorageInteger, 8, int64(0)},
+		{StorageBlob, 12, []byte{}},
+		{StorageText, 13, ""},
+		{StorageNull, 0, nil},
+		{StorageReal, 7, 1.5},
+		{StorageInteger, 1, int64(-1)},
+	}
+
+	if !reflect.DeepEqual(tuple, expected) {
+		t.Errorf("expected %v; got %v", expected, tuple)
+	}
+}
+
+func TestRecord_Strings(t *testing.T) {
+	var rec = record(t, []byte{0x01, 0x07, 0x0f, 0x10, 0x00}, []byte{0x2a, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'a', 0xca, 0xfe})
+	rec.columns = 6
+
+	var values, err = rec.Strings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = []string{"42", "2.0", "a", "x'cafe'", "NULL", "NULL"}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values; got %d", len(expected), len(values))
+	}
+
+	for i := range expected {
+		if values[i] != expected[i] {
+			t.Errorf("value %d: expected %q; got %q", i, expected[i], values[i])
+		}
+	}
+}
+
+func TestRecord_ValueLength(t *testing.T) {
+	var rec = record(t, []byte{0x01, 0x07, 0x0f, 0x10, 0x00, 0x09}, []byte{0x2a, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 'a', 0xca, 0xfe})
+	rec.columns = 7
+
+	var expected = []int64{1, 8, 1, 2, -1, 0, -1}
+	for i, length := range expected {
+		if n, err := rec.ValueLength(i); err != nil {
+			t.Error(err)
+		} else if n != length {
+			t.Errorf("value %d: expected length %d; got %d", i, length, n)
+		}
+	}
+
+	if _, err := rec.ValueLength(len(expected)); err == nil {
+		t.Errorf("expected out of range error")
+	}
+}
+
+func TestRecord_AsJSON(t *testing.T) {
+	// '{"a":[1]}', '{', 42 and NULL
+	var rec = record(t, []byte{0x1f, 0x0f, 0x01, 0x00}, []byte(`{"a":[1]}{*`))
+
+	if msg, err := rec.AsJSON(0); err != nil {
+		t.Error(err)
+	} else if string(msg) != `{"a":[1]}` {
+		t.Errorf("expected %s; got %s", `{"a":[1]}`, msg)
+	}
+
+	if _, err := rec.AsJSON(1); err == nil {
+		t.Errorf("expected error for malformed JSON")
+	}
+
+	if _, err := rec.AsJSON(2); err == nil {
+		t.Errorf("expected error for a value that isn't TEXT")
+	}
+
+	if msg, err := rec.AsJSON(3); err != nil || msg != nil {
+		t.Errorf("expected NULL to be returned as nil; got %s (err=%v)", msg, err)
+	}
+
+	rec.uncheckedJSON = true
+	if msg, err := rec.AsJSON(1); err != nil || string(msg) != "{" {
+		t.Errorf("expected unchecked JSON to be returned as-is; got %s (err=%v)", msg, err)
+	}
+}
+
+func TestRecord_invalidText(t *testing.T) {
+	var rec = record(t, []byte{0x13}, []byte{'a', 0xff, 'b'}) // 'a\xffb'
+
+	var expectations = []struct {
+		policy   InvalidTextPolicy
+		expected any
+	}{
+		{InvalidTextKeep, "a\xffb"},
+		{InvalidTextReplace, "a�b"},
+		{InvalidTextRaw, []byte{'a', 0xff, 'b'}},
+	}
+
+	for _, e := range expectations {
+		rec.invalidText = e.policy
+
+		var val, err = rec.ValueAt(0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(val, e.expected) {
+			t.Errorf("policy %d: expected %#v; got %#v", e.policy, e.expected, val)
+		}
+	}
+
+	rec.invalidText = InvalidTextError
+	if _, err := rec.ValueAt(0); err == nil {
+		t.Errorf("expected an error for invalid text")
+	}
+}
+
+func TestRecord_AppendValues(t *testing.T) {
+	var rec = record(t, []byte{0x01, 0x0f}, []byte{0x2a, 'a'}) // (42, 'a')
+	rec.columns = 3
+
+	var values, err = rec.AppendValues([]any{"x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []any{"x", int64(42), "a", nil}; !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %#v; got %#v", expected, values)
+	}
+}
+
+func BenchmarkRecord_values(b *testing.B) {
+	var file, err = Open("testdata/chinook.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	var table *Object
+	if table, err = file.Object("Track"); err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("ValueAt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = table.ForEach(func(rec *Record) error {
+				var values = make([]any, 0, rec.NumValues())
+				for c := 0; c < rec.NumValues(); c++ {
+					var val, err = rec.ValueAt(c)
+					if err != nil {
+						return err
+					}
+					values = append(values, val)
+				}
+				return nil
+			})
+		}
+	})
+
+	b.Run("AppendValues", func(b *testing.B) {
+		b.ReportAllocs()
+		var values []any
+		for i := 0; i < b.N; i++ {
+			_ = table.ForEach(func(rec *Record) (err error) {
+				values, err = rec.AppendValues(values[:0])
+				return err
+			})
+		}
+	})
+}
+
+func TestRecord_utf16(t *testing.T) {
+	for _, name := range []string{"testdata/utf16le.db", "testdata/utf16be.db"} {
+		var file = open(t, name)
+
+		var names []any
+		var err = file.ForEach("t", func(rec *Record) error {
+			var val, err = rec.ValueAt(1)
+			names = append(names, val)
+			return err
+		})
+
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// text ends at the first NUL character, as it does for UTF-8
+		var expected = []any{"hello", "héllo wörld", "emoji 😀", "nul", "", nil}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %q; got %q", name, expected, names)
+		}
+
+		var entries int
+		if err = file.ForEach("t_name", func(rec *Record) error { entries++; _, err := rec.ValueAt(0); return err }); err != nil || entries != 6 {
+			t.Errorf("%s: expected %d index entries; got %d (err=%v)", name, 6, entries, err)
+		}
+
+		_ = file.Close()
+	}
+
+	// 'a', an unpaired high surrogate and 'b', followed by an odd trailing byte, as UTF-16le
+	var body = []byte{'a', 0, 0x3d, 0xd8, 'b', 0, 'x'}
+	rec, err := DecodeRecord(UTF16LE, append([]byte{2, byte(len(body)*2 + 13)}, body...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for policy, expected := range map[InvalidTextPolicy]any{
+		InvalidTextKeep:    "a�b",
+		InvalidTextReplace: "a�b",
+		InvalidTextRaw:     body[:6],
+	} {
+		rec.invalidText = policy
+		if val, err := rec.ValueAt(0); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(val, expected) {
+			t.Errorf("policy %d: expected %#v; got %#v", policy, expected, val)
+		}
+	}
+
+	rec.invalidText = InvalidTextError
+	if _, err = rec.ValueAt(0); err == nil {
+		t.Errorf("expected an error for invalid text")
+	}
+}
+
+func TestRecord_BlobReader(t *testing.T) {
+	var file = open(t, "testdata/large-blob.db")
+	defer file.Close()
+
+	var rows int
+	var err = file.ForEach("b", func(rec *Record) (err error) {
+		rows++
+
+		var rowid, _ = rec.Rowid()
+		var r io.Reader
+		if r, err = rec.BlobReader(2); rowid == 4 {
+			if err == nil {
+				t.Errorf("expected an error reading a NULL value")
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var streamed []byte
+		if streamed, err = io.ReadAll(r); err != nil {
+			return err
+		}
+
+		// the first row's blob starts on the leaf page; the second one starts on an overflow page
+		if rec.cell.chain == nil && rowid != 3 {
+			t.Errorf("row %d: expected the overflow chain not to be read", rowid)
+		}
+
+		var blob []byte
+		if blob, err = rec.AsBlob(2); err != nil {
+			return err
+		}
+
+		if !bytes.Equal(streamed, blob) {
+			t.Errorf("row %d: streamed %d bytes that don't match the %d bytes of the value", rowid, len(streamed), len(blob))
+		}
+
+		if tail, err := rec.AsString(3); err != nil || tail != "end" {
+			t.Errorf("row %d: expected the value following the blob to be %q; got %q (err=%v)", rowid, "end", tail, err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if rows != 4 {
+		t.Errorf("expected %d rows; got %d", 4, rows)
+	}
+}
+
+func TestRecord_AsStringOr(t *testing.T) {
+	var rec = record(t, []byte{0x0d, 0x00, 0x08, 0x15}, []byte("text")) // ('', NULL, 0, 'text')
+
+	for c, expected := range []struct {
+		s  string
+		ok bool
+	}{{"", true}, {"", false}, {"", true}, {"text", true}} {
+		if s, ok, err := rec.AsStringOr(c); err != nil {
+			t.Fatal(err)
+		} else if s != expected.s || ok != expected.ok {
+			t.Errorf("column %d: expected (%q, %v); got (%q, %v)", c, expected.s, expected.ok, s, ok)
+		}
+	}
+
+	if n, ok, err := rec.AsInt64Or(1); err != nil || ok || n != 0 {
+		t.Errorf("expected NULL to be absent; got (%d, %v, %v)", n, ok, err)
+	}
+
+	if n, ok, err := rec.AsInt64Or(2); err != nil || !ok || n != 0 {
+		t.Errorf("expected zero to be present; got (%d, %v, %v)", n, ok, err)
+	}
+
+	if f, ok, err := rec.AsFloat64Or(1); err != nil || ok || f != 0 {
+		t.Errorf("expected NULL to be absent; got (%g, %v, %v)", f, ok, err)
+	}
+
+	if _, _, err := rec.AsStringOr(10); err == nil {
+		t.Errorf("expected an error for an out of range column")
+	}
+}
+
+func TestRecord_AsInt64Or_rowid_alias(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// GenreId is the table's INTEGER PRIMARY KEY, stored as NULL in the record
+	err := file.ForEach("Genre", func(rec *Record) error {
+		if n, ok, err := rec.AsInt64Or(0); err != nil || !ok || n != 1 {
+			t.Errorf("expected (1, true); got (%d, %v, %v)", n, ok, err)
+		}
+
+		if s, ok, err := rec.AsStringOr(0); err != nil || !ok || s != "1" {
+			t.Errorf("expected (%q, true); got (%q, %v, %v)", "1", s, ok, err)
+		}
+
+		if f, ok, err := rec.AsFloat64Or(0); err != nil || !ok || f != 1 {
+			t.Errorf("expected (1, true); got (%g, %v, %v)", f, ok, err)
+		}
+		return ErrStopIteration
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecord_Scan(t *testing.T) {
+	// (42, 2.5, 'text', x'ab', NULL, '17')
+	var body = append(append([]byte{42, 0x40, 0x04, 0, 0, 0, 0, 0, 0}, "text"...), 0xab, '1', '7')
+	var rec = record(t, []byte{0x01, 0x07, 0x15, 0x0e, 0x00, 0x11}, body)
+
+	var i int64
+	var f float64
+	var s string
+	var b []byte
+	var null any
+	var n int64
+	if err := rec.Scan(&i, &f, &s, &b, &null, &n); err != nil {
+		t.Fatal(err)
+	}
+
+	if i != 42 || f != 2.5 || s != "text" || !bytes.Equal(b, []byte{0xab}) || null != nil || n != 17 {
+		t.Errorf("unexpected values: %v, %v, %q, %v, %v, %v", i, f, s, b, null, n)
+	}
+
+	// conversions between types, and nullable destinations
+	var is, fs sql.NullString
+	var ni sql.NullInt64
+	var nf sql.NullFloat64
+	var nb []byte = []byte{1}
+	if err := rec.Scan(&is, &fs, &s, &s, &nb, &f); err != nil {
+		t.Fatal(err)
+	}
+
+	if is.String != "42" || fs.String != "2.5" || nb != nil || f != 17 {
+		t.Errorf("unexpected values: %q, %q, %v, %v", is.String, fs.String, nb, f)
+	}
+
+	if err := rec.Scan(&ni, &nf, &s, &b, &ni, &s); err != nil {
+		t.Fatal(err)
+	} else if ni.Valid {
+		t.Errorf("expected NULL to scan as an invalid sql.NullInt64")
+	}
+
+	for _, dest := range [][]any{
+		{&i, &f, &s},                       // arity mismatch
+		{&i, &i, &s, &b, &null, &n},        // 2.5 is not an integer
+		{&i, &f, &i, &b, &null, &n},        // 'text' is not an integer
+		{&i, &f, &s, &b, &s, &n},           // NULL into a string
+		{&i, &f, &s, &b, &null, new(bool)}, // unsupported destination
+		{i, &f, &s, &b, &null, &n},         // not a pointer
+	} {
+		if err := rec.Scan(dest...); err == nil {
+			t.Errorf("expected an error scanning into %T", dest)
+		}
+	}
+}
+
+func TestRecord_Scan_rowid_alias(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// GenreId is the table's INTEGER PRIMARY KEY, stored as NULL in the record
+	var id int64
+	var name string
+	err := file.ForEach("Genre", func(rec *Record) error {
+		if err := rec.Scan(&id, &name); err != nil {
+			return err
+		}
+		return ErrStopIteration
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	} else if id != 1 || name != "Rock" {
+		t.Errorf("expected (1, %q); got (%d, %q)", "Rock", id, name)
+	}
+}
+
+func TestDecodeRecord_malformed(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		payload  []byte
+		expected string
+	}{
+		{"empty header", []byte{0}, "header size 0 is invalid for a payload of 1 bytes"},
+		{"header past payload", []byte{5, 1, 42}, "header size 5 is invalid for a payload of 3 bytes"},
+		{"serial type past header", []byte{2, 0x81, 0x01}, "serial type of value 0 extends past the end of the header"},
+		{"missing value bytes", []byte{3, 1, 2, 42, 0}, "header and values take 6 bytes but the payload holds 5"},
+		{"trailing bytes", []byte{2, 1, 42, 0}, "header and values take 3 bytes but the payload holds 4"},
+		{"oversized values", append(append([]byte{19}, putVarint(math.MaxInt64)...), putVarint(math.MaxInt64)...), "header and values take 4611686018427387916 bytes but the payload holds 19"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DecodeRecord(UTF8, test.payload); err == nil || err.Error() != "malformed record: "+test.expected {
+				t.Errorf("expected %q; got %v", test.expected, err)
+			}
+		})
+	}
+
+	// a payload that spills onto more overflow pages than the file has is rejected before it is read
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	var cell = &Cell{Size: 1 << 48, s: []byte{3, 0x81, 0x80}, chain: &overflowChain{pager: file.Pager, usable: file.Header.usableSize()}}
+	if _, err := NewRecord(UTF8, cell); !errors.Is(err, ErrCorruptRecord) {
+		t.Errorf("expected ErrCorruptRecord; got %v", err)
+	}
+
+	// a record holding only NULL values, and an empty record, are valid; as is a record using serial types 8 and 9,
+	// as DecodeRecord doesn't know the schema format of the payload
+	for _, payload := range [][]byte{{3, 0, 0}, {1}, {3, 8, 9}} {
+		if _, err := DecodeRecord(UTF8, payload); err != nil {
+			t.Errorf("unexpected error for %v: %v", payload, err)
+		}
+	}
+}
+
+func TestRecord_Rowid(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	var without = open(t, "testdata/without-rowid.db")
+	defer without.Close()
+
+	for _, test := range []struct {
+		file     *File
+		name     string
+		rowid    int64
+		expected bool
+	}{
+		{file, "Genre", 1, true},
+		{file, "IFK_TrackGenreId", 0, false},
+		{without, "wordcount", 0, false},
+	} {
+		var obj, err = test.file.Object(test.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = obj.ForEach(func(rec *Record) error {
+			if rowid, ok := rec.Rowid(); rowid != test.rowid || ok != test.expected {
+				t.Errorf("%s: expected (%d, %t); got (%d, %t)", test.name, test.rowid, test.expected, rowid, ok)
+			}
+			return ErrStopIteration
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := record(t, []byte{1}, []byte{42}).Rowid(); ok {
+		t.Errorf("expected a decoded record to have no rowid")
+	}
+}
+
+func TestRecord_ByName(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	track, err := file.Object("Track")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = track.Head(1, func(rec *Record) (err error) {
+		// TrackId aliases the rowid, and is stored as NULL in the record
+		if id, err := rec.ByName("TrackId"); err != nil || id != int64(1) {
+			t.Errorf("expected TrackId to be %d; got %v (err=%v)", 1, id, err)
+		}
+
+		if id, err := rec.AsInt64ByName("trackid"); err != nil || id != 1 {
+			t.Errorf("expected trackid to be %d; got %d (err=%v)", 1, id, err)
+		}
+
+		if name, err := rec.AsStringByName("Name"); err != nil || name != "For Those About To Rock (We Salute You)" {
+			t.Errorf("expected Name of the first track; got %q (err=%v)", name, err)
+		}
+
+		if price, err := rec.AsFloat64ByName("UnitPrice"); err != nil || price != 0.99 {
+			t.Errorf("expected UnitPrice to be %v; got %v (err=%v)", 0.99, price, err)
+		}
+
+		if _, err = rec.ByName("Price"); err == nil || !strings.Contains(err.Error(), `no such column "Price"`) {
+			t.Errorf("expected an error for an unknown column; got %v", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := file.Object("IFK_TrackGenreId")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = index.Head(1, func(rec *Record) error {
+		if genre, err := rec.AsInt64ByName("GenreId"); err != nil || genre != 1 {
+			t.Errorf("expected GenreId to be %d; got %d (err=%v)", 1, genre, err)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a record decoded on its own has no column names
+	if _, err = record(t, []byte{1}, []byte{42}).ByName("a"); err == nil {
+		t.Errorf("expected an error accessing a decoded record by name")
+	}
+}
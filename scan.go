@@ -0,0 +1,341 @@
+package dotlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// IndexRange iterates, in index order, over the records of the named index whose key lies within lo and hi (both inclusive),
+// invoking fn for each of them. Only the parts of the index b-tree that may hold keys within the range are read.
+//
+// The bounds are compared against the leading values of each key: a bound with fewer values than the index has
+// columns matches every key that starts with those values. A nil bound leaves that end of the range open.
+// Values are compared following sqlite's sort order and the collation and sort direction of each index column,
+// and must be one of nil, an integer, a float, a string or a []byte.
+//
+// As index records hold the indexed columns followed by the table's key (see Object.RecordLayout),
+// a query that only needs those values can use IndexRange without reading the table at all.
+func (f *File) IndexRange(index string, lo, hi []any, fn func(*Record) error) (err error) {
+	var obj *Object
+	if obj, err = f.Object(index); err != nil {
+		return err
+	}
+
+	var r *keyRange
+	if r, err = newKeyRange(obj, lo, hi); err != nil {
+		return err
+	}
+
+	return r.scan(obj, fn)
+}
+
+// RangeByIndex iterates over the rows of a table whose key in the named index lies within lo and hi (both inclusive),
+// in index order, invoking fn with the table's record for each of them. Bounds are interpreted as in IndexRange.
+//
+// Rows are looked up one at a time as the index is scanned, so matching rowids are never collected in memory.
+// WITHOUT ROWID tables are not supported.
+func (f *File) RangeByIndex(index string, lo, hi []any, fn func(*Record) error) (err error) {
+	var obj *Object
+	if obj, err = f.Object(index); err != nil {
+		return err
+	}
+
+	var r *keyRange
+	if r, err = newKeyRange(obj, lo, hi); err != nil {
+		return err
+	}
+
+	var table *Object
+	if table, err = f.Object(r.table); err != nil {
+		return err
+	}
+
+	var schema *tableSchema
+	if schema, err = table.schema(); err != nil {
+		return err
+	} else if schema.withoutRowid {
+		return fmt.Errorf("table %q is a WITHOUT ROWID table and is not supported", table.name)
+	}
+
+	var decode func(*Cell) (*Record, error)
+	if decode, err = table.decoder(); err != nil {
+		return err
+	}
+
+	return r.scan(obj, func(entry *Record) (err error) {
+		// the rowid is the last value in every entry of an index on a rowid table
+		var rowid int64
+		if rowid, err = entry.AsInt64(entry.NumValues() - 1); err != nil {
+			return err
+		}
+
+		var cell *Cell
+		if cell, err = table.tree.Search(rowid); errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("row %d referenced by index %q not found in table %q", rowid, obj.name, table.name)
+		} else if err != nil {
+			return err
+		}
+
+		var rec *Record
+		if rec, err = decode(cell); err != nil {
+			return err
+		}
+
+		return fn(rec)
+	})
+}
+
+// ErrNotFound is returned by FindByIndex and Tree.Search when no row matches the key,
+// and wrapped by File.Object when no object has the given name
+var ErrNotFound = errors.New("no matching row found")
+
+// FindByIndex returns the first row of a table, in index order, whose key in the named index equals key;
+// the equivalent of SELECT * FROM t WHERE col = ? LIMIT 1, using the index on col. If the key has fewer values than
+// the index has columns, it matches on the leading columns only. Values are compared as described in IndexRange.
+//
+// Only the path to the matching entry is read from the index. ErrNotFound is returned if no row matches.
+// As with RangeByIndex, WITHOUT ROWID tables are not supported.
+func (f *File) FindByIndex(index string, key ...any) (rec *Record, err error) {
+	if len(key) == 0 {
+		return nil, errors.New("a key with at least one value is required")
+	}
+
+	err = f.RangeByIndex(index, key, key, func(r *Record) error {
+		rec = r
+		return ErrStopIteration
+	})
+
+	if err != nil {
+		return nil, err
+	} else if rec == nil {
+		return nil, ErrNotFound
+	}
+
+	return rec, nil
+}
+
+// keyRange describes a range of keys in an index
+type keyRange struct {
+	table  string // name of the table the index is defined on
+	lo, hi []any  // inclusive bounds of the range; nil if unbounded
+
+	collate []string // collation sequence used by each column of the index
+	desc    []bool   // true if the column is sorted in descending order
+}
+
+func newKeyRange(obj *Object, lo, hi []any) (_ *keyRange, err error) {
+	var layout []IndexColumnRole
+	if layout, err = obj.RecordLayout(); err != nil {
+		return nil, err
+	}
+
+	var index *indexSchema
+	if index, err = parseIndex(obj.sql); err != nil {
+		return nil, err
+	}
+
+	var r = &keyRange{table: index.table}
+	for _, role := range layout {
+		r.collate = append(r.collate, role.Collate)
+		r.desc = append(r.desc, role.Desc)
+	}
+
+	for _, bound := range [][]any{lo, hi} {
+		if len(bound) > len(layout) {
+			return nil, fmt.Errorf("bound has %d values but index %q has %d columns", len(bound), obj.name, len(layout))
+		}
+	}
+
+	if r.lo, err = normalizeBound(lo); err != nil {
+		return nil, err
+	}
+
+	if r.hi, err = normalizeBound(hi); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// scan walks the index b-tree of obj in order, invoking fn for every record within the range
+func (r *keyRange) scan(obj *Object, fn func(*Record) error) (err error) {
+	var decode func(*Cell) (*Record, error)
+	if decode, err = obj.decoder(); err != nil {
+		return err
+	}
+
+	var root *TreeNode
+	if root, err = obj.tree.RootNode(); err != nil {
+		return err
+	}
+
+	if err = r.walk(obj.tree, root, decode, fn, 0, map[int]bool{root.PageID(): true}); errors.Is(err, ErrStopIteration) {
+		return nil
+	}
+
+	return err
+}
+
+func (r *keyRange) walk(tree *Tree, node *TreeNode, decode func(*Cell) (*Record, error), fn func(*Record) error, depth int, visited map[int]bool) (err error) {
+	if node.Kind() != NodeIndexInt && node.Kind() != NodeIndexLeaf {
+		return fmt.Errorf("page %d is not part of an index b-tree", node.PageID())
+	}
+
+	var child *TreeNode
+	for i := 0; i < node.NumCells(); i++ {
+		var cell *Cell
+		if cell, err = node.LoadCell(i); err != nil {
+			return err
+		}
+
+		var rec *Record
+		if rec, err = decode(cell); err != nil {
+			return err
+		}
+
+		var pos int
+		if pos, err = r.position(rec); err != nil {
+			return err
+		}
+
+		// the left child only holds keys smaller than this cell's, so it can be skipped if this key is below the range
+		if cell.LeftChild != 0 && pos >= 0 {
+			if child, err = tree.visit(int(cell.LeftChild), depth+1, visited); err != nil {
+				return err
+			}
+
+			if err = r.walk(tree, child, decode, fn, depth+1, visited); err != nil {
+				return err
+			}
+		}
+
+		if pos > 0 { // every following key is past the range too
+			return ErrStopIteration
+		} else if pos == 0 {
+			if err = fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+
+	if node.right != 0 {
+		if child, err = tree.visit(int(node.right), depth+1, visited); err != nil {
+			return err
+		}
+
+		return r.walk(tree, child, decode, fn, depth+1, visited)
+	}
+
+	return nil
+}
+
+// position returns -1 if the key in rec is below the range, 1 if it is above and 0 if it lies within it
+func (r *keyRange) position(rec *Record) (_ int, err error) {
+	var c int
+	if c, err = r.compare(rec, r.lo); err != nil || (r.lo != nil && c < 0) {
+		return -1, err
+	}
+
+	if c, err = r.compare(rec, r.hi); err != nil || (r.hi != nil && c > 0) {
+		return 1, err
+	}
+
+	return 0, nil
+}
+
+// compare compares the leading values of the key in rec with bound
+func (r *keyRange) compare(rec *Record, bound []any) (_ int, err error) {
+	for i := 0; i < len(bound) && i < rec.NumValues(); i++ {
+		var val any
+		if val, err = rec.ValueAt(i); err != nil {
+			return 0, err
+		}
+
+		var c = compareKey(val, bound[i], r.collate[i])
+		if r.desc[i] {
+			c = -c
+		}
+
+		if c != 0 {
+			return c, nil
+		}
+	}
+
+	return 0, nil
+}
+
+// compareKey compares two values of an index key following sqlite's sort order, where NULL sorts first,
+// applying the named collation sequence to text values.
+func compareKey(a, b any, collate string) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	var x, ok1 = a.(string)
+	var y, ok2 = b.(string)
+	if ok1 && ok2 {
+		switch strings.ToUpper(collate) {
+		case "NOCASE": // only ASCII characters are folded
+			x, y = asciiLower(x), asciiLower(y)
+		case "RTRIM":
+			x, y = strings.TrimRight(x, " "), strings.TrimRight(y, " ")
+		}
+		return strings.Compare(x, y)
+	}
+
+	return compareValues(a, b)
+}
+
+// asciiLower lower-cases the ASCII characters in s, leaving any other character unchanged
+func asciiLower(s string) string {
+	var b = []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// normalizeBound converts the values of bound to the types used for decoded values
+func normalizeBound(bound []any) (_ []any, err error) {
+	if bound == nil {
+		return nil, nil
+	}
+
+	var values = make([]any, len(bound))
+	for i, val := range bound {
+		switch v := val.(type) {
+		case nil, int64, float64, string, []byte:
+			values[i] = v
+		case int:
+			values[i] = int64(v)
+		case int8:
+			values[i] = int64(v)
+		case int16:
+			values[i] = int64(v)
+		case int32:
+			values[i] = int64(v)
+		case uint8:
+			values[i] = int64(v)
+		case uint16:
+			values[i] = int64(v)
+		case uint32:
+			values[i] = int64(v)
+		case float32:
+			values[i] = float64(v)
+		default:
+			return nil, fmt.Errorf("unsupported value of type %T in bound", val)
+		}
+	}
+
+	return values, nil
+}
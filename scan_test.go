@@ -0,0 +1,95 @@
+package dotlite
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRangeByIndex(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// SELECT TrackId FROM Track WHERE GenreId BETWEEN 20 AND 21 ORDER BY GenreId, TrackId
+	var expected []int64
+	for _, id := range strings.Split("2837,2838,3226,3227,3228,3229,3230,3231,3232,3233,3234,3235,3236,3237,3238,3239,3240,3241,3242,3243,3244,3245,3246,3247,3248,3249,"+
+		"2840,2841,2842,2843,2844,2846,2847,2849,2850,2851,2852,2853,2854,2855,2856,2862,2866,2875,2876,2881,2882,2886,2889,2890,2891,2892,2895,2897,2899,2900,"+
+		"2902,2903,2905,2907,2908,2909,2912,2916,2922,3165,3166,3167,3168,3169,3170,3171,3223,3224,3251,3252,3337,3338,3339,3340,3341,3342,3344,3345,3348,3360,"+
+		"3361,3362,3363,3364", ",") {
+		var n, _ = strconv.ParseInt(id, 10, 64)
+		expected = append(expected, n)
+	}
+
+	var rowids []int64
+	var err = file.RangeByIndex("IFK_TrackGenreId", []any{20}, []any{21}, func(rec *Record) error {
+		if genre, _ := rec.AsInt64(4); genre != 20 && genre != 21 {
+			t.Errorf("row %d: unexpected genre %d", rec.cell.Rowid, genre)
+		}
+		rowids = append(rowids, rec.cell.Rowid)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(rowids, expected) {
+		t.Errorf("expected rows %v; got %v", expected, rowids)
+	}
+}
+
+func TestIndexRange(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	// person_name_age is defined on (name COLLATE NOCASE, age DESC)
+	var names []string
+	var err = file.IndexRange("person_name_age", []any{"ALICE"}, []any{"Bob", 25}, func(rec *Record) error {
+		var name, _ = rec.AsString(0)
+		names = append(names, name)
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"alice", "bob"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v; got %v", expected, names)
+	}
+
+	if err = file.IndexRange("person_name_age", []any{struct{}{}}, nil, nil); err == nil {
+		t.Errorf("expected an error for an unsupported bound")
+	}
+}
+
+func TestFindByIndex(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	// SELECT * FROM Track WHERE GenreId = 20 LIMIT 1
+	rec, err := file.FindByIndex("IFK_TrackGenreId", 20)
+	if err != nil {
+		t.Fatal(err)
+	} else if rowid, _ := rec.Rowid(); rowid != 2837 {
+		t.Errorf("expected track %d; got %d", 2837, rowid)
+	} else if name, _ := rec.AsString(1); name != "Crossroads, Pt. 1" {
+		t.Errorf("expected track %q; got %q", "Crossroads, Pt. 1", name)
+	}
+
+	if _, err = file.FindByIndex("IFK_TrackGenreId", 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+
+	var people = open(t, "testdata/indexes.db")
+	defer people.Close()
+
+	// name is declared COLLATE NOCASE
+	if rec, err = people.FindByIndex("person_name_age", "BOB"); err != nil {
+		t.Fatal(err)
+	} else if rowid, _ := rec.Rowid(); rowid != 2 {
+		t.Errorf("expected person %d; got %d", 2, rowid)
+	}
+}
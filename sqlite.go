@@ -1,10 +1,15 @@
 package dotlite
 
 import (
+	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
 )
 
 // Magic is the 16-byte constant magic value used by sqlite3
@@ -20,6 +25,27 @@ const (
 	UTF16BE
 )
 
+// String returns the name of the encoding, as used by sqlite's encoding pragma
+func (te TextEncoding) String() string {
+	switch te {
+	case UTF8:
+		return "UTF-8"
+	case UTF16LE:
+		return "UTF-16le"
+	case UTF16BE:
+		return "UTF-16be"
+	}
+	return "unknown"
+}
+
+// ErrTruncatedHeader is returned when the file is too short to contain the 100-byte database header
+var ErrTruncatedHeader = errors.New("file is too short to contain a database header")
+
+// ErrEncrypted is returned when the file doesn't start with the sqlite magic string, but otherwise looks like
+// a database whose content is encrypted (for example by SQLCipher or the SQLite Encryption Extension), which
+// this package can't read. It is a best guess: a file of random content is reported as encrypted too.
+var ErrEncrypted = errors.New("file is not a plain sqlite database; it is likely encrypted")
+
 // Header describes the sqlite3 database header as defined under https://www.sqlite.org/fileformat.html#the_database_header
 type Header struct {
 	Magic           [16]byte
@@ -49,145 +75,801 @@ type Header struct {
 	LibraryVersion int32
 }
 
+// schemaCookieOffset is the offset of Header.SchemaCookie from the start of the file
+const schemaCookieOffset = 40
+
 // Valid validates the header ensuring it is well-formed and correct.
 func (h *Header) Valid() error {
 	if string(h.Magic[:]) != Magic {
-		return fmt.Errorf("invalid header")
+		return errorf(ErrInvalidMagic, "invalid header")
 	}
 
 	// ensure file can be read
 	if h.ReadVersion > 2 {
-		return fmt.Errorf("file not readable by current version of library")
+		return errorf(ErrUnsupportedVersion, "file not readable by current version of library")
+	}
+
+	// page size must be a power of two between 512 and 65536
+	if sz := h.pageSize(); sz < 512 || sz > 65536 || sz&(sz-1) != 0 {
+		return errorf(ErrInvalidHeader, "invalid page size %d", sz)
 	}
 
 	// Ensure reserved space at the end of the page is valid.
 	// The documentation states that "the usable size is not allowed to be less than 480 [bytes]"
-	if usable := h.PageSize - uint16(h.PageReserved); usable < 480 {
-		return fmt.Errorf("invalid file: usable page size is less than allowed limit")
+	if usable := h.usableSize(); usable < 480 {
+		return errorf(ErrInvalidHeader, "invalid file: usable page size is less than allowed limit")
 	}
 
 	// ensure payload fraction values are fixed; see: https://www.sqlite.org/fileformat.html#payload_fractions
 	if h.MaxEmbeddedFrac != 64 || h.MinEmbeddedFrac != 32 || h.LeafFrac != 32 {
-		return fmt.Errorf("invalid payload fractions")
+		return errorf(ErrInvalidHeader, "invalid payload fractions")
 	}
 
 	return nil
 }
 
+// pageSize returns the database page size in bytes; a stored value of 1 represents a page size of 65536
+func (h *Header) pageSize() int {
+	if h.PageSize == 1 {
+		return 65536
+	}
+	return int(h.PageSize)
+}
+
+// usableSize returns the usable size of each page, ie. the page size less the reserved space at its end
+func (h *Header) usableSize() int { return h.pageSize() - int(h.PageReserved) }
+
 // File represents a sqlite3 database file
+//
+// A File is safe for concurrent use by multiple goroutines, eg. to read different tables in parallel, with the
+// exception of RefreshSchemaCookie, which updates the header and must not run concurrently with other methods.
 type File struct {
 	Header Header // sqlite3 database header; see: https://www.sqlite.org/fileformat.html#the_database_header
 
 	//-  start of internal state
-	file   *os.File // the underlying file reference
+	file   *os.File // the underlying file reference; nil if opened with OpenReaderAt
 	closer io.Closer
 	Pager  *Pager // pager used to fetch pages
+
+	size      int64 // size of the database file in bytes
+	resizable bool  // the file was opened with Open, and is read through a section bounded by size that can be extended
+	declared  int   // number of pages according to the in-header database size; zero if it isn't valid
+
+	strictColumns bool // report records with fewer values than the table's columns as errors
+	typedValues   bool // convert values of a table's records to their column's affinity
+
+	invalidText InvalidTextPolicy // how text values that aren't valid in the database encoding are returned
+
+	uncheckedJSON bool // return JSON text from Record.AsJSON without validating it
+
+	rejectHotJournal bool // fail to open a database with a hot rollback journal
+
+	lenient    bool        // skip rows that fail to decode instead of aborting the iteration
+	onRowError func(error) // receives errors for rows skipped in lenient mode; may be nil
+
+	maxDepth int // Tree.MaxDepth of the trees of the file; zero for DefaultMaxDepth
 }
 
-// Open reads the stream from f as a sqlite database file.
-func Open(name string) (_ *File, err error) {
-	var f *os.File
-	if f, err = os.Open(name); err != nil {
-		return nil, err
+// Option configures optional behaviour of a File
+type Option func(*File)
+
+// WithStrictColumns makes ForEach report a table's record whose number of values doesn't match
+// the number of columns in the table as an error, rather than padding omitted trailing values with NULL.
+//
+// sqlite legitimately omits trailing NULL values (for example, in rows written before a column was
+// added with ALTER TABLE ... ADD COLUMN), so this is only useful when checking a file for corruption.
+func WithStrictColumns() Option { return func(f *File) { f.strictColumns = true } }
+
+// WithTypedValues makes records read from a table return values converted to the type affinity
+// of their column (as declared in the table's schema), instead of the storage class they were stored with.
+// For example, an integer stored in a TEXT column is returned as a string.
+//
+// The conversion follows the rules of sqlite's CAST expression (see https://www.sqlite.org/lang_expr.html#castexpr):
+//
+//   - TEXT: integers and reals are rendered as text; blobs are interpreted as text
+//   - INTEGER: reals are truncated towards zero; text and blobs are converted using their longest integer prefix, or 0
+//   - REAL: integers are converted to reals; text and blobs are converted using their longest numeric prefix, or 0.0
+//   - NUMERIC: text and blobs are converted to an integer or a real; reals with an integral value become integers
+//   - BLOB (or no declared type): values are not converted
+//
+// NULL values are never converted.
+func WithTypedValues() Option { return func(f *File) { f.typedValues = true } }
+
+// InvalidTextPolicy controls how a TEXT value that isn't valid in the database's text encoding is returned.
+// Such values can appear in corrupt or hand-crafted files.
+type InvalidTextPolicy int
+
+const (
+	InvalidTextKeep    InvalidTextPolicy = iota // return the value as a string as-is, even though it is invalid (default)
+	InvalidTextError                            // report an error when the value is read
+	InvalidTextReplace                          // replace each invalid byte sequence with the unicode replacement character (U+FFFD)
+	InvalidTextRaw                              // return the underlying bytes as a []byte instead of a string
+)
+
+// WithInvalidText sets the policy used for TEXT values that aren't valid in the database's text encoding.
+func WithInvalidText(policy InvalidTextPolicy) Option {
+	return func(f *File) { f.invalidText = policy }
+}
+
+// WithUncheckedJSON makes Record.AsJSON return TEXT values without checking that they are well-formed JSON,
+// saving the cost of parsing every value when the content is trusted.
+func WithUncheckedJSON() Option { return func(f *File) { f.uncheckedJSON = true } }
+
+// WithLenientRows makes ForEach skip rows that cannot be decoded (for example, because of a corrupt cell
+// or an unreadable page) instead of aborting the iteration, allowing the readable majority of a partially
+// corrupt table to be extracted. Each failure is passed to fn (if non-nil) as it occurs, and once the iteration
+// completes, ForEach returns a *SkippedRowsError describing all the failures.
+//
+// Errors returned by the callback passed to ForEach still abort the iteration. Rows of sqlite_schema are never
+// skipped: a corrupt schema fails Schema and Object as it would otherwise.
+func WithLenientRows(fn func(err error)) Option {
+	return func(f *File) { f.lenient, f.onRowError = true, fn }
+}
+
+// SkippedRowsError is returned by ForEach on a file opened WithLenientRows when one or more rows were skipped
+type SkippedRowsError struct {
+	Errors []error // errors for each of the skipped rows (or pages), in the order they were encountered
+}
+
+func (e *SkippedRowsError) Error() string {
+	return fmt.Sprintf("%d row(s) skipped because they could not be decoded", len(e.Errors))
+}
+
+// WithMaxDepth sets the maximum depth (see Tree.MaxDepth) of every b-tree read from the file, including those
+// walked by Object.ForEach and while reading the schema, in place of DefaultMaxDepth. Lowering it bounds the work
+// done on hostile input; sqlite itself never reads trees deeper than 20 levels. A depth less than or equal to
+// zero keeps the default.
+func WithMaxDepth(depth int) Option {
+	return func(f *File) { f.maxDepth = depth }
+}
+
+// WithRetry makes the pager retry a failed page read up to attempts times in total, waiting for backoff
+// before the first retry and doubling the wait after every subsequent one. This is useful when reading files on
+// unreliable storage (such as network filesystems) where a read can fail transiently.
+// If all attempts fail, the last error is returned wrapped.
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(f *File) {
+		if attempts > 1 {
+			f.Pager.file = &retryReader{r: f.Pager.file, attempts: attempts, backoff: backoff}
+		}
 	}
+}
 
+// ReadHeader reads and validates the 100-byte database header at the start of r, without reading any page.
+// It only requires the first 100 bytes of the file to be present, making it a cheap way to inspect
+// the metadata (page size, encoding, version, application id, etc.) of many files.
+//
+// As the size of the file isn't known, Header.Size holds the in-header database size, which may be stale.
+func ReadHeader(r io.ReaderAt) (_ *Header, err error) {
 	var header Header
-	if err = binary.Read(f, binary.BigEndian, &header); err != nil {
+	if err = binary.Read(io.NewSectionReader(r, 0, 100), binary.BigEndian, &header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrTruncatedHeader
+		}
 		return nil, err
 	}
 
-	// determine database size (in pages) if any of this condition is met
-	// see: https://www.sqlite.org/fileformat.html#in_header_database_size
-	if header.Size == 0 || (header.ChangeCounter != header.VersionValid) {
-		var size int64
-		if size, err = f.Seek(0, io.SeekEnd); err != nil {
-			return nil, err
-		}
+	if err = header.Valid(); err != nil {
+		return nil, err
+	}
 
-		if _, err = f.Seek(0, io.SeekStart); err != nil { // reset
-			return nil, err
+	return &header, nil
+}
+
+// Open reads the stream from f as a sqlite database file.
+//
+// A rollback journal left behind by an interrupted transaction is ignored; see File.HasHotJournal and
+// WithRejectHotJournal to detect such a journal.
+func Open(name string, opts ...Option) (_ *File, err error) {
+	var f *os.File
+	if f, err = os.Open(name); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil { // don't leak the file handle if the file can't be opened
+			_ = f.Close()
 		}
+	}()
 
-		var pages = (size + int64(header.PageSize) - 1) / int64(header.PageSize)
-		header.Size = int32(pages)
+	var size int64
+	if size, err = f.Seek(0, io.SeekEnd); err != nil {
+		return nil, err
 	}
 
-	if err = header.Valid(); err != nil {
+	var file *File
+	if file, err = OpenReaderAt(f, size, opts...); err != nil {
 		return nil, err
 	}
+	file.file, file.closer, file.resizable = f, f, true
+
+	if file.rejectHotJournal && file.HasHotJournal() {
+		return nil, ErrHotJournal
+	}
 
-	if _, err = f.Seek(0, io.SeekStart); err != nil {
+	return file, nil
+}
+
+// OpenReaderAt reads the first size bytes of r as a sqlite database file. Pages are read from r as they are needed,
+// so r may be backed by anything that supports random access, such as a remote object read with ranged requests.
+//
+// Closing the returned File doesn't close r. As there is no file name to find it by, a rollback journal is never
+// checked for (see File.HasHotJournal).
+func OpenReaderAt(r io.ReaderAt, size int64, opts ...Option) (_ *File, err error) {
+	var sr = io.NewSectionReader(r, 0, size)
+
+	var hdr *Header
+	if hdr, err = ReadHeader(sr); err != nil {
+		if !errors.Is(err, ErrTruncatedHeader) && encrypted(sr, size) {
+			return nil, ErrEncrypted
+		}
 		return nil, err
 	}
+	var header = *hdr
+
+	var declared = header.resize(size)
 
 	// pager is used to fetch and read pages of data from the database file
 	// other high-level constructs (such as free-list and btree) builds on top of pager
-	var pager = &Pager{file: f, size: int(header.PageSize), pages: int(header.Size)}
+	var pager = &Pager{file: sr, length: size, size: header.pageSize(), pages: int(header.Size)}
+
+	var file = &File{Header: header, Pager: pager, closer: nopCloser{}, size: size, declared: declared}
+	for _, opt := range opts {
+		opt(file)
+	}
 
-	var file = &File{Header: header, Pager: pager, file: f, closer: f}
 	return file, nil
 }
 
+// OpenBytes reads b as a sqlite database file, eg. one embedded with go:embed. The slice isn't copied,
+// so it must not be modified while the File is in use.
+func OpenBytes(b []byte, opts ...Option) (*File, error) {
+	return OpenReaderAt(bytes.NewReader(b), int64(len(b)), opts...)
+}
+
+// encrypted reports whether the file, which doesn't hold a valid header, looks like an encrypted database:
+// its size is a whole number of pages, and it either starts with a random salt in place of the magic string
+// followed by a plaintext header (as SQLCipher can be configured to write), or starts with random bytes.
+func encrypted(r io.ReaderAt, size int64) bool {
+	var buf [24]byte
+	if _, err := r.ReadAt(buf[:], 0); err != nil || string(buf[:16]) == Magic || size%512 != 0 {
+		return false
+	}
+
+	// payload fractions are fixed, and follow the page size and format versions in a plaintext header
+	if buf[21] == 64 && buf[22] == 32 && buf[23] == 32 {
+		return true
+	}
+
+	// encrypted content is indistinguishable from random bytes, which are mostly distinct and rarely all printable
+	var distinct, printable = make(map[byte]bool), 0
+	for _, b := range buf {
+		distinct[b] = true
+		if b >= 0x20 && b < 0x7f || b == '\n' || b == '\r' || b == '\t' {
+			printable++
+		}
+	}
+	return len(distinct) >= 16 && printable < len(buf)
+}
+
+// resize sets Size to the number of pages in a database file of the given size, and returns the in-header
+// database size if it is valid, or zero otherwise
+func (header *Header) resize(size int64) (declared int) {
+	// determine database size (in pages) if any of this condition is met
+	// see: https://www.sqlite.org/fileformat.html#in_header_database_size
+	//
+	// an in-header size that claims more pages than the file actually holds is also ignored
+	// as those pages could never be read anyway.
+	if header.Size > 0 && header.ChangeCounter == header.VersionValid {
+		declared = int(header.Size)
+	}
+
+	var pages = (size + int64(header.pageSize()) - 1) / int64(header.pageSize())
+	if header.Size <= 0 || (header.ChangeCounter != header.VersionValid) || int64(header.Size) > pages {
+		header.Size = int32(pages)
+	}
+
+	return declared
+}
+
+// nopCloser is an io.Closer that does nothing
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
 // NumPages returns the number of pages in the database
 func (f *File) NumPages() int { return int(f.Header.Size) }
 
+// VerifySize checks that the size of the underlying file, as of when it was opened, matches the number of pages
+// in the database. It reports an error if the file ends with an incomplete page, or if the in-header database size
+// (when valid) claims more pages than the file holds, indicating the file was truncated, or fewer, indicating
+// trailing garbage.
+//
+// Open never reads past the end of the file, so a truncated file otherwise only surfaces as errors while reading pages.
+func (f *File) VerifySize() (err error) {
+	var size, pageSize = f.size, int64(f.PageSize())
+	if size%pageSize != 0 {
+		return fmt.Errorf("file size %d is not a multiple of the page size %d: the last page is incomplete", size, pageSize)
+	}
+
+	switch pages := int(size / pageSize); {
+	case f.declared > pages:
+		return fmt.Errorf("header declares %d pages but the file only holds %d: the file is truncated", f.declared, pages)
+	case f.declared != 0 && f.declared < pages:
+		return fmt.Errorf("header declares %d pages but the file holds %d: the file has trailing data", f.declared, pages)
+	}
+
+	return nil
+}
+
 // PageSize returns the database page size in bytes
-func (f *File) PageSize() int { return int(f.Header.PageSize) }
+func (f *File) PageSize() int { return f.Header.pageSize() }
 
 // Encoding returns the text encoding for this database
 func (f *File) Encoding() TextEncoding { return f.Header.TextEncoding }
 
+// EncodingName returns the name of the text encoding for this database, eg. UTF-8
+func (f *File) EncodingName() string { return f.Header.TextEncoding.String() }
+
 // Version returns the sqlite version number used to create this database
 func (f *File) Version() int { return int(f.Header.LibraryVersion) }
 
+// UserVersion returns the user version of the database, as read and set by PRAGMA user_version
+func (f *File) UserVersion() int32 { return f.Header.UserVersion }
+
+// ApplicationID returns the application id of the database, as read and set by PRAGMA application_id
+func (f *File) ApplicationID() int32 { return f.Header.ApplicationID }
+
+// SchemaFormat returns the schema format number of the database, one of 1 to 4 (see PRAGMA legacy_file_format)
+func (f *File) SchemaFormat() int { return int(f.Header.SchemaFormat) }
+
+// ChangeCounter returns the file change counter, which sqlite increments on every transaction that modifies the
+// database (in rollback-journal mode; it may be stale in WAL mode)
+func (f *File) ChangeCounter() int32 { return f.Header.ChangeCounter }
+
+// ReservedBytes returns the number of bytes reserved at the end of every page, usually zero
+func (f *File) ReservedBytes() int { return int(f.Header.PageReserved) }
+
+// LargestRootPage returns the page number of the largest root b-tree page in an auto-vacuum
+// (or incremental-vacuum) database, or zero if the database doesn't use auto-vacuum.
+func (f *File) LargestRootPage() int { return int(f.Header.AutoVacuum) }
+
+// IncrementalVacuum reports whether the database is in incremental-vacuum mode, where free pages are only
+// moved to the end of the file and truncated when requested (see PRAGMA incremental_vacuum),
+// rather than on every commit. Such a database is also an auto-vacuum database (see LargestRootPage).
+func (f *File) IncrementalVacuum() bool { return f.Header.AutoVacuum != 0 && f.Header.IncrVacuum != 0 }
+
+// ValidateRootPages checks that the root page of every table and index in an auto-vacuum database
+// lies within the largest root page recorded in the header (see LargestRootPage), as sqlite relocates
+// root pages to the start of the file when vacuuming. It returns an error for every object that violates this.
+//
+// Databases that don't use auto-vacuum have no such invariant and always pass the check.
+func (f *File) ValidateRootPages() (errs []error) {
+	var largest = f.LargestRootPage()
+	if largest == 0 {
+		return nil
+	}
+
+	var err = f.schema(func(obj *Object) error {
+		if !obj.hasTree() {
+			return nil
+		}
+
+		if obj.tree.root < 1 || obj.tree.root > largest {
+			errs = append(errs, fmt.Errorf("%s %q has root page %d but the largest root page is %d", obj.typ, obj.name, obj.tree.root, largest))
+		}
+		return nil
+	})
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// SchemaVersion returns the schema cookie and the schema format number from the file's header.
+// sqlite increments the schema cookie every time the schema changes.
+func (f *File) SchemaVersion() (cookie uint32, format int32) {
+	return binary.BigEndian.Uint32(f.Header.SchemaCookie[:]), f.Header.SchemaFormat
+}
+
+// RefreshSchemaCookie re-reads the schema cookie from the header of the underlying file and reports
+// whether it changed since the file was opened (or since the last call to RefreshSchemaCookie), ie. whether
+// another connection has altered the schema in the meantime. The new cookie is then reported by SchemaVersion.
+//
+// When the cookie changed, the rest of the header is re-read too, along with the size of a file opened with Open,
+// so that pages added by the writer can be read. The schema is never cached: Schema and Object always read
+// sqlite_schema afresh, so a changed schema is picked up by the next call to them. Objects obtained before
+// the change should be looked up again. If the pager has a page cache (see WithPageCache), it is emptied.
+//
+// The size of a file opened otherwise (eg. with OpenReaderAt, OpenBytes or OpenFileMmap) is fixed, and a write-ahead
+// log is only read when the file is opened: such files must be reopened to read pages added since.
+//
+// Unlike other methods of File, RefreshSchemaCookie must not be called concurrently with any other method.
+func (f *File) RefreshSchemaCookie() (changed bool, err error) {
+	var cookie [4]byte
+	var r, offset = f.Pager.source(1) // bypass the page cache, which may hold a stale copy
+	if _, err = r.ReadAt(cookie[:], offset+schemaCookieOffset); err != nil {
+		return false, err
+	}
+
+	changed = cookie != f.Header.SchemaCookie
+	f.Header.SchemaCookie = cookie
+
+	if !changed {
+		return false, nil
+	}
+
+	if f.Pager.cache != nil {
+		f.Pager.cache.reset()
+	}
+
+	if f.Pager.wal == nil {
+		if err = f.reload(); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// reload re-reads the size of the underlying file (if it was opened with Open) and the header,
+// and updates the bounds of the pager to match
+func (f *File) reload() (err error) {
+	var size = f.size
+	if f.resizable {
+		var info os.FileInfo
+		if info, err = f.file.Stat(); err != nil {
+			return err
+		}
+		size = info.Size()
+
+		// the pager reads the file through a section bounded by its size when it was opened
+		var sr = io.NewSectionReader(f.file, 0, size)
+		if r, ok := f.Pager.file.(*retryReader); ok {
+			r.r = sr
+		} else {
+			f.Pager.file = sr
+		}
+	}
+
+	var hdr *Header
+	if hdr, err = ReadHeader(f.Pager.file); err != nil {
+		return err
+	}
+
+	f.declared = hdr.resize(size)
+	f.Header, f.size = *hdr, size
+	f.Pager.size, f.Pager.pages, f.Pager.length = hdr.pageSize(), int(hdr.Size), size
+
+	return nil
+}
+
+// SameSchema reports whether a and b have the same schema cookie and format, using only their headers.
+//
+// It is meant to compare two copies (or snapshots) of the same database, eg. to decide if a previously parsed
+// schema can be reused: a matching cookie then means the schema hasn't changed. Unrelated databases may
+// have matching cookies while having different schemas.
+func SameSchema(a, b *File) bool {
+	var ac, af = a.SchemaVersion()
+	var bc, bf = b.SchemaVersion()
+	return ac == bc && af == bf
+}
+
 // Close closes the underlying file handle
 func (f *File) Close() error { return f.closer.Close() }
 
-// Schema returns a list of all tables and indexes found in the file.
+// Schema returns a list of all tables, indexes, views and triggers found in the file.
 // It parses sqlite_schema table, found at database page 1.
 //
 // see: https://www.sqlite.org/fileformat.html#storage_of_the_sql_database_schema
 func (f *File) Schema() (_ []*Object, err error) {
-	var tree = NewTree(f, f.Pager, 1)
-	var schemaTable = NewObject("sqlite_schema", "table", "CREATE TABLE sqlite_schema(type,name,tbl_name,rootpage,sql)", tree)
+	var objects []*Object
+	err = f.schema(func(obj *Object) error {
+		objects = append(objects, obj)
+		return nil
+	})
+
+	return objects, err
+}
+
+// SchemaFrom is like Schema, but reads the schema table from the b-tree rooted at the given page instead of page 1.
+//
+// It is meant for recovering databases whose sqlite_schema is damaged, by pointing at a salvaged copy of the
+// schema table found elsewhere in the file. The objects' root pages are taken as-is from the salvaged table.
+func (f *File) SchemaFrom(root int) (_ []*Object, err error) {
+	if root < 1 || root > f.NumPages() {
+		return nil, errorf(ErrPageOutOfRange, "root page %d is out of range: database has %d pages", root, f.NumPages())
+	}
 
 	var objects []*Object
-	err = schemaTable.ForEach(func(record *Record) (err error) {
+	err = f.schemaAt(root, func(obj *Object) error {
+		objects = append(objects, obj)
+		return nil
+	})
+
+	return objects, err
+}
+
+// schema walks the sqlite_schema table, invoking fn for every table, index, view and trigger found in it.
+// fn can return ErrStopIteration to stop the walk early.
+func (f *File) schema(fn func(*Object) error) error { return f.schemaAt(1, fn) }
+
+// schemaAt walks a schema table stored in the b-tree rooted at the given page
+func (f *File) schemaAt(root int, fn func(*Object) error) error {
+	var tree = NewTree(f, f.Pager, root)
+	var schemaTable = NewObject("sqlite_schema", "table", "CREATE TABLE sqlite_schema(type,name,tbl_name,rootpage,sql)", tree)
+	schemaTable.strict = true // a schema missing objects can't be relied upon, so corrupt rows fail the walk
+
+	return schemaTable.ForEach(func(record *Record) (err error) {
+		record.invalidText = InvalidTextKeep // the sql must be returned exactly as it is stored
+
 		var typ, _ = record.AsString(0)
 		var name, _ = record.AsString(1)
+		var tbl, _ = record.AsString(2)
 		var root, _ = record.AsInt(3)
 		var sql, _ = record.AsString(4)
 
-		if typ == "table" || typ == "index" {
-			objects = append(objects, NewObject(name, typ, sql, NewTree(f, f.Pager, root)))
+		if parseObjectType(typ) != ObjectUnknown {
+			var obj = NewObject(name, typ, sql, NewTree(f, f.Pager, root))
+			obj.tbl = tbl
+			return fn(obj)
 		}
 
 		return nil
 	})
+}
 
-	return objects, err
+// Object returns the table, index, view or trigger with the given name.
+// Unlike Schema, it stops reading sqlite_schema as soon as the object is found.
+func (f *File) Object(name string) (obj *Object, err error) {
+	err = f.schema(func(o *Object) error {
+		if o.Name() == name {
+			obj = o
+			return ErrStopIteration
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	} else if obj == nil {
+		return nil, errorf(ErrNotFound, "object with name %q not found", name)
+	}
+
+	return obj, nil
 }
 
-func (f *File) Object(name string) (_ *Object, err error) {
-	var objects []*Object
-	if objects, err = f.Schema(); err != nil {
+// Indexes returns the indexes on the named table, as associated by the tbl_name column of sqlite_schema, in the
+// order they appear there. Automatic indexes (named sqlite_autoindex_*) are included, with an empty SQL.
+// Table names are matched case-insensitively, as sqlite does.
+func (f *File) Indexes(table string) (indexes []*Object, err error) {
+	err = f.schema(func(obj *Object) error {
+		if obj.Kind() == ObjectIndex && strings.EqualFold(obj.TableName(), table) {
+			indexes = append(indexes, obj)
+		}
+		return nil
+	})
+
+	if err != nil {
 		return nil, err
 	}
 
-	for _, obj := range objects {
-		if obj.Name() == name {
-			return obj, nil
+	return indexes, nil
+}
+
+// Sequences reads sqlite_sequence, returning the largest rowid ever used by every AUTOINCREMENT table that
+// had a row inserted into it, keyed by the name of the table (see Object.Sequence). If no such table exists,
+// and so neither does sqlite_sequence, the map is empty.
+func (f *File) Sequences() (_ map[string]int64, err error) {
+	var sequences = make(map[string]int64)
+
+	var sequence *Object
+	if sequence, err = f.Object("sqlite_sequence"); errors.Is(err, ErrNotFound) {
+		return sequences, nil // sqlite_sequence doesn't exist until a row is inserted into an AUTOINCREMENT table
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = sequence.ForEach(func(rec *Record) (err error) {
+		var name string
+		if name, err = rec.AsString(0); err != nil {
+			return err
+		}
+
+		var seq int64
+		if seq, err = rec.AsInt64(1); err != nil {
+			return err
 		}
+
+		sequences[name] = seq
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("object with name %q not found", name)
+	return sequences, nil
 }
 
 func (f *File) ForEach(name string, fn func(*Record) error) (err error) {
+	return f.ForEachContext(context.Background(), name, fn)
+}
+
+// ForEachContext iterates over each row of the named object like ForEach; see Object.ForEachContext.
+func (f *File) ForEachContext(ctx context.Context, name string, fn func(*Record) error) (err error) {
 	var table *Object
 	if table, err = f.Object(name); err != nil {
 		return err
 	}
 
-	return table.ForEach(fn)
+	return table.ForEachContext(ctx, fn)
+}
+
+// TableCatalog bundles a table with its parsed schema and the indexes defined on it
+type TableCatalog struct {
+	Table       *Object       // the table itself
+	Columns     []*Column     // columns defined in the table's schema
+	ForeignKeys []*ForeignKey // foreign key constraints defined on the table
+	Indexes     []*Object     // indexes on the table (including automatic ones), in the order they appear in sqlite_schema
+}
+
+// CatalogByTable reads the schema and returns, for every table keyed by its name, the table along with
+// its parsed schema and its indexes, as associated by the tbl_name column of sqlite_schema.
+// It gives documentation and migration tools everything about a table in a single call.
+func (f *File) CatalogByTable() (_ map[string]TableCatalog, err error) {
+	var objects []*Object
+	if objects, err = f.Schema(); err != nil {
+		return nil, err
+	}
+
+	var catalog = make(map[string]TableCatalog)
+	var names = make(map[string]string) // lower-cased table name -> table name
+	for _, obj := range objects {
+		if obj.Kind() != ObjectTable {
+			continue
+		}
+
+		var table *tableSchema
+		if table, err = obj.schema(); err != nil {
+			return nil, err
+		}
+
+		catalog[obj.Name()] = TableCatalog{Table: obj, Columns: table.columns, ForeignKeys: table.foreignKeys}
+		names[strings.ToLower(obj.Name())] = obj.Name()
+	}
+
+	for _, obj := range objects {
+		if obj.Kind() != ObjectIndex {
+			continue
+		}
+
+		if name, ok := names[strings.ToLower(obj.TableName())]; ok {
+			var entry = catalog[name]
+			entry.Indexes = append(entry.Indexes, obj)
+			catalog[name] = entry
+		}
+	}
+
+	return catalog, nil
+}
+
+// CycleError is returned by TableOrder when the foreign key graph contains one or more cycles
+type CycleError struct {
+	Tables []string // tables that were placed before (some of) the tables they reference in order to break the cycle(s)
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("foreign key cycle detected; broken at table(s): %s", strings.Join(e.Tables, ", "))
+}
+
+// TableOrder returns the names of all tables in the database ordered such that
+// a table referenced by a foreign key always appears before the tables referencing it.
+// Dumping tables in this order allows the output to be re-imported with foreign keys enforced.
+//
+// Self-references and references to tables that do not exist are ignored. Ties are broken
+// using the order in which tables appear in sqlite_schema, making the result deterministic.
+//
+// If the foreign key graph contains a cycle, it is broken by placing the earliest (in schema order)
+// table of the cycle first. In that case the complete order is still returned along with a *CycleError
+// describing where the cycle(s) were broken.
+func (f *File) TableOrder() (_ []string, err error) {
+	var objects []*Object
+	if objects, err = f.Schema(); err != nil {
+		return nil, err
+	}
+
+	var tables []*Object
+	var index = make(map[string]int) // lower-cased table name -> position in tables
+	for _, obj := range objects {
+		if obj.Kind() == ObjectTable {
+			index[strings.ToLower(obj.Name())] = len(tables)
+			tables = append(tables, obj)
+		}
+	}
+
+	// parents[i] holds the set of tables that table i references
+	var parents = make([]map[int]bool, len(tables))
+	for i, table := range tables {
+		var fks []*ForeignKey
+		if fks, err = table.ForeignKeys(); err != nil {
+			return nil, err
+		}
+
+		parents[i] = make(map[int]bool)
+		for _, fk := range fks {
+			if j, ok := index[strings.ToLower(fk.ReferencedTable)]; ok && j != i {
+				parents[i][j] = true
+			}
+		}
+	}
+
+	var order = make([]string, 0, len(tables))
+	var placed = make([]bool, len(tables))
+	var broken []string
+
+	for len(order) < len(tables) {
+		var next = -1
+		for i := range tables {
+			if placed[i] {
+				continue
+			}
+
+			var ready = true
+			for j := range parents[i] {
+				if !placed[j] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 { // every remaining table is waiting on another; break the cycle at its earliest table
+			for i := range tables {
+				if !placed[i] && inCycle(i, parents, placed) {
+					next = i
+					break
+				}
+			}
+			broken = append(broken, tables[next].Name())
+		}
+
+		placed[next] = true
+		order = append(order, tables[next].Name())
+	}
+
+	if len(broken) > 0 {
+		return order, &CycleError{Tables: broken}
+	}
+
+	return order, nil
+}
+
+// inCycle reports whether table i can reach itself by following references through tables that aren't placed yet
+func inCycle(i int, parents []map[int]bool, placed []bool) bool {
+	var seen = make(map[int]bool)
+	var stack = []int{i}
+	for len(stack) > 0 {
+		var n = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for j := range parents[n] {
+			if j == i {
+				return true
+			}
+
+			if !placed[j] && !seen[j] {
+				seen[j] = true
+				stack = append(stack, j)
+			}
+		}
+	}
+	return false
 }
@@ -1,6 +1,16 @@
 package dotlite
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -28,6 +38,41 @@ func TestOpen(t *testing.T) {
 	if enc := file.Encoding(); enc != UTF8 {
 		t.Errorf("expected encoding to be %d; got %d", UTF8, enc)
 	}
+
+	if name := file.EncodingName(); name != "UTF-8" {
+		t.Errorf("expected encoding name to be %q; got %q", "UTF-8", name)
+	}
+}
+
+func TestFile_header(t *testing.T) {
+	var b = read(t, "testdata/chinook.db")
+	binary.BigEndian.PutUint32(b[60:], 42)         // user version
+	binary.BigEndian.PutUint32(b[68:], 0x5bad1dea) // application id
+
+	var file, err = OpenBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := file.UserVersion(); v != 42 {
+		t.Errorf("expected user version to be %d; got %d", 42, v)
+	}
+
+	if id := file.ApplicationID(); id != 0x5bad1dea {
+		t.Errorf("expected application id to be %#x; got %#x", 0x5bad1dea, id)
+	}
+
+	if format := file.SchemaFormat(); format != 4 {
+		t.Errorf("expected schema format to be %d; got %d", 4, format)
+	}
+
+	if counter := file.ChangeCounter(); counter != 31279 {
+		t.Errorf("expected change counter to be %d; got %d", 31279, counter)
+	}
+
+	if reserved := file.ReservedBytes(); reserved != 0 {
+		t.Errorf("expected no reserved bytes; got %d", reserved)
+	}
 }
 
 func TestOpen_invalid_magic(t *testing.T) {
@@ -36,6 +81,137 @@ func TestOpen_invalid_magic(t *testing.T) {
 	}
 }
 
+func TestOpen_encrypted(t *testing.T) {
+	var random = make([]byte, 4096)
+	rand.New(rand.NewSource(42)).Read(random)
+
+	chinook, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a random salt in place of the magic string, followed by the plaintext header
+	var salted = append([]byte{}, chinook...)
+	copy(salted, random[:16])
+
+	for _, test := range []struct {
+		name      string
+		content   []byte
+		encrypted bool
+	}{
+		{"random content", random, true},
+		{"plaintext header", salted, true},
+		{"not a whole number of pages", random[:4000], false},
+		{"text", bytes.Repeat([]byte("not a database\n"), 64), false},
+		{"zeroes", make([]byte, 4096), false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := OpenBytes(test.content)
+			if err == nil {
+				t.Fatalf("expected an error")
+			} else if errors.Is(err, ErrEncrypted) != test.encrypted {
+				t.Errorf("expected ErrEncrypted to be %v; got %v", test.encrypted, err)
+			}
+		})
+	}
+}
+
+func TestOpen_truncated_header(t *testing.T) {
+	// only the first 50 bytes of the header are present
+	if _, err := Open("testdata/truncated-header.db"); !errors.Is(err, ErrTruncatedHeader) {
+		t.Errorf("expected truncated header error; got %v", err)
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	var b, err = os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the header needs to be present
+	var header *Header
+	if header, err = ReadHeader(bytes.NewReader(b[:100])); err != nil {
+		t.Fatal(err)
+	}
+
+	if sz := header.pageSize(); sz != 1024 {
+		t.Errorf("expected page size to be %d; got %d", 1024, sz)
+	}
+
+	if ver := header.LibraryVersion; ver != 3041000 {
+		t.Errorf("expected library version to be %d; got %d", 3041000, ver)
+	}
+
+	if _, err = ReadHeader(bytes.NewReader(b[:50])); !errors.Is(err, ErrTruncatedHeader) {
+		t.Errorf("expected truncated header error; got %v", err)
+	}
+
+	b[0] = 'X'
+	if _, err = ReadHeader(bytes.NewReader(b)); err == nil {
+		t.Errorf("expected invalid magic error")
+	}
+}
+
+func TestOpenReaderAt(t *testing.T) {
+	var b, err = os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenReaderAt(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if pages := file.NumPages(); pages != len(b)/1024 {
+		t.Errorf("expected %d pages; got %d", len(b)/1024, pages)
+	} else if err = file.VerifySize(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var rows int
+	if err = file.ForEach("Track", func(*Record) error { rows++; return nil }); err != nil {
+		t.Fatal(err)
+	} else if rows != 3503 {
+		t.Errorf("expected 3503 rows in Track; got %d", rows)
+	}
+
+	if file.HasHotJournal() {
+		t.Errorf("expected no hot journal")
+	}
+
+	// only the given size is read, even if the reader holds more
+	if _, err = OpenReaderAt(bytes.NewReader(b), 50); !errors.Is(err, ErrTruncatedHeader) {
+		t.Errorf("expected truncated header error; got %v", err)
+	}
+}
+
+func TestOpenBytes(t *testing.T) {
+	var b, err = os.ReadFile("testdata/indexes.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := OpenBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	objects, err := file.Schema()
+	if err != nil {
+		t.Fatal(err)
+	} else if len(objects) == 0 {
+		t.Errorf("expected objects in the schema")
+	}
+
+	if _, err = OpenBytes(nil); !errors.Is(err, ErrTruncatedHeader) {
+		t.Errorf("expected truncated header error; got %v", err)
+	}
+}
+
 func TestOpen_size_is_computed(t *testing.T) {
 	// 4 bytes starting at position 28 are zeroed
 	var file = open(t, "testdata/chinook-no-size.db")
@@ -46,6 +222,41 @@ func TestOpen_size_is_computed(t *testing.T) {
 	}
 }
 
+func TestVerifySize(t *testing.T) {
+	for _, name := range []string{"testdata/chinook.db", "testdata/chinook-no-size.db"} {
+		var file = open(t, name)
+		if err := file.VerifySize(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+		_ = file.Close()
+	}
+
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pageSize = 1024
+	for desc, content := range map[string][]byte{
+		"truncated":        b[:len(b)-pageSize],
+		"trailing garbage": append(append([]byte{}, b...), make([]byte, pageSize)...),
+		"incomplete page":  b[:len(b)-pageSize/2],
+	} {
+		var name = filepath.Join(t.TempDir(), "copy.db")
+		if err = os.WriteFile(name, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		var file = open(t, name)
+		if err = file.VerifySize(); err == nil {
+			t.Errorf("%s: expected a size mismatch", desc)
+		} else {
+			t.Logf("%s: %v", desc, err)
+		}
+		_ = file.Close()
+	}
+}
+
 func TestSchema(t *testing.T) {
 	var file = open(t, "testdata/chinook.db")
 	defer file.Close()
@@ -60,6 +271,24 @@ func TestSchema(t *testing.T) {
 	}
 }
 
+func TestSchemaFrom(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	objects, err := file.SchemaFrom(1)
+	if err != nil {
+		t.Fatalf("failed to determine schema: %v", err)
+	}
+
+	if tl := len(objects); tl != 23 {
+		t.Errorf("expected %d objects; got %d", 23, tl)
+	}
+
+	if _, err = file.SchemaFrom(file.NumPages() + 1); err == nil {
+		t.Errorf("expected error for a root page past the end of the file")
+	}
+}
+
 func TestSchema_find_table(t *testing.T) {
 	var file = open(t, "testdata/chinook.db")
 	defer file.Close()
@@ -85,3 +314,471 @@ func TestOverflow_database(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestTableOrder(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	order, err := file.TableOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pos = make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+
+	for _, dep := range [][2]string{{"Artist", "Album"}, {"Album", "Track"}, {"Employee", "Customer"}, {"Customer", "Invoice"}, {"Track", "InvoiceLine"}} {
+		if pos[dep[0]] > pos[dep[1]] {
+			t.Errorf("expected %q to be ordered before %q", dep[0], dep[1])
+		}
+	}
+}
+
+func TestTableOrder_cycle(t *testing.T) {
+	var file = open(t, "testdata/foreign-keys.db")
+	defer file.Close()
+
+	order, err := file.TableOrder()
+
+	var cycle *CycleError
+	if !errors.As(err, &cycle) {
+		t.Fatalf("expected a cycle error; got %v", err)
+	}
+
+	if expected := []string{"department"}; !reflect.DeepEqual(cycle.Tables, expected) {
+		t.Errorf("expected cycle to be broken at %v; got %v", expected, cycle.Tables)
+	}
+
+	if expected := []string{"customer", "invoice", "department", "employee", "badge"}; !reflect.DeepEqual(order, expected) {
+		t.Errorf("expected order to be %v; got %v", expected, order)
+	}
+}
+
+func TestWalk_stop_iteration(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	var seen int
+	var err = NewTree(file, file.Pager, 1).Walk(func(*Cell) error {
+		if seen++; seen == 2 {
+			return ErrStopIteration
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Errorf("expected ErrStopIteration to end the walk without error; got %v", err)
+	} else if seen != 2 {
+		t.Errorf("expected walk to stop after %d cells; got %d", 2, seen)
+	}
+}
+
+func TestSameSchema(t *testing.T) {
+	var chinook = open(t, "testdata/chinook.db")
+	defer chinook.Close()
+
+	if cookie, format := chinook.SchemaVersion(); cookie != 0x41 || format < 1 || format > 4 {
+		t.Errorf("unexpected schema version: cookie=%d format=%d", cookie, format)
+	}
+
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name = filepath.Join(t.TempDir(), "copy.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var copied = open(t, name)
+	defer copied.Close()
+
+	if !SameSchema(chinook, copied) {
+		t.Errorf("expected copies of the same database to have the same schema")
+	}
+
+	var older = open(t, "testdata/chinook-no-size.db") // same tables, but a different schema cookie
+	defer older.Close()
+
+	if SameSchema(chinook, older) {
+		t.Errorf("expected schema cookies to differ")
+	}
+}
+
+func TestValidateRootPages(t *testing.T) {
+	var chinook = open(t, "testdata/chinook.db")
+	defer chinook.Close()
+
+	if largest := chinook.LargestRootPage(); largest != 0 {
+		t.Errorf("expected no largest root page without auto-vacuum; got %d", largest)
+	} else if errs := chinook.ValidateRootPages(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	var vacuum = open(t, "testdata/auto-vacuum.db")
+	defer vacuum.Close()
+
+	if largest := vacuum.LargestRootPage(); largest != 5 {
+		t.Errorf("expected largest root page to be %d; got %d", 5, largest)
+	} else if errs := vacuum.ValidateRootPages(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	if vacuum.IncrementalVacuum() {
+		t.Errorf("expected a full auto-vacuum database")
+	}
+
+	b, err := os.ReadFile("testdata/auto-vacuum.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[55] = 4 // lower the largest root page below the root of index b_y
+
+	var name = filepath.Join(t.TempDir(), "corrupt.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var corrupt = open(t, name)
+	defer corrupt.Close()
+
+	if errs := corrupt.ValidateRootPages(); len(errs) != 1 {
+		t.Errorf("expected 1 violation; got %v", errs)
+	} else {
+		t.Logf("violation: %v", errs[0])
+	}
+}
+
+func TestOpen_incremental_vacuum(t *testing.T) {
+	// rows 21..40 were deleted and the freelist was only partially vacuumed
+	var file = open(t, "testdata/incremental-vacuum.db")
+	defer file.Close()
+
+	if !file.IncrementalVacuum() {
+		t.Errorf("expected an incremental-vacuum database")
+	}
+
+	if largest := file.LargestRootPage(); largest != 4 {
+		t.Errorf("expected largest root page to be %d; got %d", 4, largest)
+	} else if errs := file.ValidateRootPages(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	if pages, free := file.NumPages(), file.Header.TotalFreePages; pages != 65 || free != 29 {
+		t.Errorf("expected %d pages of which %d are free; got %d pages of which %d are free", 65, 29, pages, free)
+	}
+
+	for _, name := range []string{"t", "t_body"} {
+		var rows int
+		if err := file.ForEach(name, func(*Record) error { rows++; return nil }); err != nil {
+			t.Error(err)
+		} else if rows != 20 {
+			t.Errorf("%s: expected %d rows; got %d", name, 20, rows)
+		}
+	}
+}
+
+func TestRefreshSchemaCookie(t *testing.T) {
+	b, err := os.ReadFile("testdata/chinook.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var name = filepath.Join(t.TempDir(), "copy.db")
+	if err = os.WriteFile(name, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var file = open(t, name)
+	defer file.Close()
+
+	if changed, err := file.RefreshSchemaCookie(); err != nil || changed {
+		t.Fatalf("expected schema cookie to be unchanged; got changed=%v err=%v", changed, err)
+	}
+
+	// simulate a writer bumping the schema cookie
+	w, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.WriteAt([]byte{0, 0, 0, 0x42}, 40); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+
+	if changed, err := file.RefreshSchemaCookie(); err != nil || !changed {
+		t.Fatalf("expected schema cookie to have changed; got changed=%v err=%v", changed, err)
+	}
+
+	if cookie, _ := file.SchemaVersion(); cookie != 0x42 {
+		t.Errorf("expected schema cookie to be %d; got %d", 0x42, cookie)
+	}
+
+	if changed, err := file.RefreshSchemaCookie(); err != nil || changed {
+		t.Errorf("expected schema cookie to be unchanged since the last refresh; got changed=%v err=%v", changed, err)
+	}
+
+	// testdata/schema-change-after.db is testdata/schema-change-before.db after sqlite ran
+	// CREATE TABLE b(y TEXT); INSERT INTO b VALUES ('one'), ('two'); growing the file from 2 to 3 pages
+	before, err := os.ReadFile("testdata/schema-change-before.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := os.ReadFile("testdata/schema-change-after.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name = filepath.Join(t.TempDir(), "grow.db")
+	if err = os.WriteFile(name, before, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var grown = open(t, name)
+	defer grown.Close()
+
+	if err = os.WriteFile(name, after, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := grown.RefreshSchemaCookie(); err != nil || !changed {
+		t.Fatalf("expected schema cookie to have changed; got changed=%v err=%v", changed, err)
+	} else if grown.NumPages() != 3 {
+		t.Errorf("expected the file to have grown to %d pages; got %d", 3, grown.NumPages())
+	}
+
+	if got := rows(t, grown, "b"); !reflect.DeepEqual(got, [][]string{{"one"}, {"two"}}) {
+		t.Errorf("unexpected rows in b: %v", got)
+	}
+}
+
+func TestCatalogByTable(t *testing.T) {
+	var file = open(t, "testdata/indexes.db")
+	defer file.Close()
+
+	catalog, err := file.CatalogByTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = map[string][]string{
+		"person":     {"person_name_age", "person_email"},
+		"membership": {"membership_role"},
+	}
+
+	if len(catalog) != len(expected) {
+		t.Fatalf("expected %d tables; got %d", len(expected), len(catalog))
+	}
+
+	for name, indexes := range expected {
+		var entry, ok = catalog[name]
+		if !ok {
+			t.Errorf("table %q not found in catalog", name)
+			continue
+		}
+
+		if entry.Table.Name() != name || len(entry.Columns) != 4 {
+			t.Errorf("%s: unexpected table %q with %d columns", name, entry.Table.Name(), len(entry.Columns))
+		}
+
+		var names []string
+		for _, index := range entry.Indexes {
+			names = append(names, index.Name())
+			if index.TableName() != name {
+				t.Errorf("%s: index %q belongs to %q", name, index.Name(), index.TableName())
+			}
+		}
+
+		if !reflect.DeepEqual(names, indexes) {
+			t.Errorf("%s: expected indexes %v; got %v", name, indexes, names)
+		}
+	}
+}
+
+func TestFile_concurrent(t *testing.T) {
+	file, err := Open("testdata/chinook.db", WithPageCache(64))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// row counts as reported by sqlite
+	var counts = map[string]int{"Track": 3503, "InvoiceLine": 2240, "PlaylistTrack": 8715, "IFK_TrackAlbumId": 3503}
+
+	shared, err := file.Object("Track") // the same object, shared by several goroutines
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var errs = make(chan error, 2*len(counts))
+	var walk = func(name string, obj *Object) {
+		defer wg.Done()
+
+		var rows int
+		var err = obj.ForEachMap(func(map[string]any) error { rows++; return nil })
+		if err == nil && rows != counts[name] {
+			err = fmt.Errorf("expected %d rows in %s; got %d", counts[name], name, rows)
+		}
+		errs <- err
+	}
+
+	for name := range counts {
+		obj, err := file.Object(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(2)
+		go walk(name, obj)
+		go walk("Track", shared)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestFile_Indexes(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	for _, test := range []struct {
+		table    string
+		expected []string
+	}{
+		{"Track", []string{"IFK_TrackAlbumId", "IFK_TrackGenreId", "IFK_TrackMediaTypeId"}},
+		{"playlisttrack", []string{"sqlite_autoindex_PlaylistTrack_1", "IFK_PlaylistTrackTrackId"}},
+		{"Artist", nil},
+		{"NoSuchTable", nil},
+	} {
+		var indexes, err = file.Indexes(test.table)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, index := range indexes {
+			names = append(names, index.Name())
+		}
+
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("expected indexes of %q to be %v; got %v", test.table, test.expected, names)
+		}
+	}
+
+	var indexes, _ = file.Indexes("PlaylistTrack")
+	if sql := indexes[0].SQL(); sql != "" {
+		t.Errorf("expected automatic index to have no sql; got %q", sql)
+	}
+}
+
+func TestFile_Schema_views_and_triggers(t *testing.T) {
+	var file = open(t, "testdata/views.db")
+	defer file.Close()
+
+	objects, err := file.Schema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, obj := range objects {
+		types = append(types, obj.Type()+" "+obj.Name())
+	}
+
+	var expected = []string{"table Album", "table Log", "view AlbumTitles", "trigger AlbumInserted"}
+	if !reflect.DeepEqual(types, expected) {
+		t.Fatalf("expected objects %v; got %v", expected, types)
+	}
+
+	for _, obj := range objects[2:] {
+		if !strings.HasPrefix(obj.SQL(), "CREATE "+strings.ToUpper(obj.Type())) {
+			t.Errorf("expected sql of %s; got %q", obj.Name(), obj.SQL())
+		}
+
+		if err = obj.ForEach(func(*Record) error { return nil }); err == nil || !strings.Contains(err.Error(), "has no rows") {
+			t.Errorf("expected %s to have no rows; got %v", obj.Name(), err)
+		}
+
+		if err = obj.Slice(0, 1, func(*Record) error { return nil }); err == nil {
+			t.Errorf("expected slicing %s to fail", obj.Name())
+		}
+	}
+
+	if table := objects[3].TableName(); table != "Album" {
+		t.Errorf("expected trigger to be on %q; got %q", "Album", table)
+	}
+
+	if errs := file.CheckIntegrity(); len(errs) != 0 {
+		t.Errorf("expected no integrity errors; got %v", errs)
+	}
+
+	if _, err = file.PageMap(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFile_Sequences(t *testing.T) {
+	var file = open(t, "testdata/autoincrement.db")
+	defer file.Close()
+
+	sequences, err := file.Sequences()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := map[string]int64{"event": 4}; !reflect.DeepEqual(sequences, expected) {
+		t.Errorf("expected sequences %v; got %v", expected, sequences)
+	}
+
+	// chinook has no AUTOINCREMENT table
+	var chinook = open(t, "testdata/chinook.db")
+	defer chinook.Close()
+
+	if sequences, err = chinook.Sequences(); err != nil {
+		t.Fatal(err)
+	} else if len(sequences) != 0 {
+		t.Errorf("expected no sequences; got %v", sequences)
+	}
+
+	// a damaged sqlite_sequence (on page 3) is an error, not an absent one
+	var b = read(t, "testdata/autoincrement.db")
+	b[2*4096] = 0
+
+	damaged, err := OpenBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = damaged.Sequences(); !errors.Is(err, ErrCorruptPage) {
+		t.Errorf("expected a corrupt page error; got %v", err)
+	}
+
+	event, err := damaged.Object("event")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err = event.Sequence(); !errors.Is(err, ErrCorruptPage) {
+		t.Errorf("expected a corrupt page error; got %v", err)
+	}
+}
+
+func TestFile_Object_not_found(t *testing.T) {
+	var file = open(t, "testdata/chinook.db")
+	defer file.Close()
+
+	if _, err := file.Object("NoSuchTable"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound; got %v", err)
+	}
+}
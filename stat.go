@@ -0,0 +1,106 @@
+package dotlite
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stat1Entry is a row of sqlite_stat1, as written by ANALYZE: the estimated cardinality of a table or an index.
+//
+// see: https://www.sqlite.org/fileformat.html#the_sqlite_stat1_table
+type Stat1Entry struct {
+	Table string // table the statistics are about
+	Index string // index the statistics are about; empty for the entry of a table without any index
+
+	Rows int64 // approximate number of rows in the table (and so in the index)
+
+	// Distinct holds, for each prefix of the index's columns (its first column, its first two columns, and so on),
+	// the average number of rows sharing the same values for those columns. It is empty for the entry of a table.
+	Distinct []int64
+
+	// Options holds the keywords following the numbers of the stat, such as "unordered" or "sz=N"
+	Options []string
+}
+
+// Stat1 reads the sqlite_stat1 table, keying the entries by the name of their index, or by the name of their table
+// for a table without any index. If the database was never analyzed, and so has no sqlite_stat1, the map is empty.
+func (f *File) Stat1() (_ map[string]Stat1Entry, err error) {
+	var entries = make(map[string]Stat1Entry)
+
+	var stat *Object
+	if stat, err = f.Object("sqlite_stat1"); errors.Is(err, ErrNotFound) {
+		return entries, nil // sqlite_stat1 doesn't exist until the database is analyzed
+	} else if err != nil {
+		return nil, err
+	}
+
+	err = stat.ForEach(func(rec *Record) (err error) {
+		var entry Stat1Entry
+		if entry.Table, err = rec.AsString(0); err != nil {
+			return err
+		}
+
+		if entry.Index, _, err = rec.AsStringOr(1); err != nil {
+			return err
+		}
+
+		var s string
+		if s, err = rec.AsString(2); err != nil {
+			return err
+		} else if err = entry.parse(s); err != nil {
+			return fmt.Errorf("sqlite_stat1 entry of %q: %w", entry.key(), err)
+		}
+
+		entries[entry.key()] = entry
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// key returns the key of the entry in the map returned by Stat1
+func (e *Stat1Entry) key() string {
+	if e.Index != "" {
+		return e.Index
+	}
+	return e.Table
+}
+
+// parse parses the stat column: a list of integers, followed by optional keywords
+func (e *Stat1Entry) parse(stat string) error {
+	var fields = strings.Fields(stat)
+	if len(fields) == 0 {
+		return fmt.Errorf("stat is empty")
+	}
+
+	var i int
+	for ; i < len(fields); i++ {
+		var n, err = strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			break
+		}
+
+		if i == 0 {
+			e.Rows = n
+		} else {
+			e.Distinct = append(e.Distinct, n)
+		}
+	}
+
+	if i == 0 {
+		return fmt.Errorf("stat %q doesn't start with a row count", stat)
+	}
+
+	e.Options = fields[i:]
+	if len(e.Options) == 0 {
+		e.Options = nil
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFile_Stat1(t *testing.T) {
+	var file = open(t, "testdata/analyzed.db")
+	defer file.Close()
+
+	stats, err := file.Stat1()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = map[string]Stat1Entry{
+		"Tag":      {Table: "Tag", Rows: 3},
+		"ItemName": {Table: "Item", Index: "ItemName", Rows: 1234, Distinct: []int64{1}},
+	}
+
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected stats %+v; got %+v", expected, stats)
+	}
+
+	// chinook was never analyzed
+	var chinook = open(t, "testdata/chinook.db")
+	defer chinook.Close()
+
+	if stats, err = chinook.Stat1(); err != nil {
+		t.Fatal(err)
+	} else if len(stats) != 0 {
+		t.Errorf("expected no stats; got %+v", stats)
+	}
+}
+
+func TestStat1Entry_parse(t *testing.T) {
+	for _, test := range []struct {
+		stat     string
+		expected Stat1Entry
+		err      bool
+	}{
+		{"2000", Stat1Entry{Rows: 2000}, false},
+		{"2000 10 1", Stat1Entry{Rows: 2000, Distinct: []int64{10, 1}}, false},
+		{"2000 10 unordered sz=12", Stat1Entry{Rows: 2000, Distinct: []int64{10}, Options: []string{"unordered", "sz=12"}}, false},
+		{"", Stat1Entry{}, true},
+		{"unordered", Stat1Entry{}, true},
+	} {
+		var entry Stat1Entry
+		if err := entry.parse(test.stat); (err != nil) != test.err {
+			t.Errorf("%q: unexpected error %v", test.stat, err)
+		} else if !test.err && !reflect.DeepEqual(entry, test.expected) {
+			t.Errorf("%q: expected %+v; got %+v", test.stat, test.expected, entry)
+		}
+	}
+}
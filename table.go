@@ -0,0 +1,676 @@
+package dotlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Column represents an individual column defined in a table's schema
+type Column struct {
+	Name     string   // column's name
+	Type     string   // column's declared type; empty if no type was declared
+	Affinity Affinity // column's type affinity, determined from the declared type
+
+	NotNull       bool // is there a NOT NULL constraint?
+	PrimaryKey    bool // is the column part of the table's primary key?
+	AutoIncrement bool // is this an auto-incrementing (INTEGER PRIMARY KEY AUTOINCREMENT) column?
+
+	DefaultExpr string // DEFAULT expression used to generate a default value; empty if there isn't one
+	Collate     string // name of the collation sequence used by the column; empty if none was declared
+	CheckExpr   string // expression of the CHECK constraint(s) defined inline on the column
+}
+
+// ForeignKey represents a foreign key constraint defined either inline on a column or on the table itself
+type ForeignKey struct {
+	Columns           []string // columns in this table that are part of the foreign key
+	ReferencedTable   string   // name of the referenced (parent) table
+	ReferencedColumns []string // columns in the referenced table; empty if the parent's primary key is implied
+}
+
+// tableSchema holds information parsed from a CREATE TABLE statement
+type tableSchema struct {
+	name         string
+	columns      []*Column
+	foreignKeys  []*ForeignKey
+	checks       []*check // CHECK constraints defined on the columns or the table
+	primaryKey   []string // columns that make up the primary key, if one is declared
+	withoutRowid bool     // is it a WITHOUT ROWID table?
+
+	rowid  int  // position of the column that aliases the rowid; -1 if there isn't one
+	pkDesc bool // true if an inline primary key was declared as DESC
+}
+
+// parseTable parses the CREATE TABLE statement in sql.
+//
+// It is not a complete implementation of sqlite's grammar and only understands as much of
+// the statement as is needed to describe the table's columns and constraints.
+// see: https://www.sqlite.org/lang_createtable.html
+func parseTable(sql string) (_ *tableSchema, err error) {
+	var tokens []token
+	if tokens, err = tokenize(sql); err != nil {
+		return nil, err
+	}
+
+	var p = &parser{tokens: tokens}
+	if !p.keyword("CREATE") {
+		return nil, fmt.Errorf("invalid table schema: expected CREATE")
+	}
+	_ = p.keyword("TEMP") || p.keyword("TEMPORARY")
+	if !p.keyword("TABLE") {
+		return nil, fmt.Errorf("invalid table schema: expected TABLE")
+	}
+	if p.keyword("IF") && !(p.keyword("NOT") && p.keyword("EXISTS")) {
+		return nil, fmt.Errorf("invalid table schema: malformed IF NOT EXISTS clause")
+	}
+
+	var table = &tableSchema{}
+	if table.name, err = p.name(); err != nil {
+		return nil, err
+	}
+
+	if p.op(".") { // name was qualified with the schema name
+		if table.name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+
+	if !p.op("(") {
+		return nil, fmt.Errorf("invalid table schema: expected column definitions for %q", table.name)
+	}
+
+	// each element in the definition is either a column definition or a table constraint
+	for {
+		var def = p.element()
+		if len(def) == 0 {
+			return nil, fmt.Errorf("invalid table schema: empty definition in %q", table.name)
+		}
+
+		if err = table.define(def); err != nil {
+			return nil, err
+		}
+
+		if p.op(",") {
+			continue
+		} else if p.op(")") {
+			break
+		}
+
+		return nil, fmt.Errorf("invalid table schema: unterminated definition of %q", table.name)
+	}
+
+	// table options follow the definition; see: https://www.sqlite.org/lang_createtable.html#table_options
+	for !p.done() {
+		if p.keyword("WITHOUT") && p.keyword("ROWID") {
+			table.withoutRowid = true
+		} else if !p.keyword("STRICT") && !p.op(",") && !p.op(";") {
+			return nil, fmt.Errorf("invalid table schema: unexpected %q after definition of %q", p.peek().text, table.name)
+		}
+	}
+
+	for _, col := range table.columns {
+		for _, name := range table.primaryKey {
+			if strings.EqualFold(col.Name, name) {
+				col.PrimaryKey = true
+			}
+		}
+	}
+
+	// a single-column INTEGER primary key on a rowid table becomes an alias for the rowid
+	// see: https://www.sqlite.org/lang_createtable.html#rowid
+	table.rowid = -1
+	if !table.withoutRowid && len(table.primaryKey) == 1 && !table.pkDesc {
+		for i, col := range table.columns {
+			if strings.EqualFold(col.Name, table.primaryKey[0]) && strings.EqualFold(col.Type, "INTEGER") {
+				table.rowid = i
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// storageOrder returns, for every column, the position of its value in the table's records, or nil if
+// values are stored in the order the columns are declared. That is the case for all tables except WITHOUT ROWID
+// tables, which store the primary key columns first (in the order of the primary key) followed by the others.
+//
+// see: https://www.sqlite.org/withoutrowid.html
+func (table *tableSchema) storageOrder() []int {
+	if !table.withoutRowid || len(table.primaryKey) == 0 {
+		return nil
+	}
+
+	var order = make([]int, len(table.columns))
+	var stored = make([]bool, len(table.columns))
+	var n = 0
+	for _, name := range table.primaryKey {
+		for i, col := range table.columns {
+			if strings.EqualFold(col.Name, name) && !stored[i] { // a column repeated in the primary key is only stored once
+				order[i], stored[i] = n, true
+				n++
+			}
+		}
+	}
+
+	for i := range table.columns {
+		if !stored[i] {
+			order[i] = n
+			n++
+		}
+	}
+
+	return order
+}
+
+// define adds the column or table constraint described by tokens to the table
+func (table *tableSchema) define(tokens []token) (err error) {
+	var p = &parser{tokens: tokens}
+
+	var constraint string // name of the constraint, if one is given
+	if p.keyword("CONSTRAINT") {
+		if constraint, err = p.name(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case p.keyword("PRIMARY"):
+		if !p.keyword("KEY") {
+			return fmt.Errorf("invalid table schema: expected PRIMARY KEY")
+		}
+
+		table.primaryKey, err = p.names()
+		return err
+
+	case p.keyword("UNIQUE"):
+		return nil
+
+	case p.keyword("CHECK"):
+		var expr string
+		if expr, err = p.group(); err != nil {
+			return err
+		}
+
+		table.checks = append(table.checks, &check{name: constraint, expr: expr})
+		return nil
+
+	case p.keyword("FOREIGN"):
+		if !p.keyword("KEY") {
+			return fmt.Errorf("invalid table schema: expected FOREIGN KEY")
+		}
+
+		var fk = &ForeignKey{}
+		if fk.Columns, err = p.names(); err != nil {
+			return err
+		}
+
+		if !p.keyword("REFERENCES") {
+			return fmt.Errorf("invalid table schema: expected REFERENCES")
+		}
+
+		if err = p.references(fk); err != nil {
+			return err
+		}
+
+		table.foreignKeys = append(table.foreignKeys, fk)
+		return nil
+	}
+
+	p.pos = 0 // it's a column definition; start over
+	var column = &Column{}
+	if column.Name, err = p.name(); err != nil {
+		return err
+	}
+
+	// the declared type is a sequence of names, optionally followed by one or two numeric arguments
+	var typ []string
+	for !p.done() && p.peek().kind == tokenIdent && !isConstraintKeyword(p.peek()) {
+		typ = append(typ, p.next().text)
+	}
+	if len(typ) > 0 && p.peek().is("(") {
+		var start = p.peek().pos
+		p.skip()
+		typ[len(typ)-1] += p.source(start)
+	}
+	column.Type = strings.Join(typ, " ")
+	column.Affinity = AffinityOf(column.Type)
+
+	for constraint = ""; !p.done(); {
+		switch {
+		case p.keyword("CONSTRAINT"):
+			if constraint, err = p.name(); err != nil {
+				return err
+			}
+			continue
+
+		case p.keyword("PRIMARY"):
+			if !p.keyword("KEY") {
+				return fmt.Errorf("invalid table schema: expected PRIMARY KEY")
+			}
+
+			table.primaryKey = []string{column.Name}
+			table.pkDesc = p.keyword("DESC")
+
+		case p.keyword("CHECK"):
+			var expr string
+			if expr, err = p.group(); err != nil {
+				return err
+			}
+
+			table.checks = append(table.checks, &check{name: constraint, expr: expr})
+			if column.CheckExpr == "" {
+				column.CheckExpr = expr
+			} else {
+				column.CheckExpr = "(" + column.CheckExpr + ") AND (" + expr + ")"
+			}
+
+		case p.keyword("AUTOINCREMENT"):
+			column.AutoIncrement = true
+
+		case p.keyword("NOT"):
+			if !p.keyword("NULL") {
+				return fmt.Errorf("invalid table schema: expected NOT NULL")
+			}
+			column.NotNull = true
+
+		case p.keyword("DEFAULT"):
+			if p.peek().is("(") {
+				if column.DefaultExpr, err = p.group(); err != nil {
+					return err
+				}
+			} else {
+				var start = p.peek().pos
+				if p.op("-") || p.op("+") { // signed numeric literal
+					p.next()
+				} else if p.done() {
+					return fmt.Errorf("invalid table schema: expected a DEFAULT value")
+				} else {
+					p.next()
+				}
+				column.DefaultExpr = p.source(start)
+			}
+
+		case p.keyword("COLLATE"):
+			if column.Collate, err = p.name(); err != nil {
+				return err
+			}
+
+		case p.keyword("REFERENCES"):
+			var fk = &ForeignKey{Columns: []string{column.Name}}
+			if err = p.references(fk); err != nil {
+				return err
+			}
+
+			table.foreignKeys = append(table.foreignKeys, fk)
+
+		default:
+			p.skip()
+		}
+
+		constraint = ""
+	}
+
+	table.columns = append(table.columns, column)
+	return nil
+}
+
+// isConstraintKeyword reports whether tok starts a column constraint, thereby terminating the declared type
+func isConstraintKeyword(tok token) bool {
+	for _, kw := range []string{"CONSTRAINT", "PRIMARY", "NOT", "NULL", "UNIQUE", "CHECK", "DEFAULT", "COLLATE", "REFERENCES", "GENERATED", "AS"} {
+		if tok.keyword(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// parser is a simple recursive-descent helper over a slice of tokens
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenEOF}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	var tok = p.peek()
+	if !p.done() {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword consumes the next token if it is the (case-insensitive) keyword kw
+func (p *parser) keyword(kw string) bool {
+	if p.peek().keyword(kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// op consumes the next token if it is the operator / punctuation op
+func (p *parser) op(op string) bool {
+	if p.peek().is(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// name consumes an identifier (or string literal, which sqlite accepts in place of one)
+func (p *parser) name() (string, error) {
+	if tok := p.peek(); tok.kind == tokenIdent || tok.kind == tokenString {
+		p.pos++
+		return tok.text, nil
+	}
+	return "", fmt.Errorf("invalid table schema: expected a name at offset %d", p.peek().pos)
+}
+
+// names consumes a parenthesised, comma-separated list of column names.
+// Any ordering or collation that follows a name is skipped.
+func (p *parser) names() (names []string, err error) {
+	if !p.op("(") {
+		return nil, fmt.Errorf("invalid table schema: expected column list")
+	}
+
+	for {
+		var name string
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+
+		for !p.done() && !p.peek().is(",") && !p.peek().is(")") {
+			p.skip()
+		}
+
+		if p.op(")") {
+			return names, nil
+		} else if !p.op(",") {
+			return nil, fmt.Errorf("invalid table schema: unterminated column list")
+		}
+	}
+}
+
+// references parses a foreign-key-clause, following the REFERENCES keyword, into fk
+func (p *parser) references(fk *ForeignKey) (err error) {
+	if fk.ReferencedTable, err = p.name(); err != nil {
+		return err
+	}
+
+	if p.peek().is("(") {
+		if fk.ReferencedColumns, err = p.names(); err != nil {
+			return err
+		}
+	}
+
+	// skip over ON DELETE / ON UPDATE / MATCH / DEFERRABLE clauses
+	for !p.done() && !isConstraintKeyword(p.peek()) {
+		p.skip()
+	}
+
+	return nil
+}
+
+// skip consumes the next token, or the entire group if the next token opens a parenthesis
+func (p *parser) skip() {
+	if !p.op("(") {
+		p.next()
+		return
+	}
+
+	for depth := 1; !p.done() && depth > 0; {
+		if tok := p.next(); tok.is("(") {
+			depth++
+		} else if tok.is(")") {
+			depth--
+		}
+	}
+}
+
+// group consumes a parenthesised group and returns the source text enclosed within it
+func (p *parser) group() (string, error) {
+	if !p.peek().is("(") {
+		return "", fmt.Errorf("invalid table schema: expected ( at offset %d", p.peek().pos)
+	}
+
+	var start = p.pos
+	if p.skip(); !p.tokens[p.pos-1].is(")") || p.pos-start < 2 {
+		return "", fmt.Errorf("invalid table schema: unterminated ( at offset %d", p.tokens[start].pos)
+	}
+
+	var open, close = p.tokens[start], p.tokens[p.pos-1]
+	return strings.TrimSpace(open.src[open.end:close.pos]), nil
+}
+
+// element consumes tokens up to the next top-level comma or closing parenthesis
+func (p *parser) element() []token {
+	var start = p.pos
+	for !p.done() && !p.peek().is(",") && !p.peek().is(")") {
+		p.skip()
+	}
+	return p.tokens[start:p.pos]
+}
+
+// source returns the original text spanning from the start offset to the end of the last consumed token
+func (p *parser) source(start int) string {
+	if p.pos == 0 {
+		return ""
+	}
+
+	var last = p.tokens[p.pos-1]
+	return last.src[start:last.end]
+}
+
+type tokenKind int
+
+const (
+	tokenEOF    tokenKind = iota
+	tokenIdent            // a keyword or an identifier (bare or quoted)
+	tokenString           // a 'string' literal
+	tokenNumber           // a numeric literal
+	tokenBlob             // a x'hex' blob literal
+	tokenOp               // an operator or punctuation
+)
+
+// token is a lexical token in an sql statement
+type token struct {
+	kind     tokenKind
+	text     string // text of the token; quotes are removed from identifiers and strings
+	quoted   bool   // true if the token is a quoted identifier
+	pos, end int    // offset of the token in the source
+	src      string // source the token was read from
+}
+
+// is reports whether tok is the operator / punctuation op
+func (tok token) is(op string) bool { return tok.kind == tokenOp && tok.text == op }
+
+// keyword reports whether tok is the unquoted keyword kw
+func (tok token) keyword(kw string) bool {
+	return tok.kind == tokenIdent && !tok.quoted && strings.EqualFold(tok.text, kw)
+}
+
+// tokenize splits the sql statement into tokens, dropping any whitespace and comments
+func tokenize(sql string) (tokens []token, err error) {
+	var quoted = func(i int, close byte) (string, int, error) {
+		var sb strings.Builder
+		for j := i + 1; j < len(sql); j++ {
+			if sql[j] == close {
+				if close != ']' && j+1 < len(sql) && sql[j+1] == close { // escaped quote
+					sb.WriteByte(close)
+					j++
+					continue
+				}
+				return sb.String(), j + 1, nil
+			}
+			sb.WriteByte(sql[j])
+		}
+		return "", 0, fmt.Errorf("unterminated quote at offset %d", i)
+	}
+
+	for i := 0; i < len(sql); {
+		var c = sql[i]
+		var tok = token{pos: i, src: sql}
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			if j := strings.IndexByte(sql[i:], '\n'); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(sql)
+			}
+			continue
+
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			if j := strings.Index(sql[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(sql)
+			}
+			continue
+
+		case (c == 'x' || c == 'X') && i+1 < len(sql) && sql[i+1] == '\'':
+			tok.kind = tokenBlob
+			if tok.text, i, err = quoted(i+1, '\''); err != nil {
+				return nil, err
+			}
+
+		case c == '\'':
+			tok.kind = tokenString
+			if tok.text, i, err = quoted(i, '\''); err != nil {
+				return nil, err
+			}
+
+		case c == '"' || c == '`' || c == '[':
+			var close = c
+			if c == '[' {
+				close = ']'
+			}
+
+			tok.kind, tok.quoted = tokenIdent, true
+			if tok.text, i, err = quoted(i, close); err != nil {
+				return nil, err
+			}
+
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			var j = i
+			for j < len(sql) && (isIdentChar(sql[j]) || sql[j] == '.' ||
+				((sql[j] == '+' || sql[j] == '-') && (sql[j-1] == 'e' || sql[j-1] == 'E') && !strings.HasPrefix(strings.ToLower(sql[i:j]), "0x"))) {
+				j++
+			}
+			tok.kind, tok.text, i = tokenNumber, sql[i:j], j
+
+		case isIdentChar(c) || c == '$' || c == '@' || c == ':' || c == '?' || c == '#':
+			var j = i + 1
+			for j < len(sql) && (isIdentChar(sql[j]) || sql[j] == '$') {
+				j++
+			}
+			tok.kind, tok.text, i = tokenIdent, sql[i:j], j
+
+		default:
+			var n = 1
+			for _, op := range []string{"<>", "<=", ">=", "==", "!=", "||", "<<", ">>", "->>", "->"} {
+				if strings.HasPrefix(sql[i:], op) && len(op) > n {
+					n = len(op)
+				}
+			}
+			tok.kind, tok.text, i = tokenOp, sql[i:i+n], i+n
+		}
+
+		tok.end = i
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+// keywords are the sql keywords (that may appear in a CREATE TABLE or CREATE INDEX statement) written in upper case by normalizeSQL
+var keywords = map[string]bool{
+	"ABORT": true, "ACTION": true, "ALWAYS": true, "AND": true, "AS": true, "ASC": true, "AUTOINCREMENT": true,
+	"BETWEEN": true, "CASCADE": true, "CASE": true, "CHECK": true, "COLLATE": true, "CONFLICT": true, "CONSTRAINT": true,
+	"CREATE": true, "DEFAULT": true, "DEFERRABLE": true, "DEFERRED": true, "DELETE": true, "DESC": true, "ELSE": true,
+	"END": true, "EXISTS": true, "FAIL": true, "FOREIGN": true, "GENERATED": true, "GLOB": true, "IF": true, "IGNORE": true,
+	"IMMEDIATE": true, "IN": true, "INDEX": true, "INITIALLY": true, "IS": true, "KEY": true, "LIKE": true, "MATCH": true,
+	"NO": true, "NOT": true, "NULL": true, "ON": true, "OR": true, "PRIMARY": true, "REFERENCES": true, "REPLACE": true,
+	"RESTRICT": true, "ROLLBACK": true, "ROWID": true, "SET": true, "STORED": true, "STRICT": true, "TABLE": true,
+	"TEMP": true, "TEMPORARY": true, "THEN": true, "UNIQUE": true, "UPDATE": true, "VIRTUAL": true, "WHEN": true,
+	"WHERE": true, "WITHOUT": true,
+}
+
+// normalizeSQL renders the sql statement in a canonical form: comments are removed, tokens are separated by
+// a single space (none around punctuation), keywords are upper-cased and a trailing semicolon is dropped.
+// As identifiers (including type names) are case-insensitive in sqlite, they are lower-cased (ASCII only, as sqlite
+// folds case), and only quoted, with double quotes, when they can't be written bare. Statements that differ only
+// in formatting, the case of keywords and identifiers, or the quoting of identifiers normalize to the same string.
+//
+// Double-quoted tokens keep their case though, as sqlite accepts them as string literals too (eg. DEFAULT "Hello")
+// and they can't be told apart from identifiers without resolving names; so "Name" and name normalize differently.
+func normalizeSQL(sql string) (_ string, err error) {
+	var tokens []token
+	if tokens, err = tokenize(sql); err != nil {
+		return "", err
+	}
+
+	if n := len(tokens); n > 0 && tokens[n-1].is(";") {
+		tokens = tokens[:n-1]
+	}
+
+	var sb strings.Builder
+	for i, tok := range tokens {
+		// no space at the start, after an opening parenthesis or a dot, and before punctuation
+		if i > 0 && !tokens[i-1].is("(") && !tokens[i-1].is(".") && !tok.is(",") && !tok.is(")") && !tok.is(".") && !tok.is("(") {
+			sb.WriteByte(' ')
+		}
+
+		switch {
+		case tok.kind == tokenIdent && !tok.quoted && keywords[strings.ToUpper(tok.text)]:
+			sb.WriteString(strings.ToUpper(tok.text))
+		case tok.kind == tokenIdent && tok.src[tok.pos] == '"' && asciiLower(tok.text) != tok.text:
+			sb.WriteString(`"` + strings.ReplaceAll(tok.text, `"`, `""`) + `"`) // maybe a string literal; keep its case
+		case tok.kind == tokenIdent && bareIdent(tok.text):
+			sb.WriteString(asciiLower(tok.text))
+		case tok.kind == tokenIdent:
+			sb.WriteString(`"` + strings.ReplaceAll(asciiLower(tok.text), `"`, `""`) + `"`)
+		case tok.kind == tokenString:
+			sb.WriteString("'" + strings.ReplaceAll(tok.text, "'", "''") + "'")
+		case tok.kind == tokenBlob:
+			sb.WriteString("X'" + strings.ToUpper(tok.text) + "'")
+		default:
+			sb.WriteString(tok.text)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// bareIdent reports whether the identifier can be written without quotes: it isn't a keyword,
+// and is made of identifier characters without starting with a digit
+func bareIdent(s string) bool {
+	if s == "" || isDigit(s[0]) || keywords[strings.ToUpper(s)] {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
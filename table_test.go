@@ -0,0 +1,183 @@
+package dotlite
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTable(t *testing.T) {
+	var table, err = parseTable(`CREATE TABLE IF NOT EXISTS main."order" (
+		id INTEGER PRIMARY KEY, -- the order id
+		[amount] DECIMAL(10, 2) NOT NULL CHECK(amount > 0, 1),
+		customer INTEGER DEFAULT -1 CONSTRAINT fk_customer REFERENCES customer (id) ON DELETE CASCADE,
+		notes DEFAULT 'n/a' COLLATE NOCASE, /* no type */
+		FOREIGN KEY (id, notes) REFERENCES "other table"
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.name != "order" {
+		t.Errorf("expected table name to be %q; got %q", "order", table.name)
+	}
+
+	var columns = []Column{
+		{Name: "id", Type: "INTEGER", Affinity: AffinityInteger, PrimaryKey: true},
+		{Name: "amount", Type: "DECIMAL(10, 2)", Affinity: AffinityNumeric, NotNull: true, CheckExpr: "amount > 0, 1"},
+		{Name: "customer", Type: "INTEGER", Affinity: AffinityInteger, DefaultExpr: "-1"},
+		{Name: "notes", DefaultExpr: "'n/a'", Collate: "NOCASE"},
+	}
+	if len(table.columns) != len(columns) {
+		t.Fatalf("expected %d columns; got %d", len(columns), len(table.columns))
+	}
+
+	for i, col := range table.columns {
+		if *col != columns[i] {
+			t.Errorf("expected column(%d) to be %+v; got %+v", i, columns[i], *col)
+		}
+	}
+
+	var fks = []ForeignKey{
+		{Columns: []string{"customer"}, ReferencedTable: "customer", ReferencedColumns: []string{"id"}},
+		{Columns: []string{"id", "notes"}, ReferencedTable: "other table"},
+	}
+	if len(table.foreignKeys) != len(fks) {
+		t.Fatalf("expected %d foreign keys; got %d", len(fks), len(table.foreignKeys))
+	}
+
+	for i, fk := range table.foreignKeys {
+		if !reflect.DeepEqual(*fk, fks[i]) {
+			t.Errorf("expected foreign key(%d) to be %+v; got %+v", i, fks[i], *fk)
+		}
+	}
+}
+
+func TestParseTable_parenthesized(t *testing.T) {
+	// commas and parenthesis within types, constraints and quoted names must not split columns
+	var table, err = parseTable("CREATE TABLE t (" +
+		`"first, name" VARCHAR(20, 1) NOT NULL, ` +
+		"`a``b` DOUBLE PRECISION CHECK(a > 0 AND (b < 1)), " +
+		`[c d] UNSIGNED BIG INT, e, ` +
+		`CHECK(a > 0, b < 1), UNIQUE (e, [c d]))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var columns = []Column{
+		{Name: "first, name", Type: "VARCHAR(20, 1)", Affinity: AffinityText, NotNull: true},
+		{Name: "a`b", Type: "DOUBLE PRECISION", Affinity: AffinityReal, CheckExpr: "a > 0 AND (b < 1)"},
+		{Name: "c d", Type: "UNSIGNED BIG INT", Affinity: AffinityInteger},
+		{Name: "e", Affinity: AffinityBlob},
+	}
+	if len(table.columns) != len(columns) {
+		t.Fatalf("expected %d columns; got %d", len(columns), len(table.columns))
+	}
+
+	for i, col := range table.columns {
+		if *col != columns[i] {
+			t.Errorf("expected column(%d) to be %+v; got %+v", i, columns[i], *col)
+		}
+	}
+}
+
+func TestParseTable_invalid(t *testing.T) {
+	for _, sql := range []string{
+		"CREATE INDEX x ON y(z)",
+		"CREATE TABLE x",
+		"CREATE TABLE x (a INT",
+		"CREATE TABLE x (a INT, 'b)",
+	} {
+		if _, err := parseTable(sql); err == nil {
+			t.Errorf("expected %q to fail to parse", sql)
+		}
+	}
+}
+
+func TestParseTable_constraints(t *testing.T) {
+	var table, err = parseTable(`CREATE TABLE [Album] (
+		[AlbumId] INTEGER NOT NULL,
+		[Created] TEXT DEFAULT (datetime('now')) NOT NULL,
+		CONSTRAINT [PK_Album] PRIMARY KEY ([AlbumId])
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if col := table.columns[0]; !col.PrimaryKey || !col.NotNull {
+		t.Errorf("expected %q to be a NOT NULL primary key", col.Name)
+	}
+
+	if col := table.columns[1]; col.DefaultExpr != "datetime('now')" || !col.NotNull || col.PrimaryKey {
+		t.Errorf("unexpected column definition: %+v", *col)
+	}
+
+	if table.rowid != 0 {
+		t.Errorf("expected column 0 to alias the rowid; got %d", table.rowid)
+	}
+}
+
+func TestNormalizeSQL(t *testing.T) {
+	var a, err = normalizeSQL(`create table "t" (
+		id integer primary key, -- the id
+		[name] text not null default 'it''s', /* comment */
+		price DECIMAL(10,2)
+	);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b string
+	if b, err = normalizeSQL("CREATE TABLE `t`(id integer PRIMARY KEY,\"name\" text NOT NULL DEFAULT 'it''s',price DECIMAL ( 10 , 2 ))"); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = `CREATE TABLE t(id integer PRIMARY KEY, name text NOT NULL DEFAULT 'it''s', price decimal(10, 2))`
+	if a != expected {
+		t.Errorf("expected %q; got %q", expected, a)
+	}
+
+	if a != b {
+		t.Errorf("expected both statements to normalize to the same sql; got %q and %q", a, b)
+	}
+
+	// identifiers and type names are case-insensitive; only identifiers that can't be written bare stay quoted
+	for _, test := range []struct{ a, b, expected string }{
+		{`CREATE TABLE Person(ID INTEGER, Name TEXT)`, `create table "person"("id" integer, [name] text)`, `CREATE TABLE person(id integer, name text)`},
+		{`CREATE TABLE "my table"("key" VARCHAR(10))`, "create table [my table](`KEY` varchar(10))", `CREATE TABLE "my table"("key" varchar(10))`},
+		{`CREATE INDEX "1idx" ON t(a)`, `create index [1IDX] on T(A)`, `CREATE INDEX "1idx" ON t(a)`},
+	} {
+		var a, err = normalizeSQL(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b string
+		if b, err = normalizeSQL(test.b); err != nil {
+			t.Fatal(err)
+		}
+
+		if a != test.expected || b != test.expected {
+			t.Errorf("expected %q; got %q and %q", test.expected, a, b)
+		}
+	}
+
+	// double-quoted tokens may be string literals, so their case is kept
+	for _, test := range []struct{ a, b string }{
+		{`CREATE TABLE t(a TEXT DEFAULT "Hello")`, `CREATE TABLE t(a TEXT DEFAULT "hello")`},
+		{`CREATE TABLE t(a TEXT CHECK(a IN ("A", "B")))`, `CREATE TABLE t(a TEXT CHECK(a IN ("a", "b")))`},
+	} {
+		var a, err = normalizeSQL(test.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var b string
+		if b, err = normalizeSQL(test.b); err != nil {
+			t.Fatal(err)
+		}
+
+		if a == b {
+			t.Errorf("expected %q and %q to normalize differently; got %q", test.a, test.b, a)
+		}
+	}
+}
@@ -29,3 +29,54 @@ func TestVarint(t *testing.T) {
 	// error cases
 	ve(t, []byte{0b1000_0000})
 }
+
+// putVarint encodes n like sqlite3PutVarint: the 9-byte form holds 8 groups of 7 bits followed by a full byte
+func putVarint(n int64) []byte {
+	var v = uint64(n)
+	if v&(uint64(0xff000000)<<32) != 0 {
+		var b = make([]byte, 9)
+		b[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			b[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return b
+	}
+
+	var b []byte
+	for {
+		b = append([]byte{byte(v&0x7f) | 0x80}, b...)
+		if v >>= 7; v == 0 {
+			break
+		}
+	}
+	b[len(b)-1] &= 0x7f
+	return b
+}
+
+func TestVarint_9_bytes(t *testing.T) {
+	// as written by sqlite for rowids of a table
+	for _, test := range []struct {
+		b []byte
+		n int64
+	}{
+		{[]byte{0xbf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, 1<<63 - 1},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, -1},
+		{[]byte{0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}, -1 << 63},
+	} {
+		v(t, test.b, test.n)
+		if b := putVarint(test.n); !bytes.Equal(b, test.b) {
+			t.Errorf("expected %d to encode as %x; got %x", test.n, test.b, b)
+		}
+	}
+
+	for _, n := range []int64{0, 127, 128, 1<<56 - 1, 1 << 56, 1<<63 - 1, 1<<63 - 2, -1, -2, -1 << 63, -1<<63 + 1, -(1 << 56)} {
+		var b = putVarint(n)
+		if got, err := Varint(bytes.NewReader(b)); err != nil {
+			t.Errorf("%x: %v", b, err)
+		} else if got != n {
+			t.Errorf("%x: expected %d; got %d", b, n, got)
+		}
+	}
+}
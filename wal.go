@@ -0,0 +1,224 @@
+package dotlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// walMagic is the magic number at the start of a WAL file; the least significant bit is set when the checksums
+// are computed over big-endian words, and clear when computed over little-endian words
+const walMagic = 0x377f0682
+
+// walVersion is the only WAL file format version
+const walVersion = 3007000
+
+// WALHeader is the 32-byte header of a write-ahead log file
+// see: https://www.sqlite.org/fileformat.html#wal_file_format
+type WALHeader struct {
+	Magic      uint32
+	Version    uint32 // file format version; currently always 3007000
+	PageSize   uint32 // database page size
+	Checkpoint uint32 // checkpoint sequence number
+	Salt       [2]uint32
+	Checksum   [2]uint32 // checksum of the first 24 bytes of the header
+}
+
+// walFrameHeader is the 24-byte header preceding every page written to the WAL
+type walFrameHeader struct {
+	Page     uint32 // page number
+	Commit   uint32 // size of the database in pages after the commit, for commit frames; zero for all other frames
+	Salt     [2]uint32
+	Checksum [2]uint32 // cumulative checksum of the header's first 8 bytes and the page, continued from the previous frame
+}
+
+// WAL is the write-ahead log of a database in WAL mode, holding pages that were committed but not yet
+// copied back into the database file by a checkpoint.
+//
+// Only frames up to the last valid commit frame are used: a frame is valid as long as its salt matches the
+// header's and its checksum matches the cumulative checksum of all frames before it. The first invalid frame
+// ends the log, which discards frames left behind by an earlier checkpoint as well as any partially written
+// transaction. When a page was written more than once, the latest committed frame holding it is used.
+type WAL struct {
+	Header WALHeader
+
+	file   io.ReaderAt
+	frames map[int]int64 // offset of the latest committed version of a page, keyed by page number
+	count  int           // number of valid, committed frames
+	size   int           // size of the database in pages, as of the last commit; zero if there is no commit
+}
+
+// ReadWAL reads the write-ahead log at r, validating its header and the checksum of every frame to build
+// the index of committed pages. A log whose header is invalid (or that is empty) holds no frames, as sqlite
+// ignores such a log.
+func ReadWAL(r io.ReaderAt) (_ *WAL, err error) {
+	var wal = &WAL{file: r, frames: make(map[int]int64)}
+
+	var header [32]byte
+	if _, err = r.ReadAt(header[:], 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return wal, nil
+		}
+		return nil, err
+	}
+
+	_ = binary.Read(bytes.NewReader(header[:]), binary.BigEndian, &wal.Header)
+	if wal.Header.Magic&^1 != walMagic {
+		return wal, nil
+	} else if wal.Header.Version != walVersion {
+		return nil, fmt.Errorf("unsupported WAL format version %d", wal.Header.Version)
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if wal.Header.Magic&1 == 1 {
+		order = binary.BigEndian
+	}
+
+	var pageSize = int(wal.Header.PageSize)
+	if pageSize < 512 || pageSize > 65536 || pageSize&(pageSize-1) != 0 {
+		return wal, nil
+	}
+
+	var sum = walChecksum(order, [2]uint32{}, header[:24])
+	if sum != wal.Header.Checksum {
+		return wal, nil
+	}
+
+	// pending holds the frames of the current transaction, until its commit frame is read
+	var pending = make(map[int]int64)
+	var frame = make([]byte, 24+pageSize)
+	for offset, n := int64(32), 0; ; offset, n = offset+int64(len(frame)), n+1 {
+		if _, err = r.ReadAt(frame, offset); err != nil {
+			if errors.Is(err, io.EOF) { // a partially written frame ends the log too
+				return wal, nil
+			}
+			return nil, err
+		}
+
+		var fh walFrameHeader
+		_ = binary.Read(bytes.NewReader(frame[:24]), binary.BigEndian, &fh)
+		if fh.Page == 0 || fh.Salt != wal.Header.Salt {
+			return wal, nil
+		}
+
+		if sum = walChecksum(order, walChecksum(order, sum, frame[:8]), frame[24:]); sum != fh.Checksum {
+			return wal, nil
+		}
+
+		pending[int(fh.Page)] = offset + 24
+		if fh.Commit != 0 {
+			for page, off := range pending {
+				wal.frames[page] = off
+			}
+			pending = make(map[int]int64)
+			wal.count, wal.size = n+1, int(fh.Commit)
+		}
+	}
+}
+
+// walChecksum continues the checksum s over b, whose length must be a multiple of 8
+// see: https://www.sqlite.org/fileformat.html#checksum_algorithm
+func walChecksum(order binary.ByteOrder, s [2]uint32, b []byte) [2]uint32 {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s[0] += order.Uint32(b[i:]) + s[1]
+		s[1] += order.Uint32(b[i+4:]) + s[0]
+	}
+	return s
+}
+
+// NumFrames returns the number of frames holding committed pages; frames past the last commit aren't counted
+func (wal *WAL) NumFrames() int { return wal.count }
+
+// NumPages returns the size of the database in pages as of the last commit in the log;
+// zero if the log holds no commit
+func (wal *WAL) NumPages() int { return wal.size }
+
+// lookup returns the offset, in the log, of the latest committed version of the given page
+func (wal *WAL) lookup(page int) (offset int64, ok bool) {
+	if wal == nil {
+		return 0, false
+	}
+	offset, ok = wal.frames[page]
+	return offset, ok
+}
+
+// OpenWithWAL opens the database file at name like Open, along with its write-ahead log (the name-wal file
+// next to it) if there is one. Pages committed to the log are then read from there instead of the database file,
+// so the database is seen as it would be by a new sqlite connection. Without a log, it is equivalent to Open.
+func OpenWithWAL(name string, opts ...Option) (_ *File, err error) {
+	var file *File
+	if file, err = Open(name, opts...); err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = file.Close()
+		}
+	}()
+
+	var f *os.File
+	if f, err = os.Open(name + "-wal"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return file, nil
+		}
+		return nil, err
+	}
+	file.closer = multiCloser{file.closer, f}
+
+	var wal *WAL
+	if wal, err = ReadWAL(f); err != nil {
+		return nil, err
+	}
+
+	if err = file.useWAL(wal); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// useWAL makes the pager read committed pages from the log, and reloads the header from the latest version of page 1
+func (f *File) useWAL(wal *WAL) (err error) {
+	if wal.NumFrames() == 0 {
+		return nil
+	} else if int(wal.Header.PageSize) != f.PageSize() {
+		return fmt.Errorf("WAL page size %d doesn't match the database page size %d", wal.Header.PageSize, f.PageSize())
+	}
+
+	f.Pager.wal, f.Pager.pages = wal, wal.NumPages()
+
+	var page *Page
+	if page, err = f.Pager.ReadPage(1); err != nil {
+		return err
+	}
+
+	var header Header
+	if err = binary.Read(page, binary.BigEndian, &header); err != nil {
+		return err
+	} else if err = header.Valid(); err != nil {
+		return err
+	}
+
+	// the size of the database is that of the last commit; the in-header size may describe an older version
+	header.Size = int32(wal.NumPages())
+	f.Header = header
+
+	return nil
+}
+
+// multiCloser closes all of its closers, returning the first error
+type multiCloser []io.Closer
+
+func (m multiCloser) Close() (err error) {
+	for _, c := range m {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
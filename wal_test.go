@@ -0,0 +1,144 @@
+package dotlite
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rows returns the values of all rows of the named table, as strings
+func rows(t *testing.T, file *File, name string) (rows [][]string) {
+	err := file.ForEach(name, func(rec *Record) error {
+		row, err := rec.Strings()
+		rows = append(rows, row)
+		return err
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestOpenWithWAL(t *testing.T) {
+	// testdata/wal.db was checkpointed after inserting rows 1 to 3; its log then holds (in order) transactions
+	// that set row 2 to 'two', insert row 4, create table u, insert into u, and finally set row 2 to 'TWO'
+	var committed = [][]string{{"NULL", "one"}, {"NULL", "TWO"}, {"NULL", "three"}, {"NULL", "four"}}
+
+	t.Run("without log", func(t *testing.T) {
+		var file = open(t, "testdata/wal.db")
+		defer file.Close()
+
+		var expected = [][]string{{"NULL", "one"}, {"NULL", "2"}, {"NULL", "three"}}
+		if got := rows(t, file, "t"); !reflect.DeepEqual(got, expected) {
+			t.Errorf("expected %v; got %v", expected, got)
+		}
+
+		if _, err := file.Object("u"); err == nil {
+			t.Errorf("expected table u to only exist in the log")
+		}
+	})
+
+	t.Run("with log", func(t *testing.T) {
+		file, err := OpenWithWAL("testdata/wal.db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		if file.NumPages() != 3 || file.Pager.wal.NumFrames() != 6 {
+			t.Errorf("expected 3 pages and 6 frames; got %d pages and %d frames", file.NumPages(), file.Pager.wal.NumFrames())
+		}
+
+		if got := rows(t, file, "t"); !reflect.DeepEqual(got, committed) {
+			t.Errorf("expected %v; got %v", committed, got)
+		}
+
+		if got := rows(t, file, "u"); !reflect.DeepEqual(got, [][]string{{"1"}}) {
+			t.Errorf("unexpected rows in u: %v", got)
+		}
+	})
+
+	t.Run("no log file", func(t *testing.T) {
+		file, err := OpenWithWAL("testdata/indexes.db")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		if file.Pager.wal != nil {
+			t.Errorf("expected no log")
+		}
+	})
+
+	// patch writes a copy of the database and of its log, changed by fn, to a temporary directory
+	var patch = func(t *testing.T, fn func(log []byte) []byte) string {
+		var dir = t.TempDir()
+		for _, suffix := range []string{"", "-wal"} {
+			b, err := os.ReadFile("testdata/wal.db" + suffix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if suffix == "-wal" {
+				b = fn(b)
+			}
+
+			if err = os.WriteFile(filepath.Join(dir, "wal.db"+suffix), b, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return filepath.Join(dir, "wal.db")
+	}
+
+	var last = 32 + 5*(24+1024) // offset of the sixth, and last, frame
+
+	// checksum recomputes the cumulative checksum of every frame of the log, so that a patched frame remains valid
+	var checksum = func(b []byte) []byte {
+		var order binary.ByteOrder = binary.LittleEndian
+		if binary.BigEndian.Uint32(b)&1 == 1 {
+			order = binary.BigEndian
+		}
+
+		var sum = walChecksum(order, [2]uint32{}, b[:24])
+		for offset := 32; offset+24+1024 <= len(b); offset += 24 + 1024 {
+			sum = walChecksum(order, walChecksum(order, sum, b[offset:offset+8]), b[offset+24:offset+24+1024])
+			binary.BigEndian.PutUint32(b[offset+16:], sum[0])
+			binary.BigEndian.PutUint32(b[offset+20:], sum[1])
+		}
+		return b
+	}
+
+	// all but the last transaction (setting row 2 to 'TWO') are committed
+	var partial = [][]string{{"NULL", "one"}, {"NULL", "two"}, {"NULL", "three"}, {"NULL", "four"}}
+	var ignored = [][]string{{"NULL", "one"}, {"NULL", "2"}, {"NULL", "three"}}
+
+	for _, test := range []struct {
+		name     string
+		patch    func([]byte) []byte
+		expected [][]string
+	}{
+		{"bad checksum", func(b []byte) []byte { b[last+24+1000] ^= 0xff; return b }, partial},
+		{"salt mismatch", func(b []byte) []byte { binary.BigEndian.PutUint32(b[last+8:], 42); return b }, partial},
+		{"partial frame", func(b []byte) []byte { return b[:last+500] }, partial},
+		{"uncommitted frame", func(b []byte) []byte { binary.BigEndian.PutUint32(b[last+4:], 0); return checksum(b) }, partial},
+		{"recomputed checksum", func(b []byte) []byte { return checksum(b) }, committed},
+		{"invalid header", func(b []byte) []byte { b[12] ^= 0xff; return b }, ignored}, // checksum no longer matches
+		{"wrong magic", func(b []byte) []byte { b[0] = 0; return b }, ignored},
+		{"empty log", func([]byte) []byte { return nil }, ignored},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := OpenWithWAL(patch(t, test.patch))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if got := rows(t, file, "t"); !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v; got %v", test.expected, got)
+			}
+		})
+	}
+}
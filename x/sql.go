@@ -0,0 +1,272 @@
+package x
+
+import "strings"
+
+// This file contains routines to render a parsed Table back into sql
+
+// SQL renders the table as a canonical CREATE TABLE statement.
+//
+// Identifiers are always double-quoted, keywords are upper-cased and clauses are written in a fixed order,
+// so two tables that only differ in the formatting of their original statement render to the same sql.
+// Parsing the rendered statement yields a Table equal to t.
+func (t *Table) SQL() string {
+	var sb strings.Builder
+
+	sb.WriteString("CREATE ")
+	if t.Properties.Temporary {
+		sb.WriteString("TEMP ")
+	}
+	sb.WriteString("TABLE ")
+	if t.Properties.IfNotExists {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	if t.Schema != "" {
+		sb.WriteString(quote(t.Schema) + ".")
+	}
+	sb.WriteString(quote(t.Name) + " (")
+
+	var defs []string
+	for _, col := range t.Columns {
+		defs = append(defs, col.SQL())
+	}
+	for _, cons := range t.Constraints {
+		defs = append(defs, cons.SQL())
+	}
+	sb.WriteString(strings.Join(defs, ", ") + ")")
+
+	var options []string
+	if t.Properties.WithoutRowid {
+		options = append(options, "WITHOUT ROWID")
+	}
+	if t.Properties.Strict {
+		options = append(options, "STRICT")
+	}
+	if len(options) > 0 {
+		sb.WriteString(" " + strings.Join(options, ", "))
+	}
+
+	return sb.String()
+}
+
+// SQL renders the column definition as it appears in a CREATE TABLE statement
+func (col *Column) SQL() string {
+	var parts = []string{quote(col.Name)}
+	if col.Type != "" {
+		var typ = col.Type
+		if col.Length != "" {
+			typ += "(" + col.Length + ")"
+		}
+		parts = append(parts, typ)
+	}
+
+	if col.ConstraintName != "" {
+		parts = append(parts, "CONSTRAINT "+quote(col.ConstraintName))
+	}
+
+	if col.Properties.PrimaryKey {
+		parts = append(parts, "PRIMARY KEY")
+		if order := col.PrimaryKeyOrder.sql(); order != "" {
+			parts = append(parts, order)
+		}
+		if conflict := col.PrimaryKeyConflict.sql(); conflict != "" {
+			parts = append(parts, conflict)
+		}
+		if col.Properties.AutoIncrement {
+			parts = append(parts, "AUTOINCREMENT")
+		}
+	}
+
+	if col.Properties.NotNull {
+		parts = append(parts, "NOT NULL")
+		if conflict := col.NotNullConflict.sql(); conflict != "" {
+			parts = append(parts, conflict)
+		}
+	}
+
+	if col.Properties.Unique {
+		parts = append(parts, "UNIQUE")
+		if conflict := col.UniqueConflict.sql(); conflict != "" {
+			parts = append(parts, conflict)
+		}
+	}
+
+	if col.CheckExpr != "" {
+		parts = append(parts, "CHECK "+parenthesize(col.CheckExpr))
+	}
+
+	if col.DefaultExpr != "" {
+		parts = append(parts, "DEFAULT "+col.DefaultExpr)
+	}
+
+	if col.Collate != "" {
+		parts = append(parts, "COLLATE "+col.Collate)
+	}
+
+	if col.ForeignKey != nil {
+		parts = append(parts, col.ForeignKey.clause())
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// SQL renders the table constraint as it appears in a CREATE TABLE statement
+func (cons *TableConstraint) SQL() string {
+	var parts []string
+	if cons.Name != "" {
+		parts = append(parts, "CONSTRAINT "+quote(cons.Name))
+	}
+
+	switch cons.Type {
+	case ConstraintPrimaryKey, ConstraintUnique:
+		var kw = "PRIMARY KEY"
+		if cons.Type == ConstraintUnique {
+			kw = "UNIQUE"
+		}
+
+		var columns []string
+		for _, ic := range cons.IndexedColumns {
+			var def = quote(ic.Name)
+			if ic.Collate != "" {
+				def += " COLLATE " + ic.Collate
+			}
+			if order := ic.Order.sql(); order != "" {
+				def += " " + order
+			}
+			columns = append(columns, def)
+		}
+
+		parts = append(parts, kw+" ("+strings.Join(columns, ", ")+")")
+		if conflict := cons.OnConflict.sql(); conflict != "" {
+			parts = append(parts, conflict)
+		}
+
+	case ConstraintCheck:
+		parts = append(parts, "CHECK "+parenthesize(cons.CheckExpr))
+
+	case ConstraintForeignKey:
+		if cons.ForeignKey != nil {
+			parts = append(parts, "FOREIGN KEY ("+quoteAll(cons.ForeignKey.Columns)+")", cons.ForeignKey.clause())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// clause renders the REFERENCES clause of the foreign key
+func (fk *ForeignKey) clause() string {
+	var sb strings.Builder
+	sb.WriteString("REFERENCES " + quote(fk.ReferencedTable))
+	if len(fk.ReferencedColumns) > 0 {
+		sb.WriteString(" (" + quoteAll(fk.ReferencedColumns) + ")")
+	}
+
+	if action := fk.OnDelete.sql(); action != "" {
+		sb.WriteString(" ON DELETE " + action)
+	}
+	if action := fk.OnUpdate.sql(); action != "" {
+		sb.WriteString(" ON UPDATE " + action)
+	}
+	if fk.Match != "" {
+		sb.WriteString(" MATCH " + fk.Match)
+	}
+	if deferrable := fk.Deferrable.sql(); deferrable != "" {
+		sb.WriteString(" " + deferrable)
+	}
+
+	return sb.String()
+}
+
+// sql returns the ON CONFLICT clause for the resolution; empty if none was specified
+func (c OnConflict) sql() string {
+	switch c {
+	case ConflictRollback:
+		return "ON CONFLICT ROLLBACK"
+	case ConflictAbort:
+		return "ON CONFLICT ABORT"
+	case ConflictFail:
+		return "ON CONFLICT FAIL"
+	case ConflictIgnore:
+		return "ON CONFLICT IGNORE"
+	case ConflictReplace:
+		return "ON CONFLICT REPLACE"
+	}
+	return ""
+}
+
+// sql returns the keyword for the ordering; empty if none was specified
+func (o Ordering) sql() string {
+	switch o {
+	case OrderingAsc:
+		return "ASC"
+	case OrderingDesc:
+		return "DESC"
+	}
+	return ""
+}
+
+// sql returns the keywords for the action; empty if none was specified
+func (a ForeignKeyAction) sql() string {
+	switch a {
+	case ForeignKeyActionSetNull:
+		return "SET NULL"
+	case ForeignKeyActionSetDefault:
+		return "SET DEFAULT"
+	case ForeignKeyActionCascade:
+		return "CASCADE"
+	case ForeignKeyActionRestrict:
+		return "RESTRICT"
+	case ForeignKeyActionNoAction:
+		return "NO ACTION"
+	}
+	return ""
+}
+
+// sql returns the keywords for the deferrable clause; empty if none was specified
+func (d ForeignKeyDeferrable) sql() string {
+	switch d {
+	case ForeignKeyDeferrableDeferrable:
+		return "DEFERRABLE"
+	case ForeignKeyDeferrableInitiallyDeferred:
+		return "DEFERRABLE INITIALLY DEFERRED"
+	case ForeignKeyDeferrableInitiallyImmediate:
+		return "DEFERRABLE INITIALLY IMMEDIATE"
+	case ForeignKeyDeferrableNotDeferrable:
+		return "NOT DEFERRABLE"
+	case ForeignKeyDeferrableNotDeferrableInitiallyDeferred:
+		return "NOT DEFERRABLE INITIALLY DEFERRED"
+	case ForeignKeyDeferrableNotDeferrableInitiallyImmediate:
+		return "NOT DEFERRABLE INITIALLY IMMEDIATE"
+	}
+	return ""
+}
+
+// quote returns name as a double-quoted identifier
+func quote(name string) string { return `"` + strings.ReplaceAll(name, `"`, `""`) + `"` }
+
+// quoteAll quotes every name and joins them with a comma
+func quoteAll(names []string) string {
+	var quoted = make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quote(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// parenthesize wraps expr in parenthesis, unless the parser already kept them around the expression
+func parenthesize(expr string) string {
+	var depth = 0
+	for i, c := range expr {
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			if depth--; depth == 0 && i < len(expr)-1 {
+				break // the opening parenthesis closes before the end, eg. (a) AND (b)
+			} else if depth == 0 {
+				return expr
+			}
+		} else if depth == 0 {
+			break
+		}
+	}
+	return "(" + expr + ")"
+}
@@ -2,12 +2,13 @@ package x
 
 import (
 	"encoding/json"
-	. "go.riyazali.net/dotlite"
+	"go.riyazali.net/dotlite"
+	"reflect"
 	"testing"
 )
 
-func open(t *testing.T, name string) *File {
-	var file, err = OpenFile(name)
+func open(t *testing.T, name string) *dotlite.File {
+	var file, err = dotlite.Open(name)
 	if err != nil {
 		t.Errorf("failed to open file: %v", err)
 	}
@@ -36,3 +37,33 @@ func TestTable(t *testing.T) {
 		}
 	}
 }
+
+func TestTable_SQL(t *testing.T) {
+	for _, sql := range []string{
+		`CREATE TEMP TABLE IF NOT EXISTS main."order" (id INTEGER CONSTRAINT pk PRIMARY KEY DESC ON CONFLICT REPLACE AUTOINCREMENT, amount DECIMAL(10, 2) NOT NULL ON CONFLICT FAIL CHECK (amount > 0))`,
+		`create table t (a text unique default 'x' collate nocase references p(x, y) on delete cascade on update set null match full deferrable initially deferred, b default (1 + 2))`,
+		`CREATE TABLE t (a, b, c, CONSTRAINT u UNIQUE (a COLLATE nocase DESC, b) ON CONFLICT IGNORE, PRIMARY KEY (a, b), FOREIGN KEY (b, c) REFERENCES q (x, y) ON DELETE RESTRICT NOT DEFERRABLE) WITHOUT ROWID, STRICT`,
+	} {
+		var table, err = ParseSchema(sql)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rendered = table.SQL()
+
+		var parsed *Table
+		if parsed, err = ParseSchema(rendered); err != nil {
+			t.Fatalf("failed to parse rendered sql %q: %v", rendered, err)
+		}
+
+		if !reflect.DeepEqual(table, parsed) {
+			var a, _ = json.Marshal(table)
+			var b, _ = json.Marshal(parsed)
+			t.Errorf("round-trip through %q changed the table:\n%s\n%s", rendered, a, b)
+		}
+
+		if again := parsed.SQL(); again != rendered {
+			t.Errorf("expected rendered sql to be stable; got %q and %q", rendered, again)
+		}
+	}
+}